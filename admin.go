@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/matt-horst/blog-agg/internal/database"
+
+	"golang.org/x/term"
+)
+
+const (
+	roleAdmin = "admin"
+	roleMember = "member"
+)
+
+func isAdmin(user database.User) bool {
+	return user.Role == roleAdmin
+}
+
+// Only lets admins through. Goes inside middlewareAudit so refused attempts
+// are recorded too.
+func middlewareAdmin(handler func(ctx context.Context, s *state, cmd command, user database.User) error) func(ctx context.Context, s *state, cmd command, user database.User) error {
+	return func(ctx context.Context, s *state, cmd command, user database.User) error {
+		if !isAdmin(user) {
+			return fmt.Errorf("`%s` can only be run by an admin", cmd.name)
+		}
+
+		return handler(ctx, s, cmd, user)
+	}
+}
+
+// Asks the user to type word before a destructive operation, unless --yes
+// was given. Without a terminal there's no one to ask, so --yes is required.
+func confirm(action, word string, yes bool) error {
+	if yes {
+		return nil
+	}
+
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return fmt.Errorf("Refusing to %s without confirmation: pass --yes", action)
+	}
+
+	fmt.Fprintf(os.Stderr, "This will %s. Type `%s` to continue: ", action, word)
+	line, err := stdinReader.ReadString('\n')
+	if err != nil && line == "" {
+		return fmt.Errorf("Failed to read confirmation: %v", err)
+	}
+
+	if strings.TrimSpace(line) != word {
+		return fmt.Errorf("Cancelled")
+	}
+
+	return nil
+}
+
+// Deletes every user and, through them, every feed, follow and post
+func handlerReset(ctx context.Context, s *state, cmd command, user database.User) error {
+	fs := flag.NewFlagSet("reset", flag.ContinueOnError)
+	yes := fs.Bool("yes", false, "don't ask for confirmation")
+
+	positional, err := parseFlags(fs, cmd.args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 0 {
+		return fmt.Errorf("reset takes no arguments besides flags")
+	}
+
+	err = confirm("delete every user, feed and post", "reset", *yes)
+	if err != nil {
+		return err
+	}
+
+	err = s.db.ResetUsers(ctx)
+	if err != nil {
+		return fmt.Errorf("Failed to reset users table: %v", err)
+	}
+
+	return nil
+}
+
+// Makes a user an admin or a member. The last admin can't be demoted, so
+// there is always someone left who can administer.
+func handlerSetRole(ctx context.Context, s *state, cmd command, user database.User) error {
+	if len(cmd.args) != 2 {
+		return fmt.Errorf("setrole requires two arguments: name %s|%s", roleAdmin, roleMember)
+	}
+
+	role := cmd.args[1]
+	if role != roleAdmin && role != roleMember {
+		return fmt.Errorf("Unknown role `%s`: must be %s or %s", role, roleAdmin, roleMember)
+	}
+
+	target, err := s.db.GetUser(ctx, cmd.args[0])
+	if err != nil {
+		return fmt.Errorf("Unable to find user `%s`: %v", cmd.args[0], err)
+	}
+
+	if isAdmin(target) && role != roleAdmin {
+		admins, err := s.db.CountAdmins(ctx)
+		if err != nil {
+			return fmt.Errorf("Failed to count admins: %v", err)
+		}
+		if admins <= 1 {
+			return fmt.Errorf("`%s` is the last admin; make someone else an admin first", target.Name)
+		}
+	}
+
+	err = s.db.SetUserRole(ctx, database.SetUserRoleParams{ID: target.ID, Role: role})
+	if err != nil {
+		return fmt.Errorf("Failed to set role of `%s`: %v", target.Name, err)
+	}
+
+	fmt.Printf("Set role of `%s` to %s\n", target.Name, role)
+
+	return nil
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/matt-horst/blog-agg/internal/database"
+)
+
+func TestRoles(t *testing.T) {
+	ctx := context.Background()
+	s := newTestState(t)
+
+	alice := createTestUser(t, s, "alice")
+	bob := createTestUser(t, s, "bob")
+	if !isAdmin(alice) || isAdmin(bob) {
+		t.Fatalf("Roles are %s and %s, want the first user to be the only admin", alice.Role, bob.Role)
+	}
+	feed := createTestFeed(t, s, bob, "Tech", "https://tech.example.com/rss")
+
+	// Tests don't run in a terminal, so there's no one to confirm
+	_, err := runNamedHandler(t, middlewareAdmin(handlerDeleteFeed), s, alice, "deletefeed", feed.Url)
+	if err == nil || !strings.Contains(err.Error(), "pass --yes") {
+		t.Errorf("Deleted a feed without confirmation: %v", err)
+	}
+
+	for name, handler := range map[string]func(context.Context, *state, command, database.User) error{
+		"reset": handlerReset,
+		"users": handlerUsers,
+		"deletefeed": handlerDeleteFeed,
+		"setrole": handlerSetRole,
+	} {
+		_, err := runNamedHandler(t, middlewareAdmin(handler), s, bob, name, "--yes", feed.Url)
+		if err == nil || err.Error() != "`"+name+"` can only be run by an admin" {
+			t.Errorf("Member ran %s: %v", name, err)
+		}
+	}
+	if _, err := s.db.GetFeed(ctx, feed.Url); err != nil {
+		t.Fatalf("Feed is gone after refused deletions: %v", err)
+	}
+
+	for _, args := range [][]string{
+		{"bob"},
+		{"bob", "owner"},
+		{"carol", roleAdmin},
+		{"alice", roleMember},
+	} {
+		_, err := runNamedHandler(t, handlerSetRole, s, alice, "setrole", args...)
+		if err == nil {
+			t.Errorf("setrole %q succeeded", args)
+		}
+	}
+
+	out, err := runNamedHandler(t, handlerSetRole, s, alice, "setrole", "bob", roleAdmin)
+	if err != nil {
+		t.Fatalf("Failed to promote: %v", err)
+	}
+	if out != "Set role of `bob` to admin\n" {
+		t.Errorf("setrole printed %q", out)
+	}
+
+	// With a second admin, the first can step down
+	_, err = runNamedHandler(t, handlerSetRole, s, alice, "setrole", "alice", roleMember)
+	if err != nil {
+		t.Fatalf("Failed to demote with another admin left: %v", err)
+	}
+	alice, err = s.db.GetUser(ctx, "alice")
+	if err != nil || isAdmin(alice) {
+		t.Errorf("alice is a %s (%v), want a member", alice.Role, err)
+	}
+
+	bob, err = s.db.GetUser(ctx, "bob")
+	if err != nil {
+		t.Fatalf("Failed to get user: %v", err)
+	}
+	_, err = runNamedHandler(t, middlewareAdmin(handlerReset), s, bob, "reset", "--yes")
+	if err != nil {
+		t.Fatalf("Admin failed to reset: %v", err)
+	}
+	users, err := s.db.GetUsers(ctx)
+	if err != nil || len(users) != 0 {
+		t.Errorf("Users left after reset: %v (%v)", users, err)
+	}
+}
@@ -0,0 +1,456 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net"
+	"net/http"
+	"net/mail"
+	"net/smtp"
+	"os/exec"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/matt-horst/blog-agg/internal/database"
+
+	"github.com/google/uuid"
+)
+
+const notifyTimeout = 10 * time.Second
+
+var alertRuleKinds = []string{"keyword", "regex", "author", "feed"}
+
+// A post that matched one of a user's alert rules
+type alert struct {
+	rule database.AlertRule
+	feed database.Feed
+	post database.Post
+}
+
+type notifier interface {
+	notify(ctx context.Context, a alert) error
+}
+
+// Notifiers by name. Each constructor receives the rule's target (a URL,
+// address, etc.) and may reject it.
+var notifiers = map[string]func(s *state, target string) (notifier, error){
+	"terminal": func(_ *state, _ string) (notifier, error) {
+		return terminalNotifier{}, nil
+	},
+	"desktop": func(_ *state, _ string) (notifier, error) {
+		return desktopNotifier{}, nil
+	},
+	"webhook": func(_ *state, target string) (notifier, error) {
+		if !strings.HasPrefix(target, "http://") && !strings.HasPrefix(target, "https://") {
+			return nil, fmt.Errorf("webhook notifier requires an http(s) URL target")
+		}
+		return webhookNotifier{url: target}, nil
+	},
+	"email": func(s *state, target string) (notifier, error) {
+		addr, err := mail.ParseAddress(target)
+		if err != nil {
+			return nil, fmt.Errorf("email notifier requires an email address target: %v", err)
+		}
+		target = addr.Address
+		if s.cfg.SMTP == nil {
+			return nil, fmt.Errorf("email notifier requires smtp settings in the config file")
+		}
+		return emailNotifier{s: s, to: target}, nil
+	},
+}
+
+// An alert rule ready to be evaluated against new posts
+type compiledRule struct {
+	rule database.AlertRule
+	re *regexp.Regexp
+	notifier notifier
+}
+
+func compileRule(s *state, rule database.AlertRule) (compiledRule, error) {
+	compiled := compiledRule{rule: rule}
+
+	// Case-insensitive like the other kinds and filter regexes
+	if rule.Kind == "regex" {
+		re, err := regexp.Compile("(?i)" + rule.Pattern)
+		if err != nil {
+			return compiledRule{}, fmt.Errorf("Invalid regex `%s`: %v", rule.Pattern, err)
+		}
+		compiled.re = re
+	}
+
+	newNotifier, ok := notifiers[rule.Notifier]
+	if !ok {
+		return compiledRule{}, fmt.Errorf("Unknown notifier `%s`", rule.Notifier)
+	}
+
+	n, err := newNotifier(s, rule.Target)
+	if err != nil {
+		return compiledRule{}, err
+	}
+	compiled.notifier = n
+
+	return compiled, nil
+}
+
+func (r compiledRule) matches(feed database.Feed, item RSSItem) bool {
+	pattern := strings.ToLower(r.rule.Pattern)
+
+	switch r.rule.Kind {
+	case "keyword":
+		text := strings.ToLower(item.Title + "\n" + item.Description + "\n" + item.Content)
+		return strings.Contains(text, pattern)
+	case "regex":
+		return r.re.MatchString(item.Title + "\n" + item.Description + "\n" + item.Content)
+	case "author":
+		return strings.Contains(strings.ToLower(item.author()), pattern)
+	case "feed":
+		return feed.Url == r.rule.Pattern || strings.ToLower(feed.Name) == pattern
+	}
+
+	return false
+}
+
+// Loads the rules of every user following the feed. Broken rules are logged
+// and skipped so one bad rule can't stop the others.
+func loadAlertRules(ctx context.Context, s *state, feed database.Feed) []compiledRule {
+	rules, err := s.db.GetAlertRulesForFeed(ctx, feed.ID)
+	if err != nil {
+		feedLogger(feed).Error("Failed to load alert rules", errAttrs(err))
+		return nil
+	}
+
+	compiled := []compiledRule{}
+	for _, rule := range rules {
+		c, err := compileRule(s, rule)
+		if err != nil {
+			feedLogger(feed).Warn("Skipping invalid alert rule", slog.String("rule", rule.Name), errAttrs(err))
+			continue
+		}
+		compiled = append(compiled, c)
+	}
+
+	return compiled
+}
+
+// Tags a newly created post with every matching rule and sends notifications
+func evaluateAlerts(ctx context.Context, s *state, feed database.Feed, post database.Post, item RSSItem, rules []compiledRule) {
+	for _, r := range rules {
+		if !r.matches(feed, item) {
+			continue
+		}
+
+		logger := feedLogger(feed).With(slog.String("post_url", post.Url), slog.String("rule", r.rule.Name))
+
+		err := s.db.CreatePostAlert(ctx, database.CreatePostAlertParams{PostID: post.ID, RuleID: r.rule.ID})
+		if err != nil {
+			logger.Error("Failed to tag post with alert", errAttrs(err))
+		}
+
+		sendNotification(ctx, s, r, alert{rule: r.rule, feed: feed, post: post}, logger)
+	}
+}
+
+// Sends the notification in the background so a slow webhook or mail server
+// can't hold up the scrape. It gets notifyTimeout even if ctx is cancelled;
+// commands that send notifications wait for them in s.notifications.
+func sendNotification(ctx context.Context, s *state, r compiledRule, a alert, logger *slog.Logger) {
+	s.notifications.Add(1)
+	go func() {
+		defer s.notifications.Done()
+
+		notifyCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), notifyTimeout)
+		defer cancel()
+
+		err := r.notifier.notify(notifyCtx, a)
+		if err != nil {
+			logger.Warn("Failed to send alert notification", slog.String("notifier", r.rule.Notifier), errAttrs(err))
+			return
+		}
+
+		logger.Info("Sent alert notification", slog.String("notifier", r.rule.Notifier))
+	}()
+}
+
+func (a alert) summary() string {
+	return fmt.Sprintf("[%s] %s: %s", a.rule.Name, a.feed.Name, a.post.Title)
+}
+
+type terminalNotifier struct{}
+
+func (terminalNotifier) notify(_ context.Context, a alert) error {
+	fmt.Printf("\a*** ALERT %s ***\n%s\n", a.summary(), a.post.Url)
+	return nil
+}
+
+// Uses notify-send, available on most Linux desktops
+type desktopNotifier struct{}
+
+func (desktopNotifier) notify(ctx context.Context, a alert) error {
+	title := fmt.Sprintf("gator: %s", a.rule.Name)
+	body := fmt.Sprintf("%s\n%s\n%s", a.feed.Name, a.post.Title, a.post.Url)
+
+	out, err := exec.CommandContext(ctx, "notify-send", "--app-name=gator", title, body).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("notify-send failed: %v: %s", err, strings.TrimSpace(string(out)))
+	}
+
+	return nil
+}
+
+type webhookNotifier struct {
+	url string
+}
+
+type webhookPayload struct {
+	Rule string `json:"rule"`
+	Feed string `json:"feed"`
+	FeedURL string `json:"feed_url"`
+	Title string `json:"title"`
+	URL string `json:"url"`
+	PublishedAt *time.Time `json:"published_at,omitempty"`
+	Text string `json:"text"`
+}
+
+func (n webhookNotifier) notify(ctx context.Context, a alert) error {
+	payload := webhookPayload{
+		Rule: a.rule.Name,
+		Feed: a.feed.Name,
+		FeedURL: a.feed.Url,
+		Title: a.post.Title,
+		URL: a.post.Url,
+		// Lets chat webhooks (Slack, Mattermost) render something useful as-is
+		Text: fmt.Sprintf("%s\n%s", a.summary(), a.post.Url),
+	}
+	if a.post.PublishedAt.Valid {
+		payload.PublishedAt = &a.post.PublishedAt.Time
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("Failed to marshal webhook payload: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", n.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("Failed to create webhook request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("Failed to call webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%w from webhook: %s", errUnexpectedStatus, resp.Status)
+	}
+
+	return nil
+}
+
+type emailNotifier struct {
+	s *state
+	to string
+}
+
+// The post's title comes from the feed, so line breaks in it must not be
+// able to start new headers
+func (n emailNotifier) message(a alert) []byte {
+	subject := strings.Join(strings.Fields(a.summary()), " ")
+
+	return []byte(strings.Join([]string{
+		"From: " + n.s.cfg.SMTP.From,
+		"To: " + n.to,
+		"Subject: " + mime.QEncoding.Encode("utf-8", subject),
+		"Content-Type: text/plain; charset=utf-8",
+		"",
+		a.post.Title,
+		a.post.Url,
+		"",
+		a.post.Description,
+	}, "\r\n"))
+}
+
+func (n emailNotifier) notify(ctx context.Context, a alert) error {
+	err := n.send(ctx, n.message(a))
+	if err != nil {
+		return fmt.Errorf("Failed to send email to `%s`: %w", n.to, err)
+	}
+
+	return nil
+}
+
+// Does what smtp.SendMail does, but gives up when ctx is done
+func (n emailNotifier) send(ctx context.Context, msg []byte) error {
+	cfg := n.s.cfg.SMTP
+
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port)))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	deadline, ok := ctx.Deadline()
+	if ok {
+		conn.SetDeadline(deadline)
+	}
+
+	c, err := smtp.NewClient(conn, cfg.Host)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		err = c.StartTLS(&tls.Config{ServerName: cfg.Host})
+		if err != nil {
+			return err
+		}
+	}
+	if cfg.Username != "" {
+		err = c.Auth(smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host))
+		if err != nil {
+			return err
+		}
+	}
+
+	err = c.Mail(cfg.From)
+	if err != nil {
+		return err
+	}
+	err = c.Rcpt(n.to)
+	if err != nil {
+		return err
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(msg)
+	if err != nil {
+		return err
+	}
+	err = w.Close()
+	if err != nil {
+		return err
+	}
+
+	return c.Quit()
+}
+
+func handlerAddAlert(ctx context.Context, s *state, cmd command, user database.User) error {
+	fs := flag.NewFlagSet("addalert", flag.ContinueOnError)
+	notify := fs.String("notify", "terminal", "how to notify: terminal, desktop, webhook or email")
+	target := fs.String("target", "", "webhook URL or email address for the notifier")
+
+	positional, err := parseFlags(fs, cmd.args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 3 {
+		return fmt.Errorf("addalert requires three arguments: name kind pattern (kind is one of %s)", strings.Join(alertRuleKinds, ", "))
+	}
+
+	name := positional[0]
+	kind := positional[1]
+	pattern := positional[2]
+
+	if !slices.Contains(alertRuleKinds, kind) {
+		return fmt.Errorf("Unknown alert kind `%s`: must be one of %s", kind, strings.Join(alertRuleKinds, ", "))
+	}
+
+	params := database.CreateAlertRuleParams{
+		ID: uuid.New(),
+		UserID: user.ID,
+		Name: name,
+		Kind: kind,
+		Pattern: pattern,
+		Notifier: *notify,
+		Target: *target,
+	}
+
+	// Validate before saving so a broken rule is never stored
+	_, err = compileRule(s, database.AlertRule{Kind: kind, Pattern: pattern, Notifier: *notify, Target: *target})
+	if err != nil {
+		return err
+	}
+
+	rule, err := s.db.CreateAlertRule(ctx, params)
+	if err != nil {
+		return fmt.Errorf("Failed to create alert rule: %v", err)
+	}
+
+	fmt.Printf("Alert `%s` created: %s `%s` via %s\n", rule.Name, rule.Kind, rule.Pattern, rule.Notifier)
+
+	return nil
+}
+
+func handlerAlerts(ctx context.Context, s *state, cmd command, user database.User) error {
+	rules, err := s.db.GetAlertRulesForUser(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("Failed to get alert rules: %v", err)
+	}
+
+	for _, rule := range rules {
+		target := ""
+		if rule.Target != "" {
+			target = " " + rule.Target
+		}
+		fmt.Printf("* %s: %s `%s` via %s%s\n", rule.Name, rule.Kind, rule.Pattern, rule.Notifier, target)
+	}
+
+	return nil
+}
+
+func handlerRemoveAlert(ctx context.Context, s *state, cmd command, user database.User) error {
+	if len(cmd.args) != 1 {
+		return fmt.Errorf("removealert requires the alert name")
+	}
+
+	removed, err := s.db.DeleteAlertRule(ctx, database.DeleteAlertRuleParams{UserID: user.ID, Name: cmd.args[0]})
+	if err != nil {
+		return fmt.Errorf("Failed to remove alert `%s`: %v", cmd.args[0], err)
+	}
+	if removed == 0 {
+		return fmt.Errorf("No alert named `%s`", cmd.args[0])
+	}
+
+	return nil
+}
+
+func handlerAlerted(ctx context.Context, s *state, cmd command, user database.User) error {
+	limit := 10
+	if len(cmd.args) == 1 {
+		arg, err := strconv.Atoi(cmd.args[0])
+		if err != nil {
+			return fmt.Errorf("Failed to convert `%s` to integer", cmd.args[0])
+		}
+		limit = arg
+	}
+
+	posts, err := s.db.GetAlertedPostsForUser(ctx, database.GetAlertedPostsForUserParams{UserID: user.ID, Limit: int32(limit)})
+	if err != nil {
+		return fmt.Errorf("Failed to get alerted posts: %v", err)
+	}
+
+	for _, p := range posts {
+		fmt.Printf("[%s] %s\n", p.RuleName, p.AlertedAt.Format("Mon Jan 2, 2006 15:04"))
+		fmt.Printf("*** %s ***\n", p.Title)
+		fmt.Printf("Link: %s\n", p.Url)
+		fmt.Printf("ID: %s\n", p.ID)
+		fmt.Println()
+	}
+
+	return nil
+}
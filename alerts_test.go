@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"mime"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"testing"
+	"time"
+
+	"github.com/matt-horst/blog-agg/internal/config"
+	"github.com/matt-horst/blog-agg/internal/database"
+
+	"github.com/google/uuid"
+)
+
+func TestEmailMessage(t *testing.T) {
+	s := &state{cfg: &config.Config{SMTP: &config.SMTPConfig{Host: "localhost", Port: 25, From: "gator@example.com"}}}
+	n := emailNotifier{s: s, to: "alice@example.com"}
+
+	a := alert{
+		rule: database.AlertRule{Name: "go"},
+		feed: database.Feed{Name: "Café"},
+		post: database.Post{Title: "Go 2\r\nBcc: victim@example.com\rX-Injected: yes", Url: "https://example.com/go2"},
+	}
+
+	msg, err := mail.ReadMessage(bytes.NewReader(n.message(a)))
+	if err != nil {
+		t.Fatalf("Failed to parse message: %v", err)
+	}
+
+	for _, header := range []string{"Bcc", "X-Injected"} {
+		if got := msg.Header.Get(header); got != "" {
+			t.Errorf("Post title injected header %s: %q", header, got)
+		}
+	}
+	if got := msg.Header.Get("To"); got != "alice@example.com" {
+		t.Errorf("To is %q", got)
+	}
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil {
+		t.Fatalf("Failed to decode subject: %v", err)
+	}
+	if want := "[go] Café: Go 2 Bcc: victim@example.com X-Injected: yes"; subject != want {
+		t.Errorf("Subject is %q, want %q", subject, want)
+	}
+}
+
+func TestEmailNotifierTarget(t *testing.T) {
+	s := &state{cfg: &config.Config{SMTP: &config.SMTPConfig{Host: "localhost", Port: 25, From: "gator@example.com"}}}
+
+	for _, target := range []string{"", "alice", "alice@example.com\r\nBcc: victim@example.com"} {
+		_, err := notifiers["email"](s, target)
+		if err == nil {
+			t.Errorf("Email target %q was accepted", target)
+		}
+	}
+
+	n, err := notifiers["email"](s, "Alice <alice@example.com>")
+	if err != nil {
+		t.Fatalf("Valid email target rejected: %v", err)
+	}
+	if to := n.(emailNotifier).to; to != "alice@example.com" {
+		t.Errorf("Notifier sends to %q", to)
+	}
+}
+
+func TestAlertRuleMatches(t *testing.T) {
+	s := newTestState(t)
+	feed := database.Feed{Name: "Go Blog", Url: "https://go.dev/blog/feed.atom"}
+	item := RSSItem{Title: "Go 1.25 is released", Description: "Generic type aliases", Creator: "Gopher Team"}
+
+	tests := []struct {
+		kind string
+		pattern string
+		want bool
+	}{
+		{kind: "keyword", pattern: "RELEASED", want: true},
+		{kind: "keyword", pattern: "rust", want: false},
+		{kind: "regex", pattern: `go \d+\.\d+`, want: true},
+		{kind: "regex", pattern: `^GENERIC`, want: false},
+		{kind: "regex", pattern: `(?m)^GENERIC`, want: true},
+		{kind: "author", pattern: "gopher", want: true},
+		{kind: "feed", pattern: "go blog", want: true},
+		{kind: "feed", pattern: "https://go.dev/blog/feed.atom", want: true},
+	}
+
+	for _, tt := range tests {
+		rule, err := compileRule(s, database.AlertRule{Kind: tt.kind, Pattern: tt.pattern, Notifier: "terminal"})
+		if err != nil {
+			t.Fatalf("Failed to compile %s rule `%s`: %v", tt.kind, tt.pattern, err)
+		}
+		if got := rule.matches(feed, item); got != tt.want {
+			t.Errorf("%s rule `%s` matched %v, want %v", tt.kind, tt.pattern, got, tt.want)
+		}
+	}
+}
+
+func TestEvaluateAlertsDoesNotWait(t *testing.T) {
+	s := newTestState(t)
+
+	release := make(chan struct{})
+	received := make(chan struct{}, 1)
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		received <- struct{}{}
+	}))
+	t.Cleanup(hook.Close)
+
+	rule, err := compileRule(s, database.AlertRule{ID: uuid.New(), Name: "all", Kind: "keyword", Pattern: "", Notifier: "webhook", Target: hook.URL})
+	if err != nil {
+		t.Fatalf("Failed to compile rule: %v", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		evaluateAlerts(context.Background(), s, database.Feed{Name: "Slow"}, database.Post{Title: "Post"}, RSSItem{Title: "Post"}, []compiledRule{rule})
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("evaluateAlerts waited for the webhook to answer")
+	}
+
+	close(release)
+	s.notifications.Wait()
+
+	select {
+	case <-received:
+	default:
+		t.Error("Webhook notification was never sent")
+	}
+}
+
+func TestEmailNotifierTimeout(t *testing.T) {
+	// Accepts connections but never sends the SMTP greeting
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err == nil {
+			accepted <- conn
+		}
+	}()
+	t.Cleanup(func() {
+		l.Close()
+		select {
+		case conn := <-accepted:
+			conn.Close()
+		default:
+		}
+	})
+
+	addr := l.Addr().(*net.TCPAddr)
+	s := &state{cfg: &config.Config{SMTP: &config.SMTPConfig{Host: "127.0.0.1", Port: addr.Port, From: "gator@example.com"}}}
+	n := emailNotifier{s: s, to: "alice@example.com"}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err = n.notify(ctx, alert{})
+	if err == nil {
+		t.Fatal("Sending to a silent server succeeded")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Sending gave up after %v, want about 100ms", elapsed)
+	}
+}
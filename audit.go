@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/matt-horst/blog-agg/internal/config"
+	"github.com/matt-horst/blog-agg/internal/database"
+
+	"github.com/google/uuid"
+)
+
+const defaultAuditLimit = 50
+
+// Records that the user ran the command, and whether it worked. Failing to
+// record is logged but doesn't fail the command, which has already run.
+func recordAudit(ctx context.Context, s *state, user database.User, cmd command, cmdErr error) {
+	outcome := "success"
+	errText := ""
+	if cmdErr != nil {
+		outcome = "failure"
+		errText = cmdErr.Error()
+	}
+
+	err := s.db.CreateAuditEvent(
+		ctx,
+		database.CreateAuditEventParams{
+			ID: uuid.New(),
+			ActorID: uuid.NullUUID{UUID: user.ID, Valid: user.ID != uuid.Nil},
+			ActorName: user.Name,
+			Command: cmd.name,
+			Args: auditArgs(cmd),
+			Outcome: outcome,
+			Error: errText,
+		},
+	)
+	if err != nil {
+		slog.Warn("Failed to record audit event", slog.String("command", cmd.name), errAttrs(err))
+	}
+}
+
+// The arguments to record for cmd, with the secrets it sets hidden
+func auditArgs(cmd command) []string {
+	args := slices.Clone(cmd.args)
+
+	switch {
+	case cmd.name == "config" && len(args) == 3 && args[0] == "set":
+		for _, f := range config.Fields() {
+			if f.Key == args[1] && f.Secret && args[2] != "" {
+				args[2] = "********"
+			}
+		}
+		if args[1] == "db_url" {
+			args[2] = redactPassword(args[2])
+		}
+	case cmd.name == "profile" && len(args) == 3 && args[0] == "add":
+		args[2] = redactPassword(args[2])
+	}
+
+	return args
+}
+
+// Hides the password in a database URL, if it has one
+func redactPassword(dbURL string) string {
+	u, err := url.Parse(dbURL)
+	if err != nil {
+		return dbURL
+	}
+
+	return u.Redacted()
+}
+
+// Records every run of the wrapped command in the audit log
+func middlewareAudit(handler func(ctx context.Context, s *state, cmd command, user database.User) error) func(ctx context.Context, s *state, cmd command, user database.User) error {
+	return func(ctx context.Context, s *state, cmd command, user database.User) error {
+		err := handler(ctx, s, cmd, user)
+		recordAudit(ctx, s, user, cmd, err)
+
+		return err
+	}
+}
+
+// Audits commands that don't require a login. The actor is whoever holds the
+// session after the command (e.g. a successful login) or before it (e.g.
+// logout). Failed logins have no session, so the name they tried is recorded
+// instead.
+func middlewareAuditAnonymous(handler func(ctx context.Context, s *state, cmd command) error) func(ctx context.Context, s *state, cmd command) error {
+	return func(ctx context.Context, s *state, cmd command) error {
+		before, _ := sessionUser(ctx, s)
+
+		err := handler(ctx, s, cmd)
+
+		actor, sessionErr := sessionUser(ctx, s)
+		if sessionErr != nil {
+			actor = before
+		}
+		if actor.Name == "" && len(cmd.args) > 0 && (cmd.name == "login" || cmd.name == "register") {
+			actor.Name = cmd.args[0]
+		}
+
+		recordAudit(ctx, s, actor, cmd, err)
+
+		return err
+	}
+}
+
+// Audits the runs of a command that change something, e.g. `config set` but
+// not `config get`. Nothing is recorded while the profile has no database to
+// record it in, or when it couldn't be opened.
+func middlewareAuditSubcommands(subcommands []string, handler func(ctx context.Context, s *state, cmd command) error) func(ctx context.Context, s *state, cmd command) error {
+	audited := middlewareAuditAnonymous(handler)
+
+	return func(ctx context.Context, s *state, cmd command) error {
+		if s.cfg.DbURL == "" || s.db == nil || len(cmd.args) == 0 || !slices.Contains(subcommands, cmd.args[0]) {
+			return handler(ctx, s, cmd)
+		}
+
+		return audited(ctx, s, cmd)
+	}
+}
+
+// Shows recent audit events, newest first. Members only see their own.
+func handlerAudit(ctx context.Context, s *state, cmd command, user database.User) error {
+	fs := flag.NewFlagSet("audit", flag.ContinueOnError)
+	userName := fs.String("user", "", "only show events caused by this user (admins only)")
+	commandName := fs.String("command", "", "only show runs of this command, e.g. deletefeed")
+	since := fs.String("since", "", "only show events on or after this date")
+	until := fs.String("until", "", "only show events before this date")
+	failed := fs.Bool("failed", false, "only show commands that failed")
+	limit := fs.Int("limit", defaultAuditLimit, "maximum number of events to show")
+
+	positional, err := parseFlags(fs, cmd.args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 0 {
+		return fmt.Errorf("audit takes no arguments besides flags")
+	}
+	if *limit <= 0 {
+		return fmt.Errorf("--limit must be positive")
+	}
+
+	params := database.GetAuditEventsParams{
+		FailuresOnly: *failed,
+		RowLimit: int32(*limit),
+	}
+
+	switch {
+	case !isAdmin(user):
+		if *userName != "" && *userName != user.Name {
+			return fmt.Errorf("Only admins can see what other users did")
+		}
+		params.ActorID = uuid.NullUUID{UUID: user.ID, Valid: true}
+	case *userName != "":
+		// Match deleted users by the name they had, since their ID is gone
+		actor, err := s.db.GetUser(ctx, *userName)
+		if err == nil {
+			params.ActorID = uuid.NullUUID{UUID: actor.ID, Valid: true}
+		} else {
+			params.ActorName = sql.NullString{String: *userName, Valid: true}
+		}
+	}
+
+	if *commandName != "" {
+		params.Command = sql.NullString{String: *commandName, Valid: true}
+	}
+	if *since != "" {
+		date, err := parseDate(*since)
+		if err != nil {
+			return err
+		}
+		params.Since = sql.NullTime{Time: date, Valid: true}
+	}
+	if *until != "" {
+		date, err := parseDate(*until)
+		if err != nil {
+			return err
+		}
+		params.Until = sql.NullTime{Time: date, Valid: true}
+	}
+
+	events, err := s.db.GetAuditEvents(ctx, params)
+	if err != nil {
+		return fmt.Errorf("Failed to get audit events: %v", err)
+	}
+
+	for _, e := range events {
+		actor := e.ActorName
+		if actor == "" {
+			actor = "(unknown)"
+		}
+
+		fmt.Printf("%s  %s  %s", e.CreatedAt.Format(time.DateTime), actor, strings.Join(append([]string{e.Command}, e.Args...), " "))
+		if e.Outcome == "failure" {
+			fmt.Printf("  FAILED: %s", e.Error)
+		}
+		fmt.Println()
+	}
+
+	return nil
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/matt-horst/blog-agg/internal/database"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/term"
+)
+
+const sessionLifetime = 30 * 24 * time.Hour
+
+const minPasswordLength = 8
+
+// Shared so consecutive prompts read consecutive lines when stdin is piped
+var stdinReader = bufio.NewReader(os.Stdin)
+
+// Prompts for a password without echoing it. When stdin isn't a terminal the
+// password is read as a line, so scripts can pipe it in.
+func readPassword(prompt string) (string, error) {
+	fmt.Fprint(os.Stderr, prompt)
+
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) {
+		password, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", fmt.Errorf("Failed to read password: %v", err)
+		}
+		return string(password), nil
+	}
+
+	line, err := stdinReader.ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("Failed to read password: %v", err)
+	}
+
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// Prompts for a new password twice and returns its hash
+func readNewPassword() (sql.NullString, error) {
+	password, err := readPassword("New password: ")
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	if len(password) < minPasswordLength {
+		return sql.NullString{}, fmt.Errorf("Password must be at least %d characters", minPasswordLength)
+	}
+
+	confirm, err := readPassword("Repeat new password: ")
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	if password != confirm {
+		return sql.NullString{}, fmt.Errorf("Passwords don't match")
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return sql.NullString{}, fmt.Errorf("Failed to hash password: %v", err)
+	}
+
+	return sql.NullString{String: string(hash), Valid: true}, nil
+}
+
+// Prompts for the user's password and checks it
+func verifyPassword(user database.User) error {
+	password, err := readPassword(fmt.Sprintf("Password for %s: ", user.Name))
+	if err != nil {
+		return err
+	}
+
+	err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash.String), []byte(password))
+	if err != nil {
+		return fmt.Errorf("Incorrect password for `%s`", user.Name)
+	}
+
+	return nil
+}
+
+func hashSessionToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Creates a session for the user and stores its token in the config. Expired
+// sessions are cleared out at the same time so they don't pile up.
+func startSession(ctx context.Context, s *state, user database.User) error {
+	expired, err := s.db.DeleteExpiredSessions(ctx)
+	if err != nil {
+		return fmt.Errorf("Failed to delete expired sessions: %v", err)
+	}
+	if expired > 0 {
+		slog.Debug("Deleted expired sessions", slog.Int64("count", expired))
+	}
+
+	raw := make([]byte, 32)
+	_, err = rand.Read(raw)
+	if err != nil {
+		return fmt.Errorf("Failed to generate session token: %v", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+
+	err = s.db.CreateSession(
+		ctx,
+		database.CreateSessionParams{
+			ID: uuid.New(),
+			ExpiresAt: time.Now().Add(sessionLifetime),
+			UserID: user.ID,
+			TokenHash: hashSessionToken(token),
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("Failed to create session: %v", err)
+	}
+
+	err = s.cfg.SetSession(user.Name, token)
+	if err != nil {
+		return fmt.Errorf("Failed to save session: %v", err)
+	}
+	s.cfg.CurrentUserName = user.Name
+	s.cfg.SessionToken = token
+
+	return nil
+}
+
+// The user the config's session token belongs to
+func sessionUser(ctx context.Context, s *state) (database.User, error) {
+	if s.cfg.SessionToken == "" {
+		return database.User{}, fmt.Errorf("Not logged in: run `login <name>` first")
+	}
+
+	user, err := s.db.GetUserBySession(ctx, hashSessionToken(s.cfg.SessionToken))
+	if errors.Is(err, sql.ErrNoRows) {
+		return database.User{}, fmt.Errorf("Session expired or revoked: run `login <name>` again")
+	}
+	if err != nil {
+		return database.User{}, fmt.Errorf("Failed to check session: %v", err)
+	}
+
+	return user, nil
+}
+
+// Changes the current user's password and signs out their other sessions
+func handlerPasswd(ctx context.Context, s *state, cmd command, user database.User) error {
+	if user.PasswordHash.Valid {
+		err := verifyPassword(user)
+		if err != nil {
+			return err
+		}
+	}
+
+	hash, err := readNewPassword()
+	if err != nil {
+		return err
+	}
+
+	err = s.db.SetUserPassword(ctx, database.SetUserPasswordParams{ID: user.ID, PasswordHash: hash})
+	if err != nil {
+		return fmt.Errorf("Failed to set password: %v", err)
+	}
+
+	err = s.db.DeleteOtherSessions(ctx, database.DeleteOtherSessionsParams{UserID: user.ID, TokenHash: hashSessionToken(s.cfg.SessionToken)})
+	if err != nil {
+		return fmt.Errorf("Password changed, but failed to sign out other sessions: %v", err)
+	}
+
+	fmt.Println("Password changed; other sessions have been signed out")
+
+	return nil
+}
+
+// Sets another user's password, signing out their sessions. Only admins can,
+// except while no admin has a password at all, as in a database from before
+// passwords existed: then no one can log in, so an admin may set their own
+// first password from the config they were logged in with back then.
+func handlerSetPassword(ctx context.Context, s *state, cmd command) error {
+	if len(cmd.args) != 1 {
+		return fmt.Errorf("setpassword requires a user name")
+	}
+
+	target, err := s.db.GetUser(ctx, cmd.args[0])
+	if err != nil {
+		return fmt.Errorf("Unable to find user `%s`: %v", cmd.args[0], err)
+	}
+
+	users, err := s.db.GetUsers(ctx)
+	if err != nil {
+		return fmt.Errorf("Failed to get users: %v", err)
+	}
+	bootstrap := true
+	for _, u := range users {
+		if isAdmin(u) && u.PasswordHash.Valid {
+			bootstrap = false
+			break
+		}
+	}
+
+	if bootstrap {
+		if !isAdmin(target) {
+			return fmt.Errorf("No admin has a password yet: set one for an admin first")
+		}
+		if s.cfg.CurrentUserName != target.Name {
+			return fmt.Errorf("No admin has a password yet: only `%s` can set their own first password", target.Name)
+		}
+	} else {
+		user, err := sessionUser(ctx, s)
+		if err != nil {
+			return err
+		}
+		if !isAdmin(user) {
+			return fmt.Errorf("`%s` can only be run by an admin", cmd.name)
+		}
+	}
+
+	hash, err := readNewPassword()
+	if err != nil {
+		return err
+	}
+
+	err = s.db.SetUserPassword(ctx, database.SetUserPasswordParams{ID: target.ID, PasswordHash: hash})
+	if err != nil {
+		return fmt.Errorf("Failed to set password: %v", err)
+	}
+
+	err = s.db.DeleteOtherSessions(ctx, database.DeleteOtherSessionsParams{UserID: target.ID, TokenHash: hashSessionToken(s.cfg.SessionToken)})
+	if err != nil {
+		return fmt.Errorf("Password set, but failed to sign out `%s`: %v", target.Name, err)
+	}
+
+	fmt.Printf("Set password for `%s`\n", target.Name)
+
+	return nil
+}
+
+func handlerLogout(ctx context.Context, s *state, cmd command) error {
+	if s.cfg.SessionToken != "" {
+		err := s.db.DeleteSession(ctx, hashSessionToken(s.cfg.SessionToken))
+		if err != nil {
+			return fmt.Errorf("Failed to end session: %v", err)
+		}
+	}
+
+	err := s.cfg.SetSession("", "")
+	if err != nil {
+		return fmt.Errorf("Failed to clear session: %v", err)
+	}
+
+	fmt.Println("Logged out")
+
+	return nil
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"strings"
+	"testing"
+)
+
+// Feeds lines to the password prompts as if they were piped in
+func withStdin(t *testing.T, lines ...string) {
+	t.Helper()
+
+	reader := stdinReader
+	stdinReader = bufio.NewReader(strings.NewReader(strings.Join(lines, "\n") + "\n"))
+	t.Cleanup(func() { stdinReader = reader })
+}
+
+func runCommand(t *testing.T, handler func(context.Context, *state, command) error, s *state, name string, args ...string) error {
+	t.Helper()
+
+	var err error
+	captureStdout(t, func() {
+		err = handler(context.Background(), s, command{name: name, args: args})
+	})
+
+	return err
+}
+
+func TestPasswords(t *testing.T) {
+	ctx := context.Background()
+	s := newTestState(t)
+
+	withStdin(t, "short", "short")
+	err := runCommand(t, handlerRegister, s, "register", "alice")
+	if err == nil || !strings.Contains(err.Error(), "at least") {
+		t.Errorf("Registered with a short password: %v", err)
+	}
+
+	withStdin(t, "correct horse", "battery staple")
+	err = runCommand(t, handlerRegister, s, "register", "alice")
+	if err == nil || !strings.Contains(err.Error(), "don't match") {
+		t.Errorf("Registered with mismatched passwords: %v", err)
+	}
+
+	withStdin(t, "correct horse", "correct horse")
+	err = runCommand(t, handlerRegister, s, "register", "alice")
+	if err != nil {
+		t.Fatalf("Failed to register: %v", err)
+	}
+	user, err := sessionUser(ctx, s)
+	if err != nil || user.Name != "alice" {
+		t.Fatalf("Registering signed in as `%s` (%v), want alice", user.Name, err)
+	}
+	firstToken := s.cfg.SessionToken
+
+	withStdin(t, "wrong horse")
+	err = runCommand(t, handlerLogin, s, "login", "alice")
+	if err == nil || !strings.Contains(err.Error(), "Incorrect password") {
+		t.Errorf("Logged in with the wrong password: %v", err)
+	}
+
+	withStdin(t, "correct horse")
+	err = runCommand(t, handlerLogin, s, "login", "alice")
+	if err != nil {
+		t.Fatalf("Failed to log in: %v", err)
+	}
+	if s.cfg.SessionToken == firstToken {
+		t.Error("Logging in reused the previous session token")
+	}
+
+	// Changing the password signs out every other session
+	withStdin(t, "correct horse", "new password", "new password")
+	_, err = runHandler(t, handlerPasswd, s, user)
+	if err != nil {
+		t.Fatalf("passwd failed: %v", err)
+	}
+	_, err = s.db.GetUserBySession(ctx, hashSessionToken(firstToken))
+	if err == nil {
+		t.Error("Older session survived a password change")
+	}
+	_, err = sessionUser(ctx, s)
+	if err != nil {
+		t.Errorf("Current session was signed out by passwd: %v", err)
+	}
+
+	// Accounts without a password are issued one by an admin
+	bob := createTestUser(t, s, "bob")
+	err = runCommand(t, handlerLogin, s, "login", "bob")
+	if err == nil || !strings.Contains(err.Error(), "no password yet") {
+		t.Errorf("Logged in to an account with no password: %v", err)
+	}
+	withStdin(t, "bobs password", "bobs password")
+	err = runCommand(t, handlerSetPassword, s, "setpassword", "bob")
+	if err != nil {
+		t.Fatalf("Admin failed to set a password: %v", err)
+	}
+	withStdin(t, "bobs password")
+	err = runCommand(t, handlerLogin, s, "login", "bob")
+	if err != nil {
+		t.Fatalf("Failed to log in with the issued password: %v", err)
+	}
+
+	withStdin(t, "stolen password", "stolen password")
+	err = runCommand(t, handlerSetPassword, s, "setpassword", "alice")
+	if err == nil || !strings.Contains(err.Error(), "only be run by an admin") {
+		t.Errorf("Member set an admin's password: %v", err)
+	}
+
+	token := s.cfg.SessionToken
+	err = runCommand(t, handlerLogout, s, "logout")
+	if err != nil {
+		t.Fatalf("Failed to log out: %v", err)
+	}
+	_, err = s.db.GetUserBySession(ctx, hashSessionToken(token))
+	if err == nil {
+		t.Errorf("Session of `%s` survived logging out", bob.Name)
+	}
+}
+
+func TestSetPasswordBootstrap(t *testing.T) {
+	ctx := context.Background()
+	s := newTestState(t)
+
+	// As in a database from before passwords: no one has one, and the
+	// config only names who was logged in
+	alice := createTestUser(t, s, "alice")
+	createTestUser(t, s, "bob")
+	if !isAdmin(alice) {
+		t.Fatalf("First user is a %s, want an admin", alice.Role)
+	}
+
+	s.cfg.CurrentUserName = "bob"
+	withStdin(t, "stolen password", "stolen password")
+	err := runCommand(t, handlerSetPassword, s, "setpassword", "alice")
+	if err == nil || !strings.Contains(err.Error(), "only `alice`") {
+		t.Errorf("Member set the admin's first password: %v", err)
+	}
+	withStdin(t, "bobs password", "bobs password")
+	err = runCommand(t, handlerSetPassword, s, "setpassword", "bob")
+	if err == nil || !strings.Contains(err.Error(), "set one for an admin first") {
+		t.Errorf("Member set their own password before any admin: %v", err)
+	}
+	assertNoPasswords(t, s)
+
+	s.cfg.CurrentUserName = "alice"
+	withStdin(t, "correct horse", "correct horse")
+	err = runCommand(t, handlerSetPassword, s, "setpassword", "alice")
+	if err != nil {
+		t.Fatalf("Admin failed to set their first password: %v", err)
+	}
+	alice, err = s.db.GetUser(ctx, "alice")
+	if err != nil || !alice.PasswordHash.Valid {
+		t.Fatalf("Admin has no password after bootstrap (%v)", err)
+	}
+
+	// Once an admin has a password, it takes their session
+	withStdin(t, "bobs password", "bobs password")
+	err = runCommand(t, handlerSetPassword, s, "setpassword", "bob")
+	if err == nil || !strings.Contains(err.Error(), "Not logged in") {
+		t.Errorf("Set a password without a session after bootstrap: %v", err)
+	}
+}
+
+func assertNoPasswords(t *testing.T, s *state) {
+	t.Helper()
+
+	users, err := s.db.GetUsers(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to get users: %v", err)
+	}
+	for _, u := range users {
+		if u.PasswordHash.Valid {
+			t.Errorf("`%s` has a password", u.Name)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/matt-horst/blog-agg/internal/config"
+)
+
+// Shows or changes settings of the current profile:
+//
+//	config
+//	config get <key>
+//	config set <key> <value>
+//	config validate
+//	config path
+func handlerConfig(ctx context.Context, s *state, cmd command) error {
+	if len(cmd.args) == 0 {
+		return printConfig(s)
+	}
+
+	switch sub, args := cmd.args[0], cmd.args[1:]; sub {
+	case "get":
+		if len(args) != 1 {
+			return fmt.Errorf("config get requires a setting, e.g. db_url")
+		}
+		value, err := s.cfg.Get(args[0])
+		if err != nil {
+			return err
+		}
+		fmt.Println(value)
+
+	case "set":
+		if len(args) != 2 {
+			return fmt.Errorf("config set requires two arguments: setting value (use \"\" to clear)")
+		}
+		updated, err := s.cfg.Set(args[0], args[1])
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Set %s in profile `%s`\n", args[0], s.cfg.Profile())
+
+		for _, f := range config.Fields() {
+			if _, ok := os.LookupEnv(f.EnvVar); f.Key == args[0] && ok {
+				fmt.Printf("Note: %s overrides this until it is unset\n", f.EnvVar)
+			}
+		}
+		printProblems(updated.Validate())
+
+	case "validate":
+		if len(args) != 0 {
+			return fmt.Errorf("config validate takes no arguments")
+		}
+		err := s.cfg.Validate()
+		if err != nil {
+			printProblems(err)
+			return fmt.Errorf("Config for profile `%s` is invalid", s.cfg.Profile())
+		}
+		fmt.Printf("Config for profile `%s` is valid\n", s.cfg.Profile())
+
+	case "path":
+		filepath, err := config.Path()
+		if err != nil {
+			return err
+		}
+		fmt.Println(filepath)
+
+	default:
+		return fmt.Errorf("Unknown config subcommand `%s`: expected get, set, validate or path", sub)
+	}
+
+	return nil
+}
+
+// Lists every setting with its effective value. Secrets are hidden.
+func printConfig(s *state) error {
+	fmt.Printf("Profile: %s\n", s.cfg.Profile())
+
+	for _, f := range config.Fields() {
+		value, err := s.cfg.Get(f.Key)
+		if err != nil {
+			return err
+		}
+		if f.Secret && value != "" {
+			value = "********"
+		}
+
+		source := ""
+		if _, ok := os.LookupEnv(f.EnvVar); ok {
+			source = fmt.Sprintf(" (from %s)", f.EnvVar)
+		}
+
+		fmt.Printf("* %s = %s%s\n", f.Key, value, source)
+	}
+
+	return nil
+}
+
+func printProblems(err error) {
+	if err == nil {
+		return
+	}
+
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		fmt.Printf("Problem: %v\n", err)
+		return
+	}
+
+	for _, problem := range joined.Unwrap() {
+		fmt.Printf("Problem: %v\n", problem)
+	}
+}
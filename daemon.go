@@ -0,0 +1,340 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// Key for the advisory lock held by a running aggregator
+const aggLockKey = 0x6761746f72 // "gator"
+
+const (
+	defaultHealthAddr = "127.0.0.1:9190"
+	defaultLogMaxSizeMB = 50
+	defaultLogMaxBackups = 5
+)
+
+type aggOptions struct {
+	interval time.Duration
+	daemon bool
+	pidFile string
+	logFile string
+	healthAddr string
+	metricsAddr string
+	dueAfter time.Duration
+	prunePosts bool
+}
+
+func parseAggOptions(args []string) (aggOptions, error) {
+	opts := aggOptions{}
+
+	fs := flag.NewFlagSet("agg", flag.ContinueOnError)
+	fs.BoolVar(&opts.daemon, "daemon", false, "run as a long-lived service with pidfile, log file and health endpoint")
+	fs.StringVar(&opts.pidFile, "pidfile", "", "write the process ID to this file")
+	fs.StringVar(&opts.logFile, "log-file", "", "write logs to this file, rotating it as it grows")
+	fs.StringVar(&opts.healthAddr, "health-addr", "", "serve /healthz and /readyz on this address")
+	fs.StringVar(&opts.metricsAddr, "metrics-addr", "", "serve Prometheus /metrics on this address (may match -health-addr)")
+	fs.DurationVar(&opts.dueAfter, "due-after", time.Hour, "count feeds not fetched within this long as queued")
+	fs.BoolVar(&opts.prunePosts, "prune-posts", false, "periodically delete posts outside the retention policies")
+
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return aggOptions{}, err
+	}
+
+	if len(positional) != 1 {
+		return aggOptions{}, fmt.Errorf("agg requires time between requests argument")
+	}
+
+	opts.interval, err = time.ParseDuration(positional[0])
+	if err != nil {
+		return aggOptions{}, fmt.Errorf("Failed to parse `%s` as a duration: %v", positional[0], err)
+	}
+
+	if opts.daemon {
+		if opts.pidFile == "" {
+			opts.pidFile = filepath.Join(runtimeDir(), "gator-agg.pid")
+		}
+		if opts.logFile == "" {
+			opts.logFile = filepath.Join(runtimeDir(), "gator-agg.log")
+		}
+		if opts.healthAddr == "" {
+			opts.healthAddr = defaultHealthAddr
+		}
+	}
+
+	return opts, nil
+}
+
+// Whether any of the long-running service features were asked for
+func (opts aggOptions) wantsDaemon() bool {
+	return opts.daemon || opts.pidFile != "" || opts.logFile != "" || opts.healthAddr != "" || opts.metricsAddr != ""
+}
+
+func runtimeDir() string {
+	dir := os.Getenv("XDG_RUNTIME_DIR")
+	if dir == "" {
+		dir = os.TempDir()
+	}
+
+	return dir
+}
+
+// What the health endpoint reports about the running aggregator
+type aggStatus struct {
+	mu sync.Mutex
+	startedAt time.Time
+	lastSuccess time.Time
+	lastError string
+	lastErrorAt time.Time
+	scrapes int
+}
+
+func (st *aggStatus) record(err error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	st.scrapes++
+	if err != nil {
+		st.lastError = err.Error()
+		st.lastErrorAt = time.Now()
+		return
+	}
+
+	st.lastSuccess = time.Now()
+	metricLastSuccess.SetToCurrentTime()
+}
+
+type healthReport struct {
+	Status string 				`json:"status"`
+	StartedAt time.Time 		`json:"started_at"`
+	LastSuccessfulFetch *time.Time `json:"last_successful_fetch"`
+	LastError string 			`json:"last_error,omitempty"`
+	LastErrorAt *time.Time 		`json:"last_error_at,omitempty"`
+	Scrapes int 				`json:"scrapes"`
+	QueueDepth int64 			`json:"queue_depth"`
+}
+
+// Builds a report; the aggregator is ready once it has fetched successfully
+// within the last few intervals (or is still within its first few)
+func (st *aggStatus) report(ctx context.Context, s *state, opts aggOptions) (healthReport, bool) {
+	st.mu.Lock()
+	report := healthReport{
+		Status: "ok",
+		StartedAt: st.startedAt,
+		LastError: st.lastError,
+		Scrapes: st.scrapes,
+	}
+	if !st.lastSuccess.IsZero() {
+		lastSuccess := st.lastSuccess
+		report.LastSuccessfulFetch = &lastSuccess
+	}
+	if !st.lastErrorAt.IsZero() {
+		lastErrorAt := st.lastErrorAt
+		report.LastErrorAt = &lastErrorAt
+	}
+	reference := st.startedAt
+	if !st.lastSuccess.IsZero() {
+		reference = st.lastSuccess
+	}
+	st.mu.Unlock()
+
+	ready := time.Since(reference) < 3*opts.interval
+
+	queueDepth, err := s.db.CountFeedsDue(
+		ctx,
+		sql.NullTime{Time: time.Now().Add(-opts.dueAfter), Valid: true},
+	)
+	if err != nil {
+		report.LastError = fmt.Sprintf("Failed to count queued feeds: %v", err)
+		ready = false
+	}
+	report.QueueDepth = queueDepth
+
+	if !ready {
+		report.Status = "unavailable"
+	}
+
+	return report, ready
+}
+
+func registerHealthHandlers(mux *http.ServeMux, s *state, opts aggOptions, status *aggStatus) {
+	writeReport := func(w http.ResponseWriter, r *http.Request, requireReady bool) {
+		report, ready := status.report(r.Context(), s, opts)
+
+		code := http.StatusOK
+		if requireReady && !ready {
+			code = http.StatusServiceUnavailable
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		json.NewEncoder(w).Encode(report)
+	}
+
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		writeReport(w, r, false)
+	})
+	mux.HandleFunc("GET /readyz", func(w http.ResponseWriter, r *http.Request) {
+		writeReport(w, r, true)
+	})
+}
+
+// Binds addr before returning, so a port that's taken or invalid fails the
+// start rather than only being logged once the daemon is running
+func startHTTPServer(addr string, mux *http.ServeMux) (*http.Server, error) {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to listen on `%s`: %v", addr, err)
+	}
+
+	server := &http.Server{Addr: addr, Handler: mux}
+
+	go func() {
+		err := server.Serve(l)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("HTTP server failed", slog.String("addr", addr), errAttrs(err))
+		}
+	}()
+
+	return server, nil
+}
+
+// Sets up pidfile, log file, health and metrics endpoints as requested. The returned
+// function undoes all of it.
+func startDaemon(ctx context.Context, s *state, opts aggOptions, status *aggStatus) (func(), error) {
+	cleanups := []func(){}
+	cleanup := func() {
+		for i := len(cleanups) - 1; i >= 0; i-- {
+			cleanups[i]()
+		}
+	}
+
+	if opts.logFile != "" {
+		logger := &lumberjack.Logger{
+			Filename: opts.logFile,
+			MaxSize: defaultLogMaxSizeMB,
+			MaxBackups: defaultLogMaxBackups,
+			Compress: true,
+		}
+		setupLogging(logger, s.logCfg)
+		cleanups = append(cleanups, func() {
+			setupLogging(os.Stderr, s.logCfg)
+			logger.Close()
+		})
+	}
+
+	if opts.pidFile != "" {
+		err := writePidFile(opts.pidFile)
+		if err != nil {
+			cleanup()
+			return nil, err
+		}
+		cleanups = append(cleanups, func() {
+			os.Remove(opts.pidFile)
+		})
+	}
+
+	// Health and metrics share a server when given the same address
+	muxes := map[string]*http.ServeMux{}
+	muxFor := func(addr string) *http.ServeMux {
+		if muxes[addr] == nil {
+			muxes[addr] = http.NewServeMux()
+		}
+		return muxes[addr]
+	}
+
+	if opts.healthAddr != "" {
+		registerHealthHandlers(muxFor(opts.healthAddr), s, opts, status)
+		slog.Info("Serving health checks", slog.String("addr", opts.healthAddr))
+	}
+
+	if opts.metricsAddr != "" {
+		err := registerStalestFeedMetric(context.WithoutCancel(ctx), s)
+		if err != nil {
+			cleanup()
+			return nil, fmt.Errorf("Failed to register metrics: %v", err)
+		}
+		muxFor(opts.metricsAddr).Handle("GET /metrics", promhttp.Handler())
+		slog.Info("Serving metrics", slog.String("addr", opts.metricsAddr))
+	}
+
+	for addr, mux := range muxes {
+		server, err := startHTTPServer(addr, mux)
+		if err != nil {
+			cleanup()
+			return nil, err
+		}
+		cleanups = append(cleanups, func() {
+			shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), shutdownTimeout)
+			defer cancel()
+			server.Shutdown(shutdownCtx)
+		})
+	}
+
+	return cleanup, nil
+}
+
+// Writes the current PID, refusing if the file names another live process
+func writePidFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+		if err == nil && pid != os.Getpid() && syscall.Kill(pid, 0) == nil {
+			return fmt.Errorf("Aggregator already running with PID %d (pidfile `%s`)", pid, path)
+		}
+	}
+
+	err = os.WriteFile(path, []byte(fmt.Sprintf("%d\n", os.Getpid())), 0644)
+	if err != nil {
+		return fmt.Errorf("Failed to write pidfile `%s`: %v", path, err)
+	}
+
+	return nil
+}
+
+// Takes a session-level advisory lock on a dedicated connection so only one
+// aggregator runs per database. The lock is released when the returned
+// function is called or the connection drops (for SQLite, when the process
+// exits).
+func acquireAggregatorLock(ctx context.Context, s *state) (func(), error) {
+	q, release, err := s.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get database connection for lock: %v", err)
+	}
+
+	locked, err := q.TryAdvisoryLock(ctx, aggLockKey)
+	if err != nil {
+		release()
+		return nil, fmt.Errorf("Failed to take aggregator lock: %v", err)
+	}
+	if !locked {
+		release()
+		return nil, fmt.Errorf("Another aggregator is already running against this database")
+	}
+
+	return func() {
+		_, err := q.AdvisoryUnlock(context.WithoutCancel(ctx), aggLockKey)
+		if err != nil {
+			slog.Error("Failed to release aggregator lock", errAttrs(err))
+		}
+		release()
+	}, nil
+}
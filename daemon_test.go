@@ -0,0 +1,32 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+)
+
+func TestStartHTTPServer(t *testing.T) {
+	taken, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { taken.Close() })
+
+	_, err = startHTTPServer(taken.Addr().String(), http.NewServeMux())
+	if err == nil {
+		t.Error("Starting on a port in use succeeded")
+	}
+
+	_, err = startHTTPServer("127.0.0.1:not-a-port", http.NewServeMux())
+	if err == nil {
+		t.Error("Starting on an invalid address succeeded")
+	}
+
+	server, err := startHTTPServer("127.0.0.1:0", http.NewServeMux())
+	if err != nil {
+		t.Fatalf("Failed to start: %v", err)
+	}
+	t.Cleanup(func() { server.Shutdown(context.Background()) })
+}
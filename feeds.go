@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"time"
+
+	"github.com/matt-horst/blog-agg/internal/database"
+)
+
+// Whether the user may change a feed everyone shares
+func canManageFeed(user database.User, feed database.Feed) bool {
+	return feed.UserID == user.ID || isAdmin(user)
+}
+
+// Looks up a feed by URL and checks the user may manage it
+func findManagedFeed(ctx context.Context, s *state, user database.User, feedURL string) (database.Feed, error) {
+	feed, err := s.db.GetFeed(ctx, feedURL)
+	if err != nil {
+		return database.Feed{}, fmt.Errorf("Unable to find feed `%s`: %v", feedURL, err)
+	}
+
+	if !canManageFeed(user, feed) {
+		return database.Feed{}, fmt.Errorf("Only the user who added `%s` or an admin can change it", feed.Name)
+	}
+
+	return feed, nil
+}
+
+func validateFeedURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("Invalid URL `%s`: %v", rawURL, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("Invalid URL `%s`: must start with http:// or https://", rawURL)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("Invalid URL `%s`: missing host", rawURL)
+	}
+
+	return nil
+}
+
+func handlerRenameFeed(ctx context.Context, s *state, cmd command, user database.User) error {
+	if len(cmd.args) != 2 {
+		return fmt.Errorf("renamefeed requires two arguments: url name")
+	}
+
+	feed, err := findManagedFeed(ctx, s, user, cmd.args[0])
+	if err != nil {
+		return err
+	}
+
+	err = s.db.RenameFeed(ctx, database.RenameFeedParams{ID: feed.ID, Name: cmd.args[1]})
+	if err != nil {
+		return fmt.Errorf("Failed to rename feed `%s`: %v", feed.Name, err)
+	}
+
+	fmt.Printf("Renamed `%s` to `%s`\n", feed.Name, cmd.args[1])
+
+	return nil
+}
+
+// Points a feed at a new URL. Unless --no-check is given, the new URL must
+// serve a feed we can parse.
+func handlerSetFeedURL(ctx context.Context, s *state, cmd command, user database.User) error {
+	fs := flag.NewFlagSet("setfeedurl", flag.ContinueOnError)
+	noCheck := fs.Bool("no-check", false, "don't fetch the new URL to check it serves a feed")
+
+	positional, err := parseFlags(fs, cmd.args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 2 {
+		return fmt.Errorf("setfeedurl requires two arguments: url new-url")
+	}
+
+	feed, err := findManagedFeed(ctx, s, user, positional[0])
+	if err != nil {
+		return err
+	}
+
+	newURL := positional[1]
+	err = validateFeedURL(newURL)
+	if err != nil {
+		return err
+	}
+
+	if !*noCheck {
+		_, _, err = fetchFeed(ctx, newURL)
+		if err != nil {
+			return fmt.Errorf("`%s` doesn't look like a working feed (use --no-check to set it anyway): %v", newURL, err)
+		}
+	}
+
+	err = s.db.UpdateFeedURL(ctx, database.UpdateFeedURLParams{ID: feed.ID, Url: newURL})
+	if err != nil {
+		return fmt.Errorf("Failed to change URL of `%s`: %v", feed.Name, err)
+	}
+
+	// The WebSub subscription was for the old topic; agg will subscribe again
+	// if the new feed advertises a hub
+	err = s.db.DeleteWebSubSubscription(ctx, feed.ID)
+	if err != nil {
+		slog.Warn("Failed to drop WebSub subscription", slog.String("feed_url", feed.Url), errAttrs(err))
+	}
+
+	fmt.Printf("Changed URL of `%s` to %s\n", feed.Name, newURL)
+
+	return nil
+}
+
+func handlerPauseFeed(ctx context.Context, s *state, cmd command, user database.User) error {
+	return setFeedPaused(ctx, s, cmd, user, true)
+}
+
+func handlerResumeFeed(ctx context.Context, s *state, cmd command, user database.User) error {
+	return setFeedPaused(ctx, s, cmd, user, false)
+}
+
+// Paused feeds are skipped by agg and ignore WebSub deliveries, but keep their
+// posts and followers
+func setFeedPaused(ctx context.Context, s *state, cmd command, user database.User, paused bool) error {
+	if len(cmd.args) != 1 {
+		return fmt.Errorf("%s requires a feed url", cmd.name)
+	}
+
+	feed, err := findManagedFeed(ctx, s, user, cmd.args[0])
+	if err != nil {
+		return err
+	}
+
+	pausedAt := sql.NullTime{}
+	if paused {
+		pausedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	}
+
+	err = s.db.SetFeedPaused(ctx, database.SetFeedPausedParams{ID: feed.ID, PausedAt: pausedAt})
+	if err != nil {
+		return fmt.Errorf("Failed to update feed `%s`: %v", feed.Name, err)
+	}
+
+	if paused {
+		fmt.Printf("Paused `%s`\n", feed.Name)
+	} else {
+		fmt.Printf("Resumed `%s`\n", feed.Name)
+	}
+
+	return nil
+}
+
+// Deletes a feed along with its posts, follows and history. Saved copies of
+// its posts are kept. Admins only, since it affects everyone following it.
+func handlerDeleteFeed(ctx context.Context, s *state, cmd command, user database.User) error {
+	fs := flag.NewFlagSet("deletefeed", flag.ContinueOnError)
+	yes := fs.Bool("yes", false, "don't ask for confirmation")
+
+	positional, err := parseFlags(fs, cmd.args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return fmt.Errorf("deletefeed requires a feed url")
+	}
+
+	feed, err := s.db.GetFeed(ctx, positional[0])
+	if err != nil {
+		return fmt.Errorf("Unable to find feed `%s`: %v", positional[0], err)
+	}
+
+	posts, err := s.db.CountFeedPosts(ctx, feed.ID)
+	if err != nil {
+		return fmt.Errorf("Failed to count posts of `%s`: %v", feed.Name, err)
+	}
+
+	err = confirm(fmt.Sprintf("delete `%s` and its %d posts for everyone", feed.Name, posts), "delete", *yes)
+	if err != nil {
+		return err
+	}
+
+	err = s.db.DeleteFeed(ctx, feed.ID)
+	if err != nil {
+		return fmt.Errorf("Failed to delete feed `%s`: %v", feed.Name, err)
+	}
+
+	fmt.Printf("Deleted `%s` and its %d posts\n", feed.Name, posts)
+
+	return nil
+}
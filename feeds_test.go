@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateFeedURL(t *testing.T) {
+	tests := []struct {
+		url string
+		valid bool
+	}{
+		{url: "https://example.com/rss", valid: true},
+		{url: "http://localhost:8080/feed.xml", valid: true},
+		{url: "example.com/rss", valid: false},
+		{url: "ftp://example.com/rss", valid: false},
+		{url: "javascript:alert(1)", valid: false},
+		{url: "https:///rss", valid: false},
+		{url: "https://exa mple.com/", valid: false},
+		{url: "", valid: false},
+	}
+
+	for _, tt := range tests {
+		err := validateFeedURL(tt.url)
+		if (err == nil) != tt.valid {
+			t.Errorf("validateFeedURL(%q) = %v, want valid %v", tt.url, err, tt.valid)
+		}
+	}
+}
+
+func TestSetFeedURLValidates(t *testing.T) {
+	ctx := context.Background()
+	s := newTestState(t)
+	alice := createTestUser(t, s, "alice")
+	feed := createTestFeed(t, s, alice, "Example", "https://example.com/rss")
+
+	_, err := runHandler(t, handlerSetFeedURL, s, alice, "--no-check", feed.Url, "example.com/feed")
+	if err == nil || !strings.Contains(err.Error(), "Invalid URL") {
+		t.Errorf("setfeedurl returned %v, want an invalid URL error", err)
+	}
+
+	_, err = runHandler(t, handlerSetFeedURL, s, alice, "--no-check", feed.Url, "https://example.com/feed")
+	if err != nil {
+		t.Fatalf("setfeedurl failed: %v", err)
+	}
+	_, err = s.db.GetFeed(ctx, "https://example.com/feed")
+	if err != nil {
+		t.Errorf("Feed not found at its new URL: %v", err)
+	}
+}
+
+func TestImportValidatesURLs(t *testing.T) {
+	ctx := context.Background()
+	s := newTestState(t)
+	alice := createTestUser(t, s, "alice")
+
+	path := filepath.Join(t.TempDir(), "feeds.opml")
+	err := os.WriteFile(path, []byte(`<opml version="2.0"><body>
+		<outline text="Good" xmlUrl="https://good.example.com/rss"></outline>
+		<outline text="Bad" xmlUrl="file:///etc/passwd"></outline>
+	</body></opml>`), 0644)
+	if err != nil {
+		t.Fatalf("Failed to write OPML: %v", err)
+	}
+
+	_, err = runHandler(t, handlerImport, s, alice, path)
+	if err == nil || !strings.Contains(err.Error(), "Invalid URL") {
+		t.Errorf("Import returned %v, want an invalid URL error", err)
+	}
+
+	// The import runs in one transaction, so nothing of it is kept
+	feeds, err := s.db.GetFeeds(ctx)
+	if err != nil {
+		t.Fatalf("Failed to get feeds: %v", err)
+	}
+	if len(feeds) != 0 {
+		t.Errorf("Import left feeds %+v", feeds)
+	}
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/matt-horst/blog-agg/internal/database"
+
+	"github.com/google/uuid"
+)
+
+var filterActions = []string{"exclude", "include"}
+var filterFields = []string{"title", "content", "category", "author", "any"}
+
+// Filter rules hide posts from browse without deleting them. A post is hidden
+// when it matches any exclude rule, or when include rules apply to its feed
+// and it matches none of them. Matching itself happens in the database (see
+// the filter_matches and post_visible functions).
+func handlerAddFilter(ctx context.Context, s *state, cmd command, user database.User) error {
+	fs := flag.NewFlagSet("addfilter", flag.ContinueOnError)
+	regex := fs.Bool("regex", false, "treat the pattern as a case-insensitive regular expression")
+	feedURL := fs.String("feed", "", "only apply the rule to the feed with this URL")
+
+	positional, err := parseFlags(fs, cmd.args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 4 {
+		return fmt.Errorf("addfilter requires four arguments: name action field pattern")
+	}
+
+	rule, err := parseFilterRule(ctx, s, positional[1:], *regex, *feedURL)
+	if err != nil {
+		return err
+	}
+
+	created, err := s.db.CreateFilterRule(
+		ctx,
+		database.CreateFilterRuleParams{
+			ID: uuid.New(),
+			UserID: user.ID,
+			Name: positional[0],
+			Action: rule.Action,
+			Field: rule.Field,
+			Pattern: rule.Pattern,
+			IsRegex: rule.IsRegex,
+			FeedID: rule.FeedID,
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("Failed to create filter rule: %v", err)
+	}
+
+	fmt.Printf("Filter `%s` created: %s\n", created.Name, describeFilterRule(created, *feedURL))
+
+	return nil
+}
+
+func handlerFilters(ctx context.Context, s *state, cmd command, user database.User) error {
+	rules, err := s.db.GetFilterRulesForUser(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("Failed to get filter rules: %v", err)
+	}
+
+	for _, r := range rules {
+		rule := database.FilterRule{Action: r.Action, Field: r.Field, Pattern: r.Pattern, IsRegex: r.IsRegex}
+		fmt.Printf("* %s: %s\n", r.Name, describeFilterRule(rule, r.FeedName.String))
+	}
+
+	return nil
+}
+
+func handlerRemoveFilter(ctx context.Context, s *state, cmd command, user database.User) error {
+	if len(cmd.args) != 1 {
+		return fmt.Errorf("removefilter requires the filter name")
+	}
+
+	removed, err := s.db.DeleteFilterRule(ctx, database.DeleteFilterRuleParams{UserID: user.ID, Name: cmd.args[0]})
+	if err != nil {
+		return fmt.Errorf("Failed to remove filter `%s`: %v", cmd.args[0], err)
+	}
+	if removed == 0 {
+		return fmt.Errorf("No filter named `%s`", cmd.args[0])
+	}
+
+	return nil
+}
+
+// Lists the followed posts a rule would hide, either a saved rule given by name
+// or one described by the same arguments as addfilter
+func handlerPreviewFilter(ctx context.Context, s *state, cmd command, user database.User) error {
+	fs := flag.NewFlagSet("previewfilter", flag.ContinueOnError)
+	regex := fs.Bool("regex", false, "treat the pattern as a case-insensitive regular expression")
+	feedURL := fs.String("feed", "", "only apply the rule to the feed with this URL")
+	limit := fs.Int("limit", 10, "maximum number of posts to list")
+
+	positional, err := parseFlags(fs, cmd.args)
+	if err != nil {
+		return err
+	}
+
+	var rule database.FilterRule
+	switch len(positional) {
+	case 1:
+		rule, err = s.db.GetFilterRuleByName(ctx, database.GetFilterRuleByNameParams{UserID: user.ID, Name: positional[0]})
+		if err != nil {
+			return fmt.Errorf("No filter named `%s`", positional[0])
+		}
+	case 3:
+		rule, err = parseFilterRule(ctx, s, positional, *regex, *feedURL)
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("previewfilter requires a filter name, or three arguments: action field pattern")
+	}
+
+	posts, err := s.db.PreviewFilterRule(
+		ctx,
+		database.PreviewFilterRuleParams{
+			UserID: user.ID,
+			FeedID: rule.FeedID,
+			Field: rule.Field,
+			Pattern: rule.Pattern,
+			IsRegex: rule.IsRegex,
+			// Include rules hide everything they don't match
+			HideMatches: rule.Action == "exclude",
+			RowLimit: int32(*limit),
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("Failed to preview filter: %v", err)
+	}
+
+	total := int64(0)
+	if len(posts) > 0 {
+		total = posts[0].Total
+	}
+
+	fmt.Printf("This rule would hide %d posts\n", total)
+	for _, p := range posts {
+		fmt.Printf("* %s (%s", p.Title, p.FeedName)
+		if p.PublishedAt.Valid {
+			fmt.Printf(", %s", p.PublishedAt.Time.Format("Mon Jan 2, 2006"))
+		}
+		fmt.Printf(")\n")
+		fmt.Printf("	%s\n", p.Url)
+	}
+	if total > int64(len(posts)) {
+		fmt.Printf("...and %d more\n", total-int64(len(posts)))
+	}
+
+	return nil
+}
+
+// Validates the action, field and pattern arguments of a rule and resolves its
+// feed. The returned rule has no ID or name.
+func parseFilterRule(ctx context.Context, s *state, args []string, isRegex bool, feedURL string) (database.FilterRule, error) {
+	action := args[0]
+	field := args[1]
+	pattern := args[2]
+
+	if !slices.Contains(filterActions, action) {
+		return database.FilterRule{}, fmt.Errorf("Unknown filter action `%s`: must be one of %s", action, strings.Join(filterActions, ", "))
+	}
+	if !slices.Contains(filterFields, field) {
+		return database.FilterRule{}, fmt.Errorf("Unknown filter field `%s`: must be one of %s", field, strings.Join(filterFields, ", "))
+	}
+	if pattern == "" {
+		return database.FilterRule{}, fmt.Errorf("Filter pattern must not be empty")
+	}
+
+	// Postgres and Go differ on regex syntax, so ask the database that will
+	// run the pattern
+	if isRegex {
+		err := s.db.CheckFilterRegex(ctx, pattern)
+		if err != nil {
+			return database.FilterRule{}, fmt.Errorf("Invalid regex `%s`: %v", pattern, err)
+		}
+	}
+
+	rule := database.FilterRule{Action: action, Field: field, Pattern: pattern, IsRegex: isRegex}
+
+	if feedURL != "" {
+		feed, err := s.db.GetFeed(ctx, feedURL)
+		if err != nil {
+			return database.FilterRule{}, fmt.Errorf("Unable to find feed `%s`: %v", feedURL, err)
+		}
+		rule.FeedID = uuid.NullUUID{UUID: feed.ID, Valid: true}
+	}
+
+	return rule, nil
+}
+
+func describeFilterRule(rule database.FilterRule, feed string) string {
+	match := "containing"
+	if rule.IsRegex {
+		match = "matching"
+	} else if rule.Field == "category" {
+		match = "equal to"
+	}
+
+	field := rule.Field
+	if field == "any" {
+		field = "any field"
+	}
+
+	scope := "all feeds"
+	if feed != "" {
+		scope = feed
+	}
+
+	return fmt.Sprintf("%s posts with %s %s `%s` (%s)", rule.Action, field, match, rule.Pattern, scope)
+}
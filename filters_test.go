@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/matt-horst/blog-agg/internal/database"
+
+	"github.com/google/uuid"
+)
+
+func TestFilters(t *testing.T) {
+	testFilters(t, newTestState(t))
+}
+
+func testFilters(t *testing.T, s *state) {
+	day := func(d int) time.Time {
+		return time.Date(2024, time.March, d, 12, 0, 0, 0, time.UTC)
+	}
+
+	alice := createTestUser(t, s, "alice")
+	tech := createTestFeed(t, s, alice, "Tech", "https://tech.example.com/rss")
+	cooking := createTestFeed(t, s, alice, "Cooking", "https://cooking.example.com/rss")
+
+	for _, p := range []struct {
+		feed database.Feed
+		title string
+		content string
+		author string
+		categories []string
+		day int
+	}{
+		{feed: tech, title: "Go 1.22 Released", content: "Range over integers", author: "The Go Team", categories: []string{"Releases", "Go"}, day: 1},
+		{feed: tech, title: "Rust 1.76", content: "ABI compatibility", author: "Rust Team", categories: []string{"Releases"}, day: 2},
+		{feed: tech, title: "Cloud deals", content: "Save on hosting", author: "Ads", categories: []string{"Sponsored"}, day: 3},
+		{feed: cooking, title: "Bread basics", content: "Flour and water", author: "Baker", categories: []string{"Baking"}, day: 4},
+		{feed: cooking, title: "Sourdough starter", content: "Brought to you by Go Flour", author: "Baker", categories: []string{"Baking", "Sponsored"}, day: 5},
+	} {
+		_, err := s.db.CreatePost(
+			context.Background(),
+			database.CreatePostParams{
+				ID: uuid.New(),
+				Title: p.title,
+				Url: p.feed.Url + "/" + strings.ReplaceAll(strings.ToLower(p.title), " ", "-"),
+				PublishedAt: sql.NullTime{Time: day(p.day), Valid: true},
+				FeedID: p.feed.ID,
+				Content: p.content,
+				Author: p.author,
+				Categories: p.categories,
+			},
+		)
+		if err != nil {
+			t.Fatalf("Failed to create post `%s`: %v", p.title, err)
+		}
+	}
+
+	visible := func(args ...string) []string {
+		t.Helper()
+
+		out, err := runHandler(t, handlerBrowse, s, alice, append(args, "10")...)
+		if err != nil {
+			t.Fatalf("browse failed: %v", err)
+		}
+		return browseTitles(out)
+	}
+	run := func(handler func(context.Context, *state, command, database.User) error, args ...string) string {
+		t.Helper()
+
+		out, err := runHandler(t, handler, s, alice, args...)
+		if err != nil {
+			t.Fatalf("%q failed: %v", args, err)
+		}
+		return out
+	}
+
+	// Categories match whole, ignoring case
+	run(handlerAddFilter, "no-ads", "exclude", "category", "sponsored")
+	if got, want := visible(), []string{"Bread basics", "Rust 1.76", "Go 1.22 Released"}; !slices.Equal(got, want) {
+		t.Errorf("Visible posts %q, want %q", got, want)
+	}
+	if out := run(handlerPreviewFilter, "exclude", "category", "release"); out != "This rule would hide 0 posts\n" {
+		t.Errorf("Previewing a partial category printed %q", out)
+	}
+
+	// Include rules only hide posts of the feeds they apply to
+	run(handlerAddFilter, "--feed", tech.Url, "--regex", "only-go", "include", "title", "^go")
+	if got, want := visible(), []string{"Bread basics", "Go 1.22 Released"}; !slices.Equal(got, want) {
+		t.Errorf("Visible posts %q, want %q", got, want)
+	}
+
+	// Content covers the description too; plain patterns ignore case
+	run(handlerAddFilter, "no-ints", "exclude", "content", "INTEGERS")
+	if got, want := visible(), []string{"Bread basics"}; !slices.Equal(got, want) {
+		t.Errorf("Visible posts %q, want %q", got, want)
+	}
+	if got := visible("--unfiltered"); len(got) != 5 {
+		t.Errorf("browse --unfiltered showed %q, want every post", got)
+	}
+
+	out := run(handlerPreviewFilter, "--regex", "exclude", "any", "go (team|flour)")
+	want := "This rule would hide 2 posts\n" +
+		"* Sourdough starter (Cooking, Tue Mar 5, 2024)\n\thttps://cooking.example.com/rss/sourdough-starter\n" +
+		"* Go 1.22 Released (Tech, Fri Mar 1, 2024)\n\thttps://tech.example.com/rss/go-1.22-released\n"
+	if out != want {
+		t.Errorf("previewfilter printed %q, want %q", out, want)
+	}
+	if out := run(handlerPreviewFilter, "--limit", "1", "no-ads"); !strings.HasPrefix(out, "This rule would hide 2 posts\n") || !strings.HasSuffix(out, "...and 1 more\n") {
+		t.Errorf("Previewing a saved rule printed %q", out)
+	}
+
+	run(handlerRemoveFilter, "only-go")
+	if got, want := visible(), []string{"Bread basics", "Rust 1.76"}; !slices.Equal(got, want) {
+		t.Errorf("Visible posts after removing a rule %q, want %q", got, want)
+	}
+	out = run(handlerFilters)
+	if !strings.Contains(out, "* no-ads: ") || !strings.Contains(out, "* no-ints: ") || strings.Contains(out, "only-go") {
+		t.Errorf("filters printed %q", out)
+	}
+
+	for _, args := range [][]string{
+		{"bad", "exclude", "title", "("},
+		{"bad", "hide", "title", "ads"},
+		{"bad", "exclude", "summary", "ads"},
+		{"bad", "exclude", "title", ""},
+	} {
+		_, err := runHandler(t, handlerAddFilter, s, alice, append([]string{"--regex"}, args...)...)
+		if err == nil {
+			t.Errorf("addfilter %q succeeded", args)
+		}
+	}
+	_, err := runHandler(t, handlerRemoveFilter, s, alice, "only-go")
+	if err == nil {
+		t.Error("Removed a filter twice")
+	}
+}
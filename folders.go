@@ -0,0 +1,424 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/matt-horst/blog-agg/internal/database"
+
+	"github.com/google/uuid"
+)
+
+// A user's folders, indexed for path lookups. Top-level folders are the
+// children of uuid.Nil.
+type folderTree struct {
+	folders map[uuid.UUID]database.Folder
+	children map[uuid.UUID][]database.Folder
+}
+
+func loadFolders(ctx context.Context, q database.Store, userID uuid.UUID) (*folderTree, error) {
+	folders, err := q.GetFoldersForUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get folders: %v", err)
+	}
+
+	t := &folderTree{folders: map[uuid.UUID]database.Folder{}, children: map[uuid.UUID][]database.Folder{}}
+	for _, f := range folders {
+		t.add(f)
+	}
+
+	return t, nil
+}
+
+func (t *folderTree) add(f database.Folder) {
+	t.folders[f.ID] = f
+	t.children[parentKey(f.ParentID)] = append(t.children[parentKey(f.ParentID)], f)
+}
+
+func (t *folderTree) child(parent uuid.NullUUID, name string) (database.Folder, bool) {
+	for _, f := range t.children[parentKey(parent)] {
+		if f.Name == name {
+			return f, true
+		}
+	}
+
+	return database.Folder{}, false
+}
+
+// Resolves a slash-separated folder path such as "Tech/Go", as split by
+// splitFolderPath
+func (t *folderTree) find(path string) (database.Folder, error) {
+	names := splitFolderPath(path)
+	if len(names) == 0 {
+		return database.Folder{}, fmt.Errorf("Folder path must not be empty")
+	}
+
+	folder := database.Folder{}
+	for _, name := range names {
+		f, ok := t.child(nullFolderID(folder), name)
+		if !ok {
+			return database.Folder{}, fmt.Errorf("No folder named `%s`", path)
+		}
+		folder = f
+	}
+
+	return folder, nil
+}
+
+// Resolves a destination folder, where "/" means the top level
+func (t *folderTree) findParent(path string) (uuid.NullUUID, error) {
+	if len(splitFolderPath(path)) == 0 {
+		return uuid.NullUUID{}, nil
+	}
+
+	folder, err := t.find(path)
+	if err != nil {
+		return uuid.NullUUID{}, err
+	}
+
+	return nullFolderID(folder), nil
+}
+
+// The path of a folder, with its names escaped so it can be given back to
+// find
+func (t *folderTree) path(id uuid.UUID) string {
+	names := []string{}
+	for f, ok := t.folders[id]; ok; f, ok = t.folders[f.ParentID.UUID] {
+		names = append([]string{escapeFolderName(f.Name)}, names...)
+	}
+
+	return strings.Join(names, "/")
+}
+
+// The folder and all folders nested below it
+func (t *folderTree) subtree(id uuid.UUID) []uuid.UUID {
+	ids := []uuid.UUID{id}
+	for _, f := range t.children[id] {
+		ids = append(ids, t.subtree(f.ID)...)
+	}
+
+	return ids
+}
+
+// Finds the folder reached by following names down from the top level,
+// creating it and any missing parents. Names are taken as they are, so they
+// may contain slashes.
+func ensureFolder(ctx context.Context, q database.Store, t *folderTree, userID uuid.UUID, names []string) (database.Folder, error) {
+	if len(names) == 0 {
+		return database.Folder{}, fmt.Errorf("Folder path must not be empty")
+	}
+
+	folder := database.Folder{}
+	for _, name := range names {
+		parent := nullFolderID(folder)
+
+		f, ok := t.child(parent, name)
+		if !ok {
+			position, err := q.NextFolderPosition(ctx, database.NextFolderPositionParams{UserID: userID, ParentID: parent})
+			if err != nil {
+				return database.Folder{}, fmt.Errorf("Failed to create folder `%s`: %v", name, err)
+			}
+
+			f, err = q.CreateFolder(
+				ctx,
+				database.CreateFolderParams{
+					ID: uuid.New(),
+					UserID: userID,
+					ParentID: parent,
+					Name: name,
+					Position: position,
+				},
+			)
+			if err != nil {
+				return database.Folder{}, fmt.Errorf("Failed to create folder `%s`: %v", name, err)
+			}
+			t.add(f)
+		}
+		folder = f
+	}
+
+	return folder, nil
+}
+
+// Splits a folder path into its names. Folders imported from OPML may have
+// a slash in their name, written \/ in a path; a backslash is written \\.
+func splitFolderPath(path string) []string {
+	names := []string{}
+	name := strings.Builder{}
+	endName := func() {
+		if n := strings.TrimSpace(name.String()); n != "" {
+			names = append(names, n)
+		}
+		name.Reset()
+	}
+
+	for i := 0; i < len(path); i++ {
+		switch {
+		case path[i] == '\\' && i+1 < len(path) && (path[i+1] == '/' || path[i+1] == '\\'):
+			i++
+			name.WriteByte(path[i])
+		case path[i] == '/':
+			endName()
+		default:
+			name.WriteByte(path[i])
+		}
+	}
+	endName()
+
+	return names
+}
+
+// Escapes a folder name for use in a path
+func escapeFolderName(name string) string {
+	return strings.NewReplacer(`\`, `\\`, `/`, `\/`).Replace(name)
+}
+
+func nullFolderID(f database.Folder) uuid.NullUUID {
+	return uuid.NullUUID{UUID: f.ID, Valid: f.ID != uuid.Nil}
+}
+
+func parentKey(id uuid.NullUUID) uuid.UUID {
+	if !id.Valid {
+		return uuid.Nil
+	}
+
+	return id.UUID
+}
+
+func handlerMkFolder(ctx context.Context, s *state, cmd command, user database.User) error {
+	if len(cmd.args) != 1 {
+		return fmt.Errorf("mkfolder requires a folder path, e.g. Tech/Go")
+	}
+
+	t, err := loadFolders(ctx, s.db, user.ID)
+	if err != nil {
+		return err
+	}
+
+	folder, err := ensureFolder(ctx, s.db, t, user.ID, splitFolderPath(cmd.args[0]))
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Created folder `%s`\n", t.path(folder.ID))
+
+	return nil
+}
+
+func handlerRmFolder(ctx context.Context, s *state, cmd command, user database.User) error {
+	if len(cmd.args) != 1 {
+		return fmt.Errorf("rmfolder requires a folder path")
+	}
+
+	t, err := loadFolders(ctx, s.db, user.ID)
+	if err != nil {
+		return err
+	}
+
+	folder, err := t.find(cmd.args[0])
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.DeleteFolder(ctx, database.DeleteFolderParams{ID: folder.ID, UserID: user.ID})
+	if err != nil {
+		return fmt.Errorf("Failed to remove folder `%s`: %v", cmd.args[0], err)
+	}
+
+	fmt.Printf("Removed folder `%s` and its subfolders; their feeds moved to the top level\n", t.path(folder.ID))
+
+	return nil
+}
+
+// Moves a folder under another folder (or "/" for the top level), optionally
+// at a given position among its new siblings
+func handlerMoveFolder(ctx context.Context, s *state, cmd command, user database.User) error {
+	fs := flag.NewFlagSet("movefolder", flag.ContinueOnError)
+	position := fs.Int("position", -1, "position among the folders of the destination, starting at 0 (default: last)")
+
+	positional, err := parseFlags(fs, cmd.args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 2 {
+		return fmt.Errorf("movefolder requires two arguments: folder destination (use / for the top level)")
+	}
+
+	t, err := loadFolders(ctx, s.db, user.ID)
+	if err != nil {
+		return err
+	}
+
+	folder, err := t.find(positional[0])
+	if err != nil {
+		return err
+	}
+
+	parent, err := t.findParent(positional[1])
+	if err != nil {
+		return err
+	}
+
+	for _, id := range t.subtree(folder.ID) {
+		if parent.Valid && parent.UUID == id {
+			return fmt.Errorf("Cannot move folder `%s` into itself", t.path(folder.ID))
+		}
+	}
+
+	pos, err := placeFolder(ctx, s.db, user.ID, parent, *position)
+	if err != nil {
+		return err
+	}
+
+	err = s.db.MoveFolder(ctx, database.MoveFolderParams{ID: folder.ID, UserID: user.ID, ParentID: parent, Position: pos})
+	if err != nil {
+		return fmt.Errorf("Failed to move folder `%s`: %v", positional[0], err)
+	}
+
+	return nil
+}
+
+// Files a followed feed in a folder (or "/" for the top level), optionally at
+// a given position among the folder's feeds
+func handlerMoveFeed(ctx context.Context, s *state, cmd command, user database.User) error {
+	fs := flag.NewFlagSet("movefeed", flag.ContinueOnError)
+	position := fs.Int("position", -1, "position among the feeds of the folder, starting at 0 (default: last)")
+
+	positional, err := parseFlags(fs, cmd.args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 2 {
+		return fmt.Errorf("movefeed requires two arguments: url folder (use / for the top level)")
+	}
+
+	feed, err := s.db.GetFeed(ctx, positional[0])
+	if err != nil {
+		return fmt.Errorf("Unable to find feed `%s`: %v", positional[0], err)
+	}
+
+	t, err := loadFolders(ctx, s.db, user.ID)
+	if err != nil {
+		return err
+	}
+
+	folderID, err := t.findParent(positional[1])
+	if err != nil {
+		return err
+	}
+
+	pos, err := placeFeedFollow(ctx, s.db, user.ID, folderID, *position)
+	if err != nil {
+		return err
+	}
+
+	moved, err := s.db.MoveFeedFollow(
+		ctx,
+		database.MoveFeedFollowParams{
+			UserID: user.ID,
+			FeedID: feed.ID,
+			FolderID: folderID,
+			Position: pos,
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("Failed to move feed `%s`: %v", feed.Name, err)
+	}
+	if moved == 0 {
+		return fmt.Errorf("You don't follow `%s`", feed.Url)
+	}
+
+	return nil
+}
+
+// Picks the position for a folder under parent, making room when an explicit
+// position is requested. A negative position means last.
+func placeFolder(ctx context.Context, q database.Store, userID uuid.UUID, parent uuid.NullUUID, position int) (int32, error) {
+	if position < 0 {
+		pos, err := q.NextFolderPosition(ctx, database.NextFolderPositionParams{UserID: userID, ParentID: parent})
+		if err != nil {
+			return 0, fmt.Errorf("Failed to find folder position: %v", err)
+		}
+		return pos, nil
+	}
+
+	err := q.ShiftFolderPositions(ctx, database.ShiftFolderPositionsParams{UserID: userID, ParentID: parent, Position: int32(position)})
+	if err != nil {
+		return 0, fmt.Errorf("Failed to reorder folders: %v", err)
+	}
+
+	return int32(position), nil
+}
+
+// Same as placeFolder, for the feeds within a folder
+func placeFeedFollow(ctx context.Context, q database.Store, userID uuid.UUID, folderID uuid.NullUUID, position int) (int32, error) {
+	if position < 0 {
+		pos, err := q.NextFeedFollowPosition(ctx, database.NextFeedFollowPositionParams{UserID: userID, FolderID: folderID})
+		if err != nil {
+			return 0, fmt.Errorf("Failed to find feed position: %v", err)
+		}
+		return pos, nil
+	}
+
+	err := q.ShiftFeedFollowPositions(ctx, database.ShiftFeedFollowPositionsParams{UserID: userID, FolderID: folderID, Position: int32(position)})
+	if err != nil {
+		return 0, fmt.Errorf("Failed to reorder feeds: %v", err)
+	}
+
+	return int32(position), nil
+}
+
+// Prints the folders under parent followed by the feeds filed directly in it
+func printFolder(t *folderTree, follows map[uuid.UUID][]database.GetFeedFollowsForUserRow, parent uuid.UUID, depth int) {
+	indent := strings.Repeat("  ", depth)
+
+	for _, f := range t.children[parent] {
+		fmt.Printf("%s%s/\n", indent, escapeFolderName(f.Name))
+		printFolder(t, follows, f.ID, depth+1)
+	}
+
+	for _, f := range follows[parent] {
+		fmt.Printf("%s* %s", indent, f.FeedName)
+		if f.Priority != 0 {
+			fmt.Printf(" (priority %d)", f.Priority)
+		}
+		fmt.Println()
+		if f.Notes != "" {
+			fmt.Printf("%s  %s\n", indent, f.Notes)
+		}
+	}
+}
+
+// The followed feeds filed in the folder at path or any folder below it
+func folderFeedIDs(ctx context.Context, s *state, user database.User, path string) ([]uuid.UUID, error) {
+	t, err := loadFolders(ctx, s.db, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	folder, err := t.find(path)
+	if err != nil {
+		return nil, err
+	}
+
+	inFolder := map[uuid.UUID]bool{}
+	for _, id := range t.subtree(folder.ID) {
+		inFolder[id] = true
+	}
+
+	following, err := s.db.GetFeedFollowsForUser(ctx, user.Name)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get followed feeds: %v", err)
+	}
+
+	feedIDs := []uuid.UUID{}
+	for _, f := range following {
+		if f.FolderID.Valid && inFolder[f.FolderID.UUID] {
+			feedIDs = append(feedIDs, f.FeedID)
+		}
+	}
+
+	return feedIDs, nil
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/matt-horst/blog-agg/internal/database"
+
+	"github.com/google/uuid"
+)
+
+func TestSplitFolderPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want []string
+	}{
+		{path: "Tech/Go", want: []string{"Tech", "Go"}},
+		{path: "/ Tech // Go/", want: []string{"Tech", "Go"}},
+		{path: `News\/Politics`, want: []string{"News/Politics"}},
+		{path: `Tech/C\\C++`, want: []string{"Tech", `C\C++`}},
+		{path: `a\b`, want: []string{`a\b`}},
+		{path: "/", want: []string{}},
+	}
+
+	for _, tt := range tests {
+		got := splitFolderPath(tt.path)
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("splitFolderPath(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+
+	for _, name := range []string{"News/Politics", `C\C++`, `a\/b`, "plain"} {
+		got := splitFolderPath(escapeFolderName(name))
+		if len(got) != 1 || got[0] != name {
+			t.Errorf("Escaped %q split back into %q", name, got)
+		}
+	}
+}
+
+func TestImportedFolderWithSlash(t *testing.T) {
+	ctx := context.Background()
+	s := newTestState(t)
+	alice := createTestUser(t, s, "alice")
+
+	path := filepath.Join(t.TempDir(), "feeds.opml")
+	err := os.WriteFile(path, []byte(`<opml version="2.0"><body>
+		<outline text="News/Politics">
+			<outline text="Daily" xmlUrl="https://news.example.com/rss"></outline>
+		</outline>
+	</body></opml>`), 0644)
+	if err != nil {
+		t.Fatalf("Failed to write OPML: %v", err)
+	}
+	_, err = runHandler(t, handlerImport, s, alice, path)
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	feed, err := s.db.GetFeed(ctx, "https://news.example.com/rss")
+	if err != nil {
+		t.Fatalf("Failed to get feed: %v", err)
+	}
+
+	out, err := runHandler(t, handlerFollowing, s, alice)
+	if err != nil {
+		t.Fatalf("following failed: %v", err)
+	}
+	if !strings.Contains(out, `News\/Politics/`) {
+		t.Errorf("following printed %q, want the escaped name", out)
+	}
+
+	ids, err := folderFeedIDs(ctx, s, alice, `News\/Politics`)
+	if err != nil {
+		t.Fatalf("Failed to resolve escaped path: %v", err)
+	}
+	if !slices.Equal(ids, []uuid.UUID{feed.ID}) {
+		t.Errorf("Feeds in folder %v, want %v", ids, feed.ID)
+	}
+
+	// The unescaped path names nested folders that don't exist
+	_, err = folderFeedIDs(ctx, s, alice, "News/Politics")
+	if err == nil {
+		t.Error("Unescaped path resolved to the imported folder")
+	}
+
+	_, err = runHandler(t, handlerMkFolder, s, alice, `News\/Politics/Local`)
+	if err != nil {
+		t.Fatalf("mkfolder failed: %v", err)
+	}
+	_, err = runHandler(t, handlerMoveFeed, s, alice, feed.Url, `News\/Politics/Local`)
+	if err != nil {
+		t.Fatalf("movefeed failed: %v", err)
+	}
+	tree, err := loadFolders(ctx, s.db, alice.ID)
+	if err != nil {
+		t.Fatalf("Failed to load folders: %v", err)
+	}
+	if len(tree.folders) != 2 {
+		t.Errorf("Folders %+v, want `News/Politics` and `Local` only", tree.folders)
+	}
+	local, err := tree.find(`News\/Politics/Local`)
+	if err != nil {
+		t.Fatalf("No `Local` folder under `News/Politics`: %v", err)
+	}
+	if got := followFolder(t, s, alice, feed.ID); got != nullFolderID(local) {
+		t.Errorf("Feed filed in %v, want %v", got, local.ID)
+	}
+
+	_, err = runHandler(t, handlerRmFolder, s, alice, `News\/Politics`)
+	if err != nil {
+		t.Fatalf("rmfolder failed: %v", err)
+	}
+	folders, err := s.db.GetFoldersForUser(ctx, alice.ID)
+	if err != nil {
+		t.Fatalf("Failed to get folders: %v", err)
+	}
+	if len(folders) != 0 {
+		t.Errorf("Folders left after rmfolder: %+v", folders)
+	}
+	if got := followFolder(t, s, alice, feed.ID); got.Valid {
+		t.Errorf("Feed still filed in %v", got.UUID)
+	}
+}
+
+func followFolder(t *testing.T, s *state, user database.User, feedID uuid.UUID) uuid.NullUUID {
+	t.Helper()
+	ctx := context.Background()
+
+	following, err := s.db.GetFeedFollowsForUser(ctx, user.Name)
+	if err != nil {
+		t.Fatalf("Failed to get follows: %v", err)
+	}
+	for _, f := range following {
+		if f.FeedID == feedID {
+			return f.FolderID
+		}
+	}
+	t.Fatalf("Not following feed %v", feedID)
+
+	return uuid.NullUUID{}
+}
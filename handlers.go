@@ -3,46 +3,62 @@ package main
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"strconv"
 	"time"
+	"encoding/base64"
 	"encoding/xml"
+	"flag"
+	"io"
 	"html"
 	"net/http"
+	"slices"
 	"strings"
 	"database/sql"
+	"errors"
 
 	"github.com/matt-horst/blog-agg/internal/database"
 
 	"github.com/google/uuid"
 )
 
-func middlewareLoggedIn(handler func(s *state, cmd command, user database.User) error) func(s *state, cmd command) error {
-	return func(s *state, cmd command) error {
-		user, err := s.db.GetUser(context.Background(), s.cfg.CurrentUserName)
+func middlewareLoggedIn(handler func(ctx context.Context, s *state, cmd command, user database.User) error) func(ctx context.Context, s *state, cmd command) error {
+	return func(ctx context.Context, s *state, cmd command) error {
+		user, err := sessionUser(ctx, s)
 		if err != nil {
-			return fmt.Errorf("Unable to find user `%s`: %v", s.cfg.CurrentUserName, err)
+			return err
 		}
 
-		return handler(s, cmd, user)
+		return handler(ctx, s, cmd, user)
 	}
 }
 
-func handlerLogin(s *state, cmd command) error {
+func handlerLogin(ctx context.Context, s *state, cmd command) error {
 	if len(cmd.args) == 0 {
 		return fmt.Errorf("Username is required")
 	}
 
 	name :=  cmd.args[0]
 
-	user, err := s.db.GetUser(context.Background(), name)
+	user, err := s.db.GetUser(ctx, name)
 	if err != nil {
 		return fmt.Errorf("Unable to find user: %v", err)
 	}
 
-	err = s.cfg.SetUser(user.Name)
+	// Anyone could choose the first password for an account that has none, so
+	// it has to be issued by an admin instead
+	if !user.PasswordHash.Valid {
+		return fmt.Errorf("`%s` has no password yet: ask an admin to run `setpassword %s`", user.Name, user.Name)
+	}
+
+	err = verifyPassword(user)
+	if err != nil {
+		return err
+	}
+
+	err = startSession(ctx, s, user)
 	if err != nil {
-		return fmt.Errorf("Failed to set the new user: %v", err)
+		return err
 	}
 
 	fmt.Printf("New user set to `%s`\n", user.Name)
@@ -50,25 +66,31 @@ func handlerLogin(s *state, cmd command) error {
 	return nil
 }
 
-func handlerRegister(s *state, cmd command) error {
+func handlerRegister(ctx context.Context, s *state, cmd command) error {
 	if len(cmd.args) == 0 {
 		return fmt.Errorf("Username is a required argument")
 	}
 
 	name := cmd.args[0]
 
+	hash, err := readNewPassword()
+	if err != nil {
+		return err
+	}
+
 	params := database.CreateUserParams{
 		ID: uuid.New(),
 		Name: name,
+		PasswordHash: hash,
 	}
-	_, err := s.db.CreateUser(context.Background(), params)
+	user, err := s.db.CreateUser(ctx, params)
 	if err != nil {
 		return fmt.Errorf("Failed to create new user: %v", err)
 	}
 
-	err = s.cfg.SetUser(name)
+	err = startSession(ctx, s, user)
 	if err != nil {
-		return fmt.Errorf("Failed to user: %v", err)
+		return err
 	}
 
 	fmt.Printf("New user successfully created: %s\n", name)
@@ -76,17 +98,8 @@ func handlerRegister(s *state, cmd command) error {
 	return nil
 }
 
-func handlerReset(s *state, cmd command) error {
-	err := s.db.ResetUsers(context.Background())
-	if err != nil {
-		return fmt.Errorf("Failed to reset users table: %v", err)
-	}
-
-	return nil
-}
-
-func handlerUsers(s *state, cmd command) error {
-	users, err := s.db.GetUsers(context.Background())
+func handlerUsers(ctx context.Context, s *state, cmd command, _ database.User) error {
+	users, err := s.db.GetUsers(ctx)
 	if err != nil {
 		return fmt.Errorf("Failed to get users from database: %v", err)
 	}
@@ -98,14 +111,19 @@ func handlerUsers(s *state, cmd command) error {
 			current = " (current)"
 		} 
 
-		fmt.Printf("* %s%s\n", user.Name, current)
+		role := ""
+		if isAdmin(user) {
+			role = " (admin)"
+		}
+
+		fmt.Printf("* %s%s%s\n", user.Name, role, current)
 	}
 
 	return nil
 }
 
 
-func handlerAddFeed(s *state, cmd command, user database.User) error {
+func handlerAddFeed(ctx context.Context, s *state, cmd command, user database.User) error {
 	if len(cmd.args) != 2 {
 		return fmt.Errorf("addfeed requires two arguments: name url")
 	}
@@ -113,6 +131,11 @@ func handlerAddFeed(s *state, cmd command, user database.User) error {
 	name := cmd.args[0]
 	url := cmd.args[1]
 
+	err := validateFeedURL(url)
+	if err != nil {
+		return err
+	}
+
 	params := database.CreateFeedParams{
 		ID: uuid.New(),
 		Name: name,
@@ -120,7 +143,7 @@ func handlerAddFeed(s *state, cmd command, user database.User) error {
 		UserID: user.ID,
 	}
 	feed, err := s.db.CreateFeed(
-		context.Background(),
+		ctx,
 		params,
 	)
 	if err != nil {
@@ -128,7 +151,7 @@ func handlerAddFeed(s *state, cmd command, user database.User) error {
 	}
 
 	_, err = s.db.CreateFeedFollow(
-		context.Background(),
+		ctx,
 		database.CreateFeedFollowParams{
 			ID: uuid.New(),
 			UserID: user.ID,
@@ -144,33 +167,37 @@ func handlerAddFeed(s *state, cmd command, user database.User) error {
 	return nil
 }
 
-func handlerFeeds(s *state, cmd command) error {
-	feeds, err := s.db.GetFeeds(context.Background())
+func handlerFeeds(ctx context.Context, s *state, cmd command) error {
+	feeds, err := s.db.GetFeeds(ctx)
 	if err != nil {
 		return fmt.Errorf("Failed to get feeds from database: %v", err)
 	}
 
 	for _, feed := range feeds {
-		fmt.Printf("* %s %s %s\n", feed.Name, feed.Url, feed.UserName)
+		fmt.Printf("* %s %s %s", feed.Name, feed.Url, feed.UserName)
+		if feed.PausedAt.Valid {
+			fmt.Printf(" (paused)")
+		}
+		fmt.Println()
 	}
 
 	return nil
 }
 
-func handlerFollow(s *state, cmd command, user database.User) error {
+func handlerFollow(ctx context.Context, s *state, cmd command, user database.User) error {
 	if len(cmd.args) != 1 {
 		return fmt.Errorf("follow command requires url argument")
 	}
 
 	url := cmd.args[0]
 
-	feed, err := s.db.GetFeed(context.Background(), url)
+	feed, err := s.db.GetFeed(ctx, url)
 	if err != nil {
 		return fmt.Errorf("Unable to find feed `%s`: %v", url, err)
 	}
 
 	feedFollow, err := s.db.CreateFeedFollow(
-		context.Background(),
+		ctx,
 		database.CreateFeedFollowParams{
 			ID: uuid.New(),
 			UserID: user.ID,
@@ -186,33 +213,41 @@ func handlerFollow(s *state, cmd command, user database.User) error {
 	return nil
 }
 
-func handlerFollowing(s *state, _ command, _ database.User) error {
-	following, err := s.db.GetFeedFollowsForUser(context.Background(), s.cfg.CurrentUserName)
+func handlerFollowing(ctx context.Context, s *state, _ command, user database.User) error {
+	following, err := s.db.GetFeedFollowsForUser(ctx, user.Name)
 	if err != nil {
-		return fmt.Errorf("Unable to find user `%s`: %v", s.cfg.CurrentUserName, err)
+		return fmt.Errorf("Unable to find user `%s`: %v", user.Name, err)
 	}
 
+	t, err := loadFolders(ctx, s.db, user.ID)
+	if err != nil {
+		return err
+	}
+
+	byFolder := map[uuid.UUID][]database.GetFeedFollowsForUserRow{}
 	for _, f := range following {
-		fmt.Printf("* %s\n", f.FeedName)
+		byFolder[parentKey(f.FolderID)] = append(byFolder[parentKey(f.FolderID)], f)
 	}
 
+	printFolder(t, byFolder, uuid.Nil, 0)
+
 	return nil
 }
 
-func handlerUnfollow(s *state, cmd command, user database.User) error {
+func handlerUnfollow(ctx context.Context, s *state, cmd command, user database.User) error {
 	if len(cmd.args) != 1 {
 		return fmt.Errorf("unfollow requires url as argument")
 	}
 
 	url := cmd.args[0]
 
-	feed, err := s.db.GetFeed(context.Background(), url)
+	feed, err := s.db.GetFeed(ctx, url)
 	if err != nil {
 		return fmt.Errorf("Unable to find feed for `%s`: %v", url, err)
 	}
 
 	_, err = s.db.DeleteFeedFollow(
-		context.Background(),
+		ctx,
 		database.DeleteFeedFollowParams{
 			UserID: user.ID,
 			FeedID: feed.ID,
@@ -225,95 +260,438 @@ func handlerUnfollow(s *state, cmd command, user database.User) error {
 	return nil
 }
 
-func handlerAgg(s *state, cmd command) error {
-	if len(cmd.args) != 1 {
-		return fmt.Errorf("agg requires time between requests argument")
+// Sets the current user's own title, notes and priority for a followed feed.
+// Only the given flags are changed; an empty --title restores the feed's name.
+func handlerEditFollow(ctx context.Context, s *state, cmd command, user database.User) error {
+	fs := flag.NewFlagSet("editfollow", flag.ContinueOnError)
+	title := fs.String("title", "", "your own title for the feed")
+	notes := fs.String("notes", "", "notes about the feed")
+	priority := fs.Int("priority", 0, "priority of the feed, higher is more important")
+
+	positional, err := parseFlags(fs, cmd.args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return fmt.Errorf("editfollow requires a feed url")
+	}
+
+	feed, err := s.db.GetFeed(ctx, positional[0])
+	if err != nil {
+		return fmt.Errorf("Unable to find feed `%s`: %v", positional[0], err)
+	}
+
+	follow, err := s.db.GetFeedFollow(ctx, database.GetFeedFollowParams{UserID: user.ID, FeedID: feed.ID})
+	if err != nil {
+		return fmt.Errorf("You don't follow `%s`", feed.Url)
+	}
+
+	params := database.UpdateFeedFollowDetailsParams{
+		UserID: user.ID,
+		FeedID: feed.ID,
+		Title: follow.Title,
+		Notes: follow.Notes,
+		Priority: follow.Priority,
+	}
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "title":
+			params.Title = strings.TrimSpace(*title)
+		case "notes":
+			params.Notes = *notes
+		case "priority":
+			params.Priority = int32(*priority)
+		}
+	})
+
+	err = s.db.UpdateFeedFollowDetails(ctx, params)
+	if err != nil {
+		return fmt.Errorf("Failed to update feed-follow: %v", err)
+	}
+
+	return nil
+}
+
+func handlerAgg(ctx context.Context, s *state, cmd command) error {
+	opts, err := parseAggOptions(cmd.args)
+	if err != nil {
+		return err
 	}
 
-	timeBetweenReqs, err := time.ParseDuration(cmd.args[0])
+	unlock, err := acquireAggregatorLock(ctx, s)
 	if err != nil {
-		return fmt.Errorf("Failed to parse `%s` as a duration: %v", cmd.args[0], err)
+		return err
 	}
+	defer unlock()
+	defer s.notifications.Wait()
 
-	fmt.Printf("Printing feeds every %v\n", timeBetweenReqs)
+	status := &aggStatus{startedAt: time.Now()}
+	if opts.wantsDaemon() {
+		stopDaemon, err := startDaemon(ctx, s, opts, status)
+		if err != nil {
+			return err
+		}
+		defer stopDaemon()
+	}
+
+	slog.Info("Starting aggregator", slog.Duration("interval", opts.interval))
+
+	ticker := time.NewTicker(opts.interval)
+	defer ticker.Stop()
+
+	start := time.Now()
+	total := scrapeResult{}
+	scrapes := 0
+	lastPrune := time.Time{}
+
+	for {
+		if time.Since(lastPrune) > fetchHistoryPruneInterval {
+			err := pruneFetchHistory(ctx, s)
+			if err != nil {
+				slog.Error("Failed to prune fetch history", errAttrs(err))
+			}
+			if opts.prunePosts {
+				err = prunePostsForAgg(ctx, s)
+				if err != nil {
+					slog.Error("Failed to prune posts", errAttrs(err))
+				}
+			}
+			lastPrune = time.Now()
+		}
 
-	ticker := time.NewTicker(timeBetweenReqs)
+		// Let an in-flight scrape finish (up to a deadline) after a shutdown
+		// signal rather than cutting it off mid-insert
+		scrapeCtx, cancel := withShutdownGrace(ctx, shutdownTimeout)
+		result, err := scrapeFeeds(scrapeCtx, s)
+		cancel()
 
-	for ; ; <-ticker.C {
-		err = scrapeFeeds(s)
+		scrapes++
+		total.add(result)
+		status.record(err)
 		if err != nil {
-			log.Printf("Failed to scrape feeds: %v\n", err)
+			total.failedFetches++
+		}
+
+		select {
+		case <-ctx.Done():
+			slog.Info(
+				"Aggregator shutting down",
+				slog.Duration("uptime", time.Since(start).Round(time.Second)),
+				slog.Int("scrapes", scrapes),
+				slog.Int("failed_scrapes", total.failedFetches),
+				slog.Int("new_posts", total.newPosts),
+				slog.Int("updated", total.updated),
+				slog.Int("duplicates", total.duplicates),
+				slog.Int("failed_posts", total.failedPosts),
+			)
+			return nil
+		case <-ticker.C:
 		}
 	}
 }
 
-func handlerBrowse(s *state, cmd command, user database.User) error {
+func handlerBrowse(ctx context.Context, s *state, cmd command, user database.User) error {
+	feedURLs := stringList{}
+
+	fs := flag.NewFlagSet("browse", flag.ContinueOnError)
+	all := fs.Bool("all", false, "include posts already marked as read")
+	markRead := fs.Bool("mark-read", false, "mark the listed posts as read")
+	unfiltered := fs.Bool("unfiltered", false, "include posts hidden by your filter rules")
+	minPriority := fs.Int("min-priority", 0, "only show posts from feeds with at least this priority")
+	fs.Var(&feedURLs, "feed", "only show posts from the feed with this URL (repeatable)")
+	folder := fs.String("folder", "", "only show posts from feeds in this folder and its subfolders")
+	since := fs.String("since", "", "only show posts published on or after this date")
+	until := fs.String("until", "", "only show posts published before this date")
+	sortOrder := fs.String("sort", "newest", "sort order: newest or oldest")
+	after := fs.String("after", "", "continue from the cursor printed by a previous page")
+
+	positional, err := parseFlags(fs, cmd.args)
+	if err != nil {
+		return err
+	}
+
 	limit := 2
-	if len(cmd.args) == 1 {
-		arg, err := strconv.Atoi(cmd.args[0])
+	if len(positional) == 1 {
+		arg, err := strconv.Atoi(positional[0])
 		if err != nil {
-			return fmt.Errorf("Failed to convert `%s` to integer", cmd.args[0])
+			return fmt.Errorf("Failed to convert `%s` to integer", positional[0])
 		}
 		limit = arg
 	}
 
-	posts, err := s.db.GetPostsForUser(
-		context.Background(),
-		database.GetPostsForUserParams{
-			UserID: user.ID,
-			Limit: int32(limit),
-		},
-	)
+	params := database.BrowsePostsNewestFirstParams{
+		UserID: user.ID,
+		UnreadOnly: !*all,
+		ApplyFilters: !*unfiltered,
+		// Fetch one extra row to know whether there is another page
+		RowLimit: int32(limit + 1),
+	}
+
+	for _, url := range feedURLs {
+		feed, err := s.db.GetFeed(ctx, url)
+		if err != nil {
+			return fmt.Errorf("Unable to find feed `%s`: %v", url, err)
+		}
+		params.FeedIds = append(params.FeedIds, feed.ID)
+	}
+
+	if *folder != "" {
+		feedIDs, err := folderFeedIDs(ctx, s, user, *folder)
+		if err != nil {
+			return err
+		}
+		if len(feedIDs) == 0 {
+			return fmt.Errorf("Folder `%s` has no feeds", *folder)
+		}
+
+		// With --feed as well, only those of the given feeds in the folder
+		if len(params.FeedIds) > 0 {
+			feedIDs = slices.DeleteFunc(feedIDs, func(id uuid.UUID) bool { return !slices.Contains(params.FeedIds, id) })
+			if len(feedIDs) == 0 {
+				return fmt.Errorf("None of the given feeds are in folder `%s`", *folder)
+			}
+		}
+		params.FeedIds = feedIDs
+	}
+
+	if *since != "" {
+		date, err := parseDate(*since)
+		if err != nil {
+			return err
+		}
+		params.Since = sql.NullTime{Time: date, Valid: true}
+	}
+	if *until != "" {
+		date, err := parseDate(*until)
+		if err != nil {
+			return err
+		}
+		params.Until = sql.NullTime{Time: date, Valid: true}
+	}
+
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "min-priority" {
+			params.MinPriority = sql.NullInt32{Int32: int32(*minPriority), Valid: true}
+		}
+	})
+
+	if *after != "" {
+		params.CursorPublishedAt, params.CursorID, err = decodeBrowseCursor(*after)
+		if err != nil {
+			return err
+		}
+	}
+
+	var posts []database.Post
+	switch *sortOrder {
+	case "newest":
+		posts, err = s.db.BrowsePostsNewestFirst(ctx, params)
+	case "oldest":
+		posts, err = s.db.BrowsePostsOldestFirst(ctx, database.BrowsePostsOldestFirstParams(params))
+	default:
+		return fmt.Errorf("Unknown sort order `%s`: must be newest or oldest", *sortOrder)
+	}
 	if err != nil {
 		return fmt.Errorf("Failed to get posts for user")
 	}
 
-	fmt.Printf("Found %d posts for you!\n", len(posts))
+	hasMore := len(posts) > limit
+	if hasMore {
+		posts = posts[:limit]
+	}
+
+	if *all {
+		fmt.Printf("Found %d posts for you!\n", len(posts))
+	} else {
+		fmt.Printf("Found %d unread posts for you!\n", len(posts))
+	}
+	following, err := s.db.GetFeedFollowsForUser(ctx, user.Name)
+	if err != nil {
+		return fmt.Errorf("Failed to get followed feeds: %v", err)
+	}
+	feedNames := map[uuid.UUID]string{}
+	for _, f := range following {
+		feedNames[f.FeedID] = f.FeedName
+	}
+
 	for _, p := range posts {
-		fmt.Printf("%s\n", p.PublishedAt.Time.Format("Mon Jan 2, 2006"))
+		fmt.Printf("%s - %s\n", p.PublishedAt.Time.Format("Mon Jan 2, 2006"), feedNames[p.FeedID])
 		fmt.Printf("*** %s ***\n", p.Title)
 		fmt.Printf("	%v\n", p.Description)
 		fmt.Printf("Link: %s\n", p.Url)
+		fmt.Printf("ID: %s\n", p.ID)
 		fmt.Println()
 	}
 
+	if hasMore {
+		fmt.Printf("More posts available, repeat with the same filters and --after %s\n", encodeBrowseCursor(posts[len(posts)-1]))
+	}
+
+	if *markRead {
+		for _, p := range posts {
+			_, err = s.db.MarkPostRead(
+				ctx,
+				database.MarkPostReadParams{
+					UserID: user.ID,
+					PostID: p.ID,
+				},
+			)
+			if err != nil {
+				return fmt.Errorf("Failed to mark post `%s` as read: %v", p.Url, err)
+			}
+		}
+	}
+
 	return nil
 }
 
+// Cursors encode the sort key (publication time, falling back to creation
+// time) and ID of the last post on a page
+func encodeBrowseCursor(p database.Post) string {
+	sortTime := p.CreatedAt
+	if p.PublishedAt.Valid {
+		sortTime = p.PublishedAt.Time
+	}
+
+	raw := fmt.Sprintf("%d:%s", sortTime.UnixNano(), p.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeBrowseCursor(cursor string) (sql.NullTime, uuid.NullUUID, error) {
+	invalid := fmt.Errorf("Invalid cursor `%s`", cursor)
+
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return sql.NullTime{}, uuid.NullUUID{}, invalid
+	}
+
+	nanos, id, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return sql.NullTime{}, uuid.NullUUID{}, invalid
+	}
+
+	n, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return sql.NullTime{}, uuid.NullUUID{}, invalid
+	}
+
+	postID, err := uuid.Parse(id)
+	if err != nil {
+		return sql.NullTime{}, uuid.NullUUID{}, invalid
+	}
+
+	return sql.NullTime{Time: time.Unix(0, n).UTC(), Valid: true}, uuid.NullUUID{UUID: postID, Valid: true}, nil
+}
+
 type RSSFeed struct {
 	Channel struct {
 		Title 		string 		`xml:"title"`
+		// Must come before Link so `<atom:link>` elements aren't matched by the
+		// namespace-less `link` tag
+		AtomLinks 	[]AtomLink 	`xml:"http://www.w3.org/2005/Atom link"`
 		Link 		string 		`xml:"link"`
 		Description string 		`xml:"description"`
 		Item 		[]RSSItem 	`xml:"item"`
 	} `xml:"channel"`
 }
 
+type AtomLink struct {
+	Rel 	string `xml:"rel,attr"`
+	Href 	string `xml:"href,attr"`
+}
+
+// Returns the href of the first `<atom:link>` with the given rel, or "" if none
+func (f *RSSFeed) atomLink(rel string) string {
+	for _, l := range f.Channel.AtomLinks {
+		if l.Rel == rel && l.Href != "" {
+			return l.Href
+		}
+	}
+
+	return ""
+}
+
 type RSSItem struct {
 	Title 		string `xml:"title"`
 	Link 		string `xml:"link"`
 	Description string `xml:"description"`
+	Content 	string `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	Creator 	string `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	Author 		string `xml:"author"`
+	Categories 	[]string `xml:"category"`
 	PubDate 	string `xml:"pubDate"`
 }
 
-func fetchFeed(ctx context.Context, feedURL string) (*RSSFeed, error) {
+// The item's author, preferring Dublin Core's creator which is usually a name
+// rather than an email address
+func (item RSSItem) author() string {
+	if item.Creator != "" {
+		return item.Creator
+	}
+
+	return item.Author
+}
+
+// The item's categories, unescaped and without blanks or duplicates
+func (item RSSItem) categories() []string {
+	categories := []string{}
+	seen := map[string]bool{}
+	for _, c := range item.Categories {
+		c = strings.TrimSpace(html.UnescapeString(c))
+		if c == "" || seen[strings.ToLower(c)] {
+			continue
+		}
+		seen[strings.ToLower(c)] = true
+		categories = append(categories, c)
+	}
+
+	return categories
+}
+
+// Transport-level details of a fetch, recorded in the fetch history
+type fetchStats struct {
+	statusCode int
+	bytes int64
+}
+
+func fetchFeed(ctx context.Context, feedURL string) (*RSSFeed, fetchStats, error) {
+	stats := fetchStats{}
+
 	req, err := http.NewRequestWithContext(ctx, "GET", feedURL, nil)
 	if err != nil {
-		return nil, fmt.Errorf("Failed to create GET request for URL `%s`: %v", feedURL, err)
+		return nil, stats, fmt.Errorf("Failed to create GET request for URL `%s`: %v", feedURL, err)
 	}
 
 	req.Header.Set("User-Agent", "gator")
 
+	start := time.Now()
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("Failed to get response from `%s`: %v", feedURL, err)
+		observeFetch(0, start)
+		return nil, stats, fmt.Errorf("Failed to get response from `%s`: %w", feedURL, err)
 	}
+	defer resp.Body.Close()
+	defer observeFetch(resp.StatusCode, start)
 
+	stats.statusCode = resp.StatusCode
+	if resp.StatusCode != http.StatusOK {
+		return nil, stats, fmt.Errorf("%w from `%s`: %s", errUnexpectedStatus, feedURL, resp.Status)
+	}
+
+	body := &countingReader{r: resp.Body}
+	rssFeed, err := parseFeed(body)
+	stats.bytes = body.n
+
+	return rssFeed, stats, err
+}
+
+func parseFeed(r io.Reader) (*RSSFeed, error) {
 	rssFeed := &RSSFeed{}
-	decoder := xml.NewDecoder(resp.Body)
-	err = decoder.Decode(rssFeed)
+	decoder := xml.NewDecoder(r)
+	err := decoder.Decode(rssFeed)
 	if err != nil {
-		return nil, fmt.Errorf("Failed to decode rss feed xml: %v", err)
+		metricParseFailures.Inc()
+		return nil, fmt.Errorf("%w: %v", errFeedParse, err)
 	}
 
 	// Unescape HTML entities
@@ -322,42 +700,102 @@ func fetchFeed(ctx context.Context, feedURL string) (*RSSFeed, error) {
 	for i, item := range rssFeed.Channel.Item {
 		rssFeed.Channel.Item[i].Title = html.UnescapeString(item.Title)
 		rssFeed.Channel.Item[i].Description = html.UnescapeString(item.Description)
+		rssFeed.Channel.Item[i].Content = html.UnescapeString(item.Content)
 	}
 
 	return rssFeed, nil
 }
 
-func scrapeFeeds(s *state) error {
-	feed, err := s.db.GetNextFeedToFetch(context.Background())
+// Counts of what happened to the items of one or more scraped feeds
+type scrapeResult struct {
+	newPosts int
+	updated int
+	duplicates int
+	failedPosts int
+	failedFetches int
+}
+
+func (r *scrapeResult) add(other scrapeResult) {
+	r.newPosts += other.newPosts
+	r.updated += other.updated
+	r.duplicates += other.duplicates
+	r.failedPosts += other.failedPosts
+	r.failedFetches += other.failedFetches
+}
+
+func scrapeFeeds(ctx context.Context, s *state) (scrapeResult, error) {
+	feed, err := s.db.GetNextFeedToFetch(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		slog.Debug("No active feeds to fetch")
+		return scrapeResult{}, nil
+	}
 	if err != nil {
-		return fmt.Errorf("Failed to get next feed: %v", err)
+		slog.Error("Failed to get next feed", errAttrs(err))
+		return scrapeResult{}, fmt.Errorf("Failed to get next feed: %w", err)
 	}
 
-	err = s.db.MarkFeedFetched(context.Background(), feed.ID)
+	logger := feedLogger(feed)
+
+	err = s.db.MarkFeedFetched(ctx, feed.ID)
 	if err != nil {
-		return fmt.Errorf("Failed to mark feed as fetched: %v", err)
+		logger.Error("Failed to mark feed as fetched", errAttrs(err))
+		return scrapeResult{}, fmt.Errorf("Failed to mark feed as fetched: %w", err)
 	}
 
-	fetchedFeed, err := fetchFeed(context.Background(), feed.Url)
+	start := time.Now()
+	fetchedFeed, stats, err := fetchFeed(ctx, feed.Url)
 	if err != nil {
-		return fmt.Errorf("Failed to fetch feed: %v", err)
+		logger.Warn("Failed to fetch feed", slog.Duration("duration", time.Since(start)), errAttrs(err))
+		recordFetch(ctx, s, feed, start, stats, 0, scrapeResult{}, err)
+		return scrapeResult{}, fmt.Errorf("Failed to fetch feed: %w", err)
 	}
 
+	err = updateWebSubLinks(ctx, s, feed, fetchedFeed)
+	if err != nil {
+		logger.Warn("Failed to update WebSub links", errAttrs(err))
+	}
+
+	result := savePosts(ctx, s, feed, fetchedFeed)
+	recordFetch(ctx, s, feed, start, stats, len(fetchedFeed.Channel.Item), result, nil)
+
+	logger.Info(
+		"Scraped feed",
+		slog.Duration("duration", time.Since(start)),
+		slog.Int("items", len(fetchedFeed.Channel.Item)),
+		slog.Int("new_posts", result.newPosts),
+		slog.Int("updated", result.updated),
+		slog.Int("duplicates", result.duplicates),
+		slog.Int("failed_posts", result.failedPosts),
+	)
+
+	return result, nil
+}
+
+// Creates posts for every item in the fetched feed, and refreshes the posts
+// of items that changed since they were saved. Used by both polling and
+// WebSub push delivery.
+func savePosts(ctx context.Context, s *state, feed database.Feed, fetchedFeed *RSSFeed) scrapeResult {
+	result := scrapeResult{}
+	logger := feedLogger(feed)
+	rules := loadAlertRules(ctx, s, feed)
+
 	for _, item := range fetchedFeed.Channel.Item {
 		title := html.UnescapeString(item.Title)
 		description := html.UnescapeString(item.Description)
 		link := html.UnescapeString(item.Link)
+		postLogger := logger.With(slog.String("post_url", link))
 
 		publishedAt := sql.NullTime{}
 		publishedAtTime, err := parseTime(html.UnescapeString(item.PubDate))
 		if err != nil {
-			log.Printf("Failed to convert publication date `%s` to time format: %v\n", item.PubDate, err)
+			metricDateParseFailures.Inc()
+			postLogger.Warn("Failed to parse publication date", slog.String("pub_date", item.PubDate), errAttrs(err))
 		} else {
 			publishedAt.Time = publishedAtTime
 			publishedAt.Valid = true
 		}
 		p, err := s.db.CreatePost(
-			context.Background(), 
+			ctx, 
 			database.CreatePostParams{
 				ID: uuid.New(),
 				Title: title,
@@ -365,22 +803,81 @@ func scrapeFeeds(s *state) error {
 				PublishedAt: publishedAt,
 				Url: link,
 				FeedID: feed.ID,
+				Content: item.Content,
+				Author: html.UnescapeString(item.author()),
+				Categories: item.categories(),
 			},
 		)
 
-		if strings.Contains(err.Error(), "duplicate key") {
-			log.Printf("Post for `%s` already exists", link)
+		if isUniqueViolation(err) {
+			updated, err := s.db.UpdatePost(
+				ctx,
+				database.UpdatePostParams{
+					Url: link,
+					FeedID: feed.ID,
+					Title: title,
+					Description: description,
+					PublishedAt: publishedAt,
+					Content: item.Content,
+					Author: html.UnescapeString(item.author()),
+					Categories: item.categories(),
+				},
+			)
+			switch {
+			case err != nil:
+				postLogger.Error("Failed to update post", errAttrs(err))
+				result.failedPosts++
+				metricPosts.WithLabelValues("failed").Inc()
+			case updated > 0:
+				postLogger.Info("Post updated")
+				result.updated++
+				metricPosts.WithLabelValues("updated").Inc()
+			default:
+				postLogger.Debug("Post already exists")
+				result.duplicates++
+				metricPosts.WithLabelValues("duplicate").Inc()
+			}
 			continue
 		}
 		if err != nil {
-			log.Printf("Failed to create post: %v\n", err)
+			postLogger.Error("Failed to create post", errAttrs(err))
+			result.failedPosts++
+			metricPosts.WithLabelValues("failed").Inc()
 			continue
 		}
 
-		fmt.Printf("New post created for `%s`: %s (%s)\n", p.Title, p.Url, p.PublishedAt.Time.String())
+		result.newPosts++
+		metricPosts.WithLabelValues("inserted").Inc()
+		postLogger.Info(
+			"New post created",
+			slog.String("post_id", p.ID.String()),
+			slog.String("title", p.Title),
+			slog.Time("published_at", p.PublishedAt.Time),
+		)
+
+		evaluateAlerts(ctx, s, feed, p, item, rules)
 	}
 
-	return nil
+	return result
+}
+
+// Stores the hub and self links advertised by the feed, if they changed
+func updateWebSubLinks(ctx context.Context, s *state, feed database.Feed, fetchedFeed *RSSFeed) error {
+	hub := fetchedFeed.atomLink("hub")
+	self := fetchedFeed.atomLink("self")
+
+	if hub == feed.HubUrl.String && self == feed.SelfUrl.String {
+		return nil
+	}
+
+	return s.db.SetFeedWebSubLinks(
+		ctx,
+		database.SetFeedWebSubLinksParams{
+			ID: feed.ID,
+			HubUrl: sql.NullString{String: hub, Valid: hub != ""},
+			SelfUrl: sql.NullString{String: self, Valid: self != ""},
+		},
+	)
 }
 
 func parseTime(str string) (time.Time, error) {
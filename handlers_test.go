@@ -0,0 +1,716 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/matt-horst/blog-agg/internal/database"
+
+	"github.com/google/uuid"
+)
+
+func TestHandlerAddFeed(t *testing.T) {
+	ctx := context.Background()
+	s := newTestState(t)
+	alice := createTestUser(t, s, "alice")
+
+	feed := createTestFeed(t, s, alice, "Example", "https://example.com/rss")
+	if feed.Name != "Example" || feed.UserID != alice.ID {
+		t.Errorf("Got feed %+v, want `Example` owned by alice", feed)
+	}
+
+	following, err := s.db.GetFeedFollowsForUser(ctx, alice.Name)
+	if err != nil {
+		t.Fatalf("Failed to get follows: %v", err)
+	}
+	if len(following) != 1 || following[0].FeedID != feed.ID {
+		t.Errorf("Got follows %+v, want only the new feed", following)
+	}
+
+	_, err = runHandler(t, handlerAddFeed, s, alice, "Again", "https://example.com/rss")
+	if err == nil {
+		t.Error("Adding a feed with a URL already in use succeeded")
+	}
+
+	_, err = runHandler(t, handlerAddFeed, s, alice, "Missing URL")
+	if err == nil {
+		t.Error("addfeed with one argument succeeded")
+	}
+
+	for _, url := range []string{"example.org/rss", "ftp://example.org/rss", "https:///rss"} {
+		_, err = runHandler(t, handlerAddFeed, s, alice, "Invalid", url)
+		if err == nil || !strings.Contains(err.Error(), "Invalid URL") {
+			t.Errorf("addfeed with `%s` returned %v, want an invalid URL error", url, err)
+		}
+	}
+	feeds, err := s.db.GetFeeds(ctx)
+	if err != nil {
+		t.Fatalf("Failed to get feeds: %v", err)
+	}
+	if len(feeds) != 1 {
+		t.Errorf("Got %d feeds, want only the valid one", len(feeds))
+	}
+}
+
+func TestHandlerFollow(t *testing.T) {
+	ctx := context.Background()
+	s := newTestState(t)
+	alice := createTestUser(t, s, "alice")
+	bob := createTestUser(t, s, "bob")
+	feed := createTestFeed(t, s, alice, "Example", "https://example.com/rss")
+
+	out, err := runHandler(t, handlerFollow, s, bob, feed.Url)
+	if err != nil {
+		t.Fatalf("follow failed: %v", err)
+	}
+	if out != "Example - bob\n" {
+		t.Errorf("follow printed %q, want %q", out, "Example - bob\n")
+	}
+
+	_, err = s.db.GetFeedFollow(ctx, database.GetFeedFollowParams{UserID: bob.ID, FeedID: feed.ID})
+	if err != nil {
+		t.Errorf("Follow wasn't stored: %v", err)
+	}
+
+	_, err = runHandler(t, handlerFollow, s, bob, feed.Url)
+	if err == nil {
+		t.Error("Following the same feed twice succeeded")
+	}
+
+	_, err = runHandler(t, handlerFollow, s, bob, "https://example.com/unknown")
+	if err == nil || !strings.Contains(err.Error(), "Unable to find feed") {
+		t.Errorf("Following an unknown feed returned %v, want `Unable to find feed`", err)
+	}
+
+	_, err = runHandler(t, handlerFollow, s, bob)
+	if err == nil {
+		t.Error("follow without a URL succeeded")
+	}
+}
+
+func TestHandlerFollowing(t *testing.T) {
+	ctx := context.Background()
+	s := newTestState(t)
+	alice := createTestUser(t, s, "alice")
+	createTestFeed(t, s, alice, "Zebra News", "https://zebra.example.com/rss")
+	apples := createTestFeed(t, s, alice, "Apple Daily", "https://apples.example.com/rss")
+
+	err := s.db.UpdateFeedFollowDetails(
+		ctx,
+		database.UpdateFeedFollowDetailsParams{
+			UserID: alice.ID,
+			FeedID: apples.ID,
+			Title: "Apples",
+			Notes: "Mostly orchards",
+			Priority: 2,
+		},
+	)
+	if err != nil {
+		t.Fatalf("Failed to update follow: %v", err)
+	}
+
+	out, err := runHandler(t, handlerFollowing, s, alice)
+	if err != nil {
+		t.Fatalf("following failed: %v", err)
+	}
+
+	want := "* Apples (priority 2)\n  Mostly orchards\n* Zebra News\n"
+	if out != want {
+		t.Errorf("following printed %q, want %q", out, want)
+	}
+}
+
+func TestHandlerUnfollow(t *testing.T) {
+	ctx := context.Background()
+	s := newTestState(t)
+	alice := createTestUser(t, s, "alice")
+	feed := createTestFeed(t, s, alice, "Example", "https://example.com/rss")
+
+	_, err := runHandler(t, handlerUnfollow, s, alice, feed.Url)
+	if err != nil {
+		t.Fatalf("unfollow failed: %v", err)
+	}
+
+	_, err = s.db.GetFeedFollow(ctx, database.GetFeedFollowParams{UserID: alice.ID, FeedID: feed.ID})
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Follow still exists after unfollow: %v", err)
+	}
+
+	_, err = runHandler(t, handlerUnfollow, s, alice, feed.Url)
+	if err == nil {
+		t.Error("Unfollowing a feed that isn't followed succeeded")
+	}
+
+	_, err = runHandler(t, handlerUnfollow, s, alice, "https://example.com/unknown")
+	if err == nil {
+		t.Error("Unfollowing an unknown feed succeeded")
+	}
+}
+
+func createTestPost(t *testing.T, s *state, feed database.Feed, title string, publishedAt time.Time) database.Post {
+	t.Helper()
+
+	post, err := s.db.CreatePost(
+		context.Background(),
+		database.CreatePostParams{
+			ID: uuid.New(),
+			Title: title,
+			Url: feed.Url + "/" + strings.ReplaceAll(strings.ToLower(title), " ", "-"),
+			PublishedAt: sql.NullTime{Time: publishedAt, Valid: true},
+			FeedID: feed.ID,
+			Categories: []string{},
+		},
+	)
+	if err != nil {
+		t.Fatalf("Failed to create post `%s`: %v", title, err)
+	}
+
+	return post
+}
+
+// The titles of the posts browse printed, in order
+func browseTitles(out string) []string {
+	titles := []string{}
+	for _, line := range strings.Split(out, "\n") {
+		if strings.HasPrefix(line, "*** ") && strings.HasSuffix(line, " ***") {
+			titles = append(titles, strings.TrimSuffix(strings.TrimPrefix(line, "*** "), " ***"))
+		}
+	}
+
+	return titles
+}
+
+// The cursor browse printed for the next page, if any
+func browseCursor(out string) string {
+	_, cursor, ok := strings.Cut(out, "--after ")
+	if !ok {
+		return ""
+	}
+
+	return strings.TrimSpace(cursor)
+}
+
+func TestHandlerBrowse(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2024, time.March, d, 12, 0, 0, 0, time.UTC)
+	}
+
+	// alice follows tech and cooking; bob's garden feed isn't hers
+	setup := func(t *testing.T) (*state, database.User, database.Feed, database.Feed) {
+		s := newTestState(t)
+		alice := createTestUser(t, s, "alice")
+		bob := createTestUser(t, s, "bob")
+		tech := createTestFeed(t, s, alice, "Tech", "https://tech.example.com/rss")
+		cooking := createTestFeed(t, s, alice, "Cooking", "https://cooking.example.com/rss")
+		garden := createTestFeed(t, s, bob, "Garden", "https://garden.example.com/rss")
+
+		createTestPost(t, s, tech, "Tech 1", day(1))
+		createTestPost(t, s, cooking, "Cooking 2", day(2))
+		createTestPost(t, s, tech, "Tech 3", day(3))
+		createTestPost(t, s, cooking, "Cooking 4", day(4))
+		createTestPost(t, s, tech, "Tech 5", day(5))
+		createTestPost(t, s, garden, "Garden 6", day(6))
+
+		return s, alice, tech, cooking
+	}
+
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{name: "default limit", args: nil, want: []string{"Tech 5", "Cooking 4"}},
+		{name: "explicit limit", args: []string{"10"}, want: []string{"Tech 5", "Cooking 4", "Tech 3", "Cooking 2", "Tech 1"}},
+		{name: "oldest first", args: []string{"--sort", "oldest", "3"}, want: []string{"Tech 1", "Cooking 2", "Tech 3"}},
+		{name: "one feed", args: []string{"--feed", "https://cooking.example.com/rss", "10"}, want: []string{"Cooking 4", "Cooking 2"}},
+		{name: "date range", args: []string{"--since", "2024-03-02", "--until", "2024-03-04", "10"}, want: []string{"Tech 3", "Cooking 2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, alice, _, _ := setup(t)
+
+			out, err := runHandler(t, handlerBrowse, s, alice, tt.args...)
+			if err != nil {
+				t.Fatalf("browse failed: %v", err)
+			}
+			if got := browseTitles(out); !slices.Equal(got, tt.want) {
+				t.Errorf("browse %v listed %q, want %q", tt.args, got, tt.want)
+			}
+		})
+	}
+
+	t.Run("min priority", func(t *testing.T) {
+		s, alice, _, cooking := setup(t)
+
+		err := s.db.UpdateFeedFollowDetails(
+			context.Background(),
+			database.UpdateFeedFollowDetailsParams{UserID: alice.ID, FeedID: cooking.ID, Title: "", Priority: 1},
+		)
+		if err != nil {
+			t.Fatalf("Failed to update follow: %v", err)
+		}
+
+		out, err := runHandler(t, handlerBrowse, s, alice, "--min-priority", "1", "10")
+		if err != nil {
+			t.Fatalf("browse failed: %v", err)
+		}
+		want := []string{"Cooking 4", "Cooking 2"}
+		if got := browseTitles(out); !slices.Equal(got, want) {
+			t.Errorf("browse --min-priority 1 listed %q, want %q", got, want)
+		}
+	})
+
+	t.Run("filter rules", func(t *testing.T) {
+		s, alice, _, cooking := setup(t)
+		ctx := context.Background()
+
+		_, err := s.db.CreateFilterRule(ctx, database.CreateFilterRuleParams{
+			ID: uuid.New(), UserID: alice.ID, Name: "no-3", Action: "exclude", Field: "title", Pattern: "tech 3",
+		})
+		if err != nil {
+			t.Fatalf("Failed to create exclude rule: %v", err)
+		}
+		_, err = s.db.CreateFilterRule(ctx, database.CreateFilterRuleParams{
+			ID: uuid.New(), UserID: alice.ID, Name: "only-4", Action: "include", Field: "title", Pattern: `\b4$`, IsRegex: true,
+			FeedID: uuid.NullUUID{UUID: cooking.ID, Valid: true},
+		})
+		if err != nil {
+			t.Fatalf("Failed to create include rule: %v", err)
+		}
+
+		out, err := runHandler(t, handlerBrowse, s, alice, "10")
+		if err != nil {
+			t.Fatalf("browse failed: %v", err)
+		}
+		want := []string{"Tech 5", "Cooking 4", "Tech 1"}
+		if got := browseTitles(out); !slices.Equal(got, want) {
+			t.Errorf("browse with filter rules listed %q, want %q", got, want)
+		}
+
+		out, err = runHandler(t, handlerBrowse, s, alice, "--unfiltered", "10")
+		if err != nil {
+			t.Fatalf("browse --unfiltered failed: %v", err)
+		}
+		if got := browseTitles(out); len(got) != 5 {
+			t.Errorf("browse --unfiltered listed %q, want all 5 posts", got)
+		}
+	})
+
+	t.Run("folder", func(t *testing.T) {
+		s, alice, tech, _ := setup(t)
+		ctx := context.Background()
+
+		work, err := s.db.CreateFolder(ctx, database.CreateFolderParams{ID: uuid.New(), UserID: alice.ID, Name: "Work"})
+		if err != nil {
+			t.Fatalf("Failed to create folder: %v", err)
+		}
+		reading, err := s.db.CreateFolder(ctx, database.CreateFolderParams{
+			ID: uuid.New(), UserID: alice.ID, Name: "Reading", ParentID: uuid.NullUUID{UUID: work.ID, Valid: true},
+		})
+		if err != nil {
+			t.Fatalf("Failed to create subfolder: %v", err)
+		}
+		_, err = s.db.CreateFolder(ctx, database.CreateFolderParams{ID: uuid.New(), UserID: alice.ID, Name: "Empty"})
+		if err != nil {
+			t.Fatalf("Failed to create folder: %v", err)
+		}
+		_, err = s.db.MoveFeedFollow(ctx, database.MoveFeedFollowParams{
+			UserID: alice.ID, FeedID: tech.ID, FolderID: uuid.NullUUID{UUID: reading.ID, Valid: true},
+		})
+		if err != nil {
+			t.Fatalf("Failed to move follow: %v", err)
+		}
+
+		out, err := runHandler(t, handlerBrowse, s, alice, "--folder", "Work", "10")
+		if err != nil {
+			t.Fatalf("browse --folder failed: %v", err)
+		}
+		want := []string{"Tech 5", "Tech 3", "Tech 1"}
+		if got := browseTitles(out); !slices.Equal(got, want) {
+			t.Errorf("browse --folder Work listed %q, want %q", got, want)
+		}
+
+		for _, folder := range []string{"Empty", "Missing"} {
+			_, err = runHandler(t, handlerBrowse, s, alice, "--folder", folder)
+			if err == nil {
+				t.Errorf("browse --folder %s succeeded", folder)
+			}
+		}
+
+		// Both filters have to match
+		out, err = runHandler(t, handlerBrowse, s, alice, "--folder", "Work", "--feed", tech.Url, "--feed", "https://cooking.example.com/rss", "10")
+		if err != nil {
+			t.Fatalf("browse --folder --feed failed: %v", err)
+		}
+		if got := browseTitles(out); !slices.Equal(got, want) {
+			t.Errorf("browse --folder Work with both feeds listed %q, want %q", got, want)
+		}
+
+		_, err = runHandler(t, handlerBrowse, s, alice, "--folder", "Work", "--feed", "https://cooking.example.com/rss")
+		if err == nil || !strings.Contains(err.Error(), "None of the given feeds") {
+			t.Errorf("browse --folder Work with a feed outside it returned %v", err)
+		}
+	})
+
+	t.Run("pages", func(t *testing.T) {
+		for _, order := range []string{"newest", "oldest"} {
+			s, alice, _, _ := setup(t)
+
+			all, err := runHandler(t, handlerBrowse, s, alice, "--sort", order, "10")
+			if err != nil {
+				t.Fatalf("browse failed: %v", err)
+			}
+			if browseCursor(all) != "" {
+				t.Errorf("browse printed a cursor with every post on one page")
+			}
+
+			got := []string{}
+			args := []string{"--sort", order, "2"}
+			for page := 0; page < 5; page++ {
+				out, err := runHandler(t, handlerBrowse, s, alice, args...)
+				if err != nil {
+					t.Fatalf("browse %v failed: %v", args, err)
+				}
+				got = append(got, browseTitles(out)...)
+
+				cursor := browseCursor(out)
+				if cursor == "" {
+					break
+				}
+				args = []string{"--sort", order, "--after", cursor, "2"}
+			}
+
+			if want := browseTitles(all); !slices.Equal(got, want) {
+				t.Errorf("Paging %s first listed %q, want %q", order, got, want)
+			}
+		}
+	})
+
+	t.Run("mark read", func(t *testing.T) {
+		s, alice, _, _ := setup(t)
+
+		out, err := runHandler(t, handlerBrowse, s, alice, "--mark-read")
+		if err != nil {
+			t.Fatalf("browse --mark-read failed: %v", err)
+		}
+		if !strings.HasPrefix(out, "Found 2 unread posts for you!\n") {
+			t.Errorf("browse --mark-read printed %q", out)
+		}
+
+		out, err = runHandler(t, handlerBrowse, s, alice, "10")
+		if err != nil {
+			t.Fatalf("browse failed: %v", err)
+		}
+		want := []string{"Tech 3", "Cooking 2", "Tech 1"}
+		if got := browseTitles(out); !slices.Equal(got, want) {
+			t.Errorf("browse after marking read listed %q, want %q", got, want)
+		}
+
+		out, err = runHandler(t, handlerBrowse, s, alice, "--all", "10")
+		if err != nil {
+			t.Fatalf("browse --all failed: %v", err)
+		}
+		if got := browseTitles(out); len(got) != 5 {
+			t.Errorf("browse --all listed %q, want all 5 posts", got)
+		}
+		if !strings.HasPrefix(out, "Found 5 posts for you!\n") {
+			t.Errorf("browse --all printed %q", out)
+		}
+	})
+
+	t.Run("invalid arguments", func(t *testing.T) {
+		s, alice, _, _ := setup(t)
+
+		for _, args := range [][]string{
+			{"--sort", "sideways"},
+			{"--after", "not-a-cursor"},
+			{"--feed", "https://unknown.example.com/rss"},
+			{"--since", "yesterday"},
+			{"many"},
+		} {
+			_, err := runHandler(t, handlerBrowse, s, alice, args...)
+			if err == nil {
+				t.Errorf("browse %v succeeded", args)
+			}
+		}
+	})
+}
+
+func TestBrowseCursor(t *testing.T) {
+	published := time.Date(2024, time.May, 1, 8, 30, 0, 123, time.UTC)
+	created := time.Date(2024, time.May, 2, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		post database.Post
+		want time.Time
+	}{
+		{post: database.Post{ID: uuid.New(), CreatedAt: created, PublishedAt: sql.NullTime{Time: published, Valid: true}}, want: published},
+		{post: database.Post{ID: uuid.New(), CreatedAt: created}, want: created},
+	}
+	for _, tt := range tests {
+		sortTime, id, err := decodeBrowseCursor(encodeBrowseCursor(tt.post))
+		if err != nil {
+			t.Fatalf("Failed to decode cursor: %v", err)
+		}
+		if !sortTime.Time.Equal(tt.want) || id.UUID != tt.post.ID {
+			t.Errorf("Cursor for %+v decoded to %v, %v", tt.post, sortTime.Time, id.UUID)
+		}
+	}
+}
+
+func TestScrapeFeeds(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("no feeds", func(t *testing.T) {
+		s := newTestState(t)
+
+		result, err := scrapeFeeds(ctx, s)
+		if err != nil || result != (scrapeResult{}) {
+			t.Errorf("Scraping no feeds returned %+v, %v", result, err)
+		}
+	})
+
+	t.Run("new and duplicate posts", func(t *testing.T) {
+		s := newTestState(t)
+		server := newFeedServer(t)
+		alice := createTestUser(t, s, "alice")
+
+		published := time.Date(2024, time.June, 1, 9, 0, 0, 0, time.UTC)
+		items := []testItem{
+			{
+				title: "Fish &amp;amp; Chips",
+				link: server.URL + "/posts/1",
+				description: "&lt;p&gt;Crispy&lt;/p&gt;",
+				pubDate: rfc1123(published),
+				creator: "Jo Cook",
+				categories: []string{"Food", "food", " ", "Recipes"},
+			},
+			{title: "Undated", link: server.URL + "/posts/2", pubDate: "sometime soon"},
+		}
+		url := server.serve("/feed.xml", http.StatusOK, rssDocument("Test", "", "", items...))
+		feed := createTestFeed(t, s, alice, "Test", url)
+
+		result, err := scrapeFeeds(ctx, s)
+		if err != nil {
+			t.Fatalf("Scrape failed: %v", err)
+		}
+		if result != (scrapeResult{newPosts: 2}) {
+			t.Errorf("First scrape returned %+v, want 2 new posts", result)
+		}
+		if got := server.userAgents; !slices.Equal(got, []string{"gator"}) {
+			t.Errorf("Scrape sent User-Agent %q, want gator", got)
+		}
+
+		post, err := s.db.GetPostByURL(ctx, server.URL+"/posts/1")
+		if err != nil {
+			t.Fatalf("Post wasn't saved: %v", err)
+		}
+		if post.Title != "Fish & Chips" || post.Description != "<p>Crispy</p>" {
+			t.Errorf("Post saved with title %q and description %q, want them unescaped", post.Title, post.Description)
+		}
+		if post.Author != "Jo Cook" || !slices.Equal(post.Categories, []string{"Food", "Recipes"}) {
+			t.Errorf("Post saved with author %q and categories %q", post.Author, post.Categories)
+		}
+		if !post.PublishedAt.Valid || !post.PublishedAt.Time.Equal(published) {
+			t.Errorf("Post saved as published at %v, want %v", post.PublishedAt, published)
+		}
+
+		undated, err := s.db.GetPostByURL(ctx, server.URL+"/posts/2")
+		if err != nil {
+			t.Fatalf("Post without a valid date wasn't saved: %v", err)
+		}
+		if undated.PublishedAt.Valid {
+			t.Errorf("Post with an invalid date saved as published at %v", undated.PublishedAt.Time)
+		}
+
+		feed, err = s.db.GetFeedByID(ctx, feed.ID)
+		if err != nil {
+			t.Fatalf("Failed to get feed: %v", err)
+		}
+		if !feed.LastFetchedAt.Valid {
+			t.Error("Feed wasn't marked as fetched")
+		}
+
+		items = append(items, testItem{title: "Later", link: server.URL + "/posts/3", pubDate: rfc1123(published.Add(time.Hour))})
+		server.serve("/feed.xml", http.StatusOK, rssDocument("Test", "", "", items...))
+
+		result, err = scrapeFeeds(ctx, s)
+		if err != nil {
+			t.Fatalf("Second scrape failed: %v", err)
+		}
+		if result != (scrapeResult{newPosts: 1, duplicates: 2}) {
+			t.Errorf("Second scrape returned %+v, want 1 new post and 2 duplicates", result)
+		}
+
+		count, err := s.db.CountFeedPosts(ctx, feed.ID)
+		if err != nil || count != 3 {
+			t.Errorf("Feed has %d posts (%v), want 3", count, err)
+		}
+
+		fetches, err := s.db.GetFeedFetchesSince(ctx, time.Time{})
+		if err != nil {
+			t.Fatalf("Failed to get fetch history: %v", err)
+		}
+		newPosts := []int32{}
+		for _, f := range fetches {
+			if f.FeedID != feed.ID || f.StatusCode.Int32 != http.StatusOK || f.ItemsSeen != int32(len(newPosts)+2) {
+				t.Errorf("Fetch recorded as %+v", f)
+			}
+			newPosts = append(newPosts, f.NewPosts)
+		}
+		if !slices.Equal(newPosts, []int32{2, 1}) {
+			t.Errorf("Fetch history recorded %v new posts, want [2 1]", newPosts)
+		}
+	})
+
+	t.Run("updated posts", func(t *testing.T) {
+		s := newTestState(t)
+		server := newFeedServer(t)
+		alice := createTestUser(t, s, "alice")
+
+		published := time.Date(2024, time.June, 1, 9, 0, 0, 0, time.UTC)
+		items := []testItem{
+			{title: "Draft", link: server.URL + "/posts/1", pubDate: rfc1123(published), categories: []string{"Go"}},
+			{title: "Steady", link: server.URL + "/posts/2", pubDate: rfc1123(published)},
+		}
+		url := server.serve("/feed.xml", http.StatusOK, rssDocument("Test", "", "", items...))
+		createTestFeed(t, s, alice, "Test", url)
+
+		_, err := scrapeFeeds(ctx, s)
+		if err != nil {
+			t.Fatalf("Scrape failed: %v", err)
+		}
+		before, err := s.db.GetPostByURL(ctx, server.URL+"/posts/1")
+		if err != nil {
+			t.Fatalf("Post wasn't saved: %v", err)
+		}
+
+		items[0].title = "Final"
+		items[0].categories = []string{"Go", "Releases"}
+		server.serve("/feed.xml", http.StatusOK, rssDocument("Test", "", "", items...))
+
+		result, err := scrapeFeeds(ctx, s)
+		if err != nil {
+			t.Fatalf("Second scrape failed: %v", err)
+		}
+		if result != (scrapeResult{updated: 1, duplicates: 1}) {
+			t.Errorf("Scrape of a changed item returned %+v, want 1 updated and 1 duplicate", result)
+		}
+
+		after, err := s.db.GetPostByURL(ctx, server.URL+"/posts/1")
+		if err != nil {
+			t.Fatalf("Post is gone: %v", err)
+		}
+		if after.ID != before.ID || after.Title != "Final" || !slices.Equal(after.Categories, []string{"Go", "Releases"}) {
+			t.Errorf("Post %s updated to %+v, want the same post with the new title and categories", before.ID, after)
+		}
+
+		result, err = scrapeFeeds(ctx, s)
+		if err != nil {
+			t.Fatalf("Third scrape failed: %v", err)
+		}
+		if result != (scrapeResult{duplicates: 2}) {
+			t.Errorf("Scrape of unchanged items returned %+v, want 2 duplicates", result)
+		}
+	})
+
+	t.Run("least recently fetched first", func(t *testing.T) {
+		s := newTestState(t)
+		server := newFeedServer(t)
+		alice := createTestUser(t, s, "alice")
+
+		for _, path := range []string{"/a.xml", "/b.xml", "/c.xml"} {
+			url := server.serve(path, http.StatusOK, rssDocument(path, "", ""))
+			createTestFeed(t, s, alice, path, url)
+		}
+
+		paused, err := s.db.GetFeed(ctx, server.URL+"/c.xml")
+		if err != nil {
+			t.Fatalf("Failed to get feed: %v", err)
+		}
+		err = s.db.SetFeedPaused(ctx, database.SetFeedPausedParams{ID: paused.ID, PausedAt: sql.NullTime{Time: time.Now(), Valid: true}})
+		if err != nil {
+			t.Fatalf("Failed to pause feed: %v", err)
+		}
+
+		for i := 0; i < 4; i++ {
+			_, err := scrapeFeeds(ctx, s)
+			if err != nil {
+				t.Fatalf("Scrape failed: %v", err)
+			}
+		}
+
+		for path, want := range map[string]int{"/a.xml": 2, "/b.xml": 2, "/c.xml": 0} {
+			if got := server.hitsFor(path); got != want {
+				t.Errorf("%s fetched %d times, want %d", path, got, want)
+			}
+		}
+	})
+
+	t.Run("websub links", func(t *testing.T) {
+		s := newTestState(t)
+		server := newFeedServer(t)
+		alice := createTestUser(t, s, "alice")
+
+		url := server.serve("/feed.xml", http.StatusOK, rssDocument("Test", "https://hub.example.com/", server.URL+"/canonical.xml"))
+		feed := createTestFeed(t, s, alice, "Test", url)
+
+		_, err := scrapeFeeds(ctx, s)
+		if err != nil {
+			t.Fatalf("Scrape failed: %v", err)
+		}
+
+		feed, err = s.db.GetFeedByID(ctx, feed.ID)
+		if err != nil {
+			t.Fatalf("Failed to get feed: %v", err)
+		}
+		if feed.HubUrl.String != "https://hub.example.com/" || feed.SelfUrl.String != server.URL+"/canonical.xml" {
+			t.Errorf("Feed saved with hub %v and self %v", feed.HubUrl, feed.SelfUrl)
+		}
+	})
+
+	t.Run("failed fetches", func(t *testing.T) {
+		tests := []struct {
+			name string
+			status int
+			body string
+			want error
+		}{
+			{name: "server error", status: http.StatusInternalServerError, body: "oops", want: errUnexpectedStatus},
+			{name: "not a feed", status: http.StatusOK, body: "<html><body>Hello</html>", want: errFeedParse},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				s := newTestState(t)
+				server := newFeedServer(t)
+				alice := createTestUser(t, s, "alice")
+
+				url := server.serve("/feed.xml", tt.status, tt.body)
+				feed := createTestFeed(t, s, alice, "Broken", url)
+
+				result, err := scrapeFeeds(ctx, s)
+				if !errors.Is(err, tt.want) {
+					t.Errorf("Scrape returned %v, want %v", err, tt.want)
+				}
+				if result != (scrapeResult{}) {
+					t.Errorf("Failed scrape returned %+v", result)
+				}
+
+				// Marked as fetched anyway, so a broken feed doesn't hold up the rest
+				feed, err = s.db.GetFeedByID(ctx, feed.ID)
+				if err != nil {
+					t.Fatalf("Failed to get feed: %v", err)
+				}
+				if !feed.LastFetchedAt.Valid {
+					t.Error("Feed wasn't marked as fetched")
+				}
+			})
+		}
+	})
+}
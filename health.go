@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/matt-horst/blog-agg/internal/database"
+
+	"github.com/google/uuid"
+)
+
+const fetchHistoryPruneInterval = time.Hour
+
+// Writes one row of fetch history. Failures are logged rather than returned so
+// a history problem never stops the scrape itself.
+func recordFetch(ctx context.Context, s *state, feed database.Feed, start time.Time, stats fetchStats, itemsSeen int, result scrapeResult, fetchErr error) {
+	errText := sql.NullString{}
+	if fetchErr != nil {
+		errText.String = fetchErr.Error()
+		errText.Valid = true
+	}
+
+	err := s.db.CreateFeedFetch(
+		ctx,
+		database.CreateFeedFetchParams{
+			ID: uuid.New(),
+			FeedID: feed.ID,
+			StatusCode: sql.NullInt32{Int32: int32(stats.statusCode), Valid: stats.statusCode != 0},
+			DurationMs: int32(time.Since(start).Milliseconds()),
+			Bytes: stats.bytes,
+			ItemsSeen: int32(itemsSeen),
+			NewPosts: int32(result.newPosts),
+			Error: errText,
+		},
+	)
+	if err != nil {
+		feedLogger(feed).Error("Failed to record fetch history", errAttrs(err))
+	}
+}
+
+// Deletes fetch history older than the configured retention
+func pruneFetchHistory(ctx context.Context, s *state) error {
+	cutoff := time.Now().Add(-s.cfg.FetchHistoryRetention())
+
+	deleted, err := s.db.DeleteFeedFetchesBefore(ctx, cutoff)
+	if err != nil {
+		return fmt.Errorf("Failed to prune fetch history: %w", err)
+	}
+
+	if deleted > 0 {
+		slog.Info("Pruned fetch history", slog.Int64("rows", deleted), slog.Time("before", cutoff))
+	}
+
+	return nil
+}
+
+type feedHealth struct {
+	feed database.GetFeedsRow
+	attempts int
+	successes int
+	lastSuccess time.Time
+	lastNewPost time.Time
+	newPosts int
+	lastError string
+}
+
+func handlerHealth(ctx context.Context, s *state, cmd command) error {
+	fs := flag.NewFlagSet("health", flag.ContinueOnError)
+	days := fs.Int("days", 3, "flag feeds failing or without new posts for this many days")
+	window := fs.Int("window", 0, "days of history to report on (default: the configured retention)")
+
+	positional, err := parseFlags(fs, cmd.args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 0 {
+		return fmt.Errorf("health takes no arguments besides flags")
+	}
+
+	windowDuration := s.cfg.FetchHistoryRetention()
+	if *window > 0 {
+		windowDuration = time.Duration(*window) * 24 * time.Hour
+	}
+	since := time.Now().Add(-windowDuration)
+	threshold := time.Now().Add(-time.Duration(*days) * 24 * time.Hour)
+
+	feeds, err := s.db.GetFeeds(ctx)
+	if err != nil {
+		return fmt.Errorf("Failed to get feeds from database: %v", err)
+	}
+
+	fetches, err := s.db.GetFeedFetchesSince(ctx, since)
+	if err != nil {
+		return fmt.Errorf("Failed to get fetch history: %v", err)
+	}
+
+	health := map[uuid.UUID]*feedHealth{}
+	for _, feed := range feeds {
+		health[feed.ID] = &feedHealth{feed: feed}
+	}
+
+	for _, f := range fetches {
+		h, ok := health[f.FeedID]
+		if !ok {
+			continue
+		}
+
+		h.attempts++
+		h.newPosts += int(f.NewPosts)
+		if !f.Error.Valid {
+			h.successes++
+		}
+	}
+
+	// The status goes by the whole history rather than the window, so a short
+	// window doesn't hide an older success or post
+	lastFetches, err := s.db.GetLastFeedFetches(ctx)
+	if err != nil {
+		return fmt.Errorf("Failed to get fetch history: %v", err)
+	}
+	for _, f := range lastFetches {
+		if h, ok := health[f.FeedID]; ok && f.Error.Valid {
+			h.lastError = f.Error.String
+		}
+	}
+
+	lastSuccesses, err := s.db.GetLastSuccessfulFeedFetches(ctx)
+	if err != nil {
+		return fmt.Errorf("Failed to get fetch history: %v", err)
+	}
+	for _, f := range lastSuccesses {
+		if h, ok := health[f.FeedID]; ok {
+			h.lastSuccess = f.FetchedAt
+		}
+	}
+
+	lastPosts, err := s.db.GetLastPostTimes(ctx)
+	if err != nil {
+		return fmt.Errorf("Failed to get posts: %v", err)
+	}
+	for _, p := range lastPosts {
+		if h, ok := health[p.FeedID]; ok {
+			h.lastNewPost = p.CreatedAt
+		}
+	}
+
+	windowDays := windowDuration.Hours() / 24
+	failing := []*feedHealth{}
+	silent := []*feedHealth{}
+
+	fmt.Printf("Feed health over the last %.0f days:\n", windowDays)
+	for _, feed := range feeds {
+		h := health[feed.ID]
+
+		rate := "n/a"
+		if h.attempts > 0 {
+			rate = fmt.Sprintf("%.0f%%", 100*float64(h.successes)/float64(h.attempts))
+		}
+
+		fmt.Printf("* %s (%s)\n", feed.Name, feed.Url)
+		fmt.Printf("	success rate: %s of %d fetches\n", rate, h.attempts)
+		fmt.Printf("	last success: %s\n", formatHealthTime(h.lastSuccess))
+		fmt.Printf("	new posts/day: %.2f\n", float64(h.newPosts)/windowDays)
+
+		if h.failing(threshold) {
+			failing = append(failing, h)
+		} else if h.silent(threshold) {
+			silent = append(silent, h)
+		}
+	}
+
+	fmt.Println()
+	fmt.Printf("Failing for %d+ days: %d\n", *days, len(failing))
+	for _, h := range failing {
+		fmt.Printf("* %s (%s): %s\n", h.feed.Name, h.feed.Url, h.lastError)
+	}
+
+	fmt.Printf("No new posts for %d+ days: %d\n", *days, len(silent))
+	for _, h := range silent {
+		fmt.Printf("* %s (%s)\n", h.feed.Name, h.feed.Url)
+	}
+
+	return nil
+}
+
+// Whether the feed has been fetched but not successfully since threshold. A
+// feed that has never worked counts from when it was added.
+func (h *feedHealth) failing(threshold time.Time) bool {
+	lastSuccess := h.lastSuccess
+	if lastSuccess.IsZero() {
+		lastSuccess = h.feed.CreatedAt
+	}
+
+	return h.feed.LastFetchedAt.Valid && lastSuccess.Before(threshold)
+}
+
+// Whether the feed has had no new posts since threshold. A feed that hasn't
+// had any yet counts from when it was added, so new feeds aren't reported
+// silent before they've had the chance to post.
+func (h *feedHealth) silent(threshold time.Time) bool {
+	lastNewPost := h.lastNewPost
+	if lastNewPost.IsZero() {
+		lastNewPost = h.feed.CreatedAt
+	}
+
+	return lastNewPost.Before(threshold)
+}
+
+func formatHealthTime(t time.Time) string {
+	if t.IsZero() {
+		return "never"
+	}
+
+	return t.Format("Mon Jan 2, 2006 15:04")
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/matt-horst/blog-agg/internal/database"
+)
+
+func TestFeedHealthStatus(t *testing.T) {
+	now := time.Now()
+	threshold := now.Add(-3 * 24 * time.Hour)
+	old := now.Add(-30 * 24 * time.Hour)
+	recent := now.Add(-time.Hour)
+	fetched := sql.NullTime{Time: recent, Valid: true}
+
+	tests := []struct {
+		name string
+		h feedHealth
+		failing bool
+		silent bool
+	}{
+		{name: "new feed, not fetched yet", h: feedHealth{feed: database.GetFeedsRow{CreatedAt: recent}}},
+		{name: "new feed, failed so far", h: feedHealth{feed: database.GetFeedsRow{CreatedAt: recent, LastFetchedAt: fetched}, attempts: 2}},
+		{name: "new feed, no posts yet", h: feedHealth{feed: database.GetFeedsRow{CreatedAt: recent, LastFetchedAt: fetched}, attempts: 1, successes: 1, lastSuccess: recent}},
+		{name: "old feed, never posted", h: feedHealth{feed: database.GetFeedsRow{CreatedAt: old, LastFetchedAt: fetched}, attempts: 1, successes: 1, lastSuccess: recent}, silent: true},
+		{name: "old feed, never worked", h: feedHealth{feed: database.GetFeedsRow{CreatedAt: old, LastFetchedAt: fetched}, attempts: 5}, failing: true, silent: true},
+		{name: "old feed, posting", h: feedHealth{feed: database.GetFeedsRow{CreatedAt: old, LastFetchedAt: fetched}, attempts: 1, successes: 1, lastSuccess: recent, lastNewPost: recent}},
+		{name: "old feed, stopped posting", h: feedHealth{feed: database.GetFeedsRow{CreatedAt: old, LastFetchedAt: fetched}, attempts: 1, successes: 1, lastSuccess: recent, lastNewPost: old}, silent: true},
+		{name: "old feed, stopped working", h: feedHealth{feed: database.GetFeedsRow{CreatedAt: old, LastFetchedAt: fetched}, attempts: 3, successes: 1, lastSuccess: old, lastNewPost: old}, failing: true, silent: true},
+	}
+	for _, tt := range tests {
+		if got := tt.h.failing(threshold); got != tt.failing {
+			t.Errorf("%s: failing = %v, want %v", tt.name, got, tt.failing)
+		}
+		if got := tt.h.silent(threshold); got != tt.silent {
+			t.Errorf("%s: silent = %v, want %v", tt.name, got, tt.silent)
+		}
+	}
+}
@@ -2,68 +2,339 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path"
+	"time"
 )
 
-const configFileName = ".gatorconfig.json"
+const configDirName = "gator"
+
+const configFileName = "config.json"
+
+// Where the config lived before it moved under $XDG_CONFIG_HOME
+const legacyConfigFileName = ".gatorconfig.json"
+
+// Version of the file layout this build writes. Older files are upgraded
+// when read; newer ones are refused rather than risk dropping settings this
+// build doesn't know about.
+const currentVersion = 1
+
+// Upgrades a file from version i to i+1
+var upgrades = []func(f *configFile){
+	// Unversioned files have the same layout; only the version is recorded
+	func(f *configFile) {},
+}
+
+const defaultFetchHistoryDays = 30
+
+// Returned, wrapped, when neither the config file nor a legacy one exists
+var ErrNoConfigFile = errors.New("No config file found")
 
 type Config struct {
 	DbURL string 			`json:"db_url"`
 	CurrentUserName string 	`json:"current_user_name,omitempty"`
+	// Proves who CurrentUserName is; issued by login
+	SessionToken string 	`json:"session_token,omitempty"`
+	LogLevel string 		`json:"log_level,omitempty"`
+	LogFormat string 		`json:"log_format,omitempty"`
+	// Days of fetch history to keep; 0 means the default
+	FetchHistoryDays int 	`json:"fetch_history_days,omitempty"`
+	// Outgoing mail server for email alerts
+	SMTP *SMTPConfig 		`json:"smtp,omitempty"`
+	// Default post retention for feeds without their own policy
+	Retention *RetentionPolicy 	`json:"retention,omitempty"`
+
+	// Which profile these settings were read from, so writes go back there
+	profile string
+}
+
+// The file on disk. The default profile's settings are kept at the top level,
+// where they were before profiles existed.
+type configFile struct {
+	Version int 				`json:"version"`
+	Config
+	CurrentProfile string 		`json:"current_profile,omitempty"`
+	Profiles map[string]Config 	`json:"profiles,omitempty"`
+}
+
+type SMTPConfig struct {
+	Host string 	`json:"host"`
+	Port int 		`json:"port"`
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	From string 	`json:"from"`
+}
+
+// Posts to keep per feed; 0 means no limit. A post is kept if either rule
+// keeps it.
+type RetentionPolicy struct {
+	KeepLast int 	`json:"keep_last,omitempty"`
+	KeepDays int 	`json:"keep_days,omitempty"`
 }
 
 func Read() (Config, error) {
-	filepath, err := getConfigFilePath()
+	return ReadProfile("")
+}
+
+// Reads the settings of the named profile, with any GATOR_* environment
+// variables applied on top. Without a name, GATOR_PROFILE is used, then the
+// profile last switched to, then the default profile.
+func ReadProfile(name string) (Config, error) {
+	f, err := readFile()
 	if err != nil {
 		return Config{}, err
 	}
 
-	data, err := os.ReadFile(filepath)
+	return readProfile(f, name)
+}
+
+// Like ReadProfile, but a missing config file reads as an empty one, for
+// the commands that create it
+func ReadProfileOrEmpty(name string) (Config, error) {
+	f, err := readFile()
+	if errors.Is(err, ErrNoConfigFile) {
+		f, err = configFile{}, nil
+	}
 	if err != nil {
-		return Config{}, fmt.Errorf("Failed to read `%s`: %v\n", filepath, err)
+		return Config{}, err
 	}
 
-	var config Config
-	err = json.Unmarshal(data, &config)
+	return readProfile(f, name)
+}
+
+func readProfile(f configFile, name string) (Config, error) {
+	if name == "" {
+		name = os.Getenv(profileEnvVar)
+	}
+	if name == "" {
+		name = f.CurrentProfile
+	}
+	if name == "" {
+		name = DefaultProfile
+	}
+
+	config, ok := f.profile(name)
+	if !ok {
+		return Config{}, fmt.Errorf("No profile named `%s`", name)
+	}
+	config.profile = name
+
+	err := config.applyEnv()
 	if err != nil {
-		return Config{}, fmt.Errorf("Failed to unmarshal json: %v\n", err)
+		return Config{}, err
 	}
 
 	return config, nil
 }
 
+// How long fetch history should be kept
+func (cfg Config) FetchHistoryRetention() time.Duration {
+	days := cfg.FetchHistoryDays
+	if days <= 0 {
+		days = defaultFetchHistoryDays
+	}
+
+	return time.Duration(days) * 24 * time.Hour
+}
+
 func (cfg Config) SetUser(userName string) error {
-	cfg.CurrentUserName = userName
+	return updateProfile(cfg.Profile(), func(c *Config) error {
+		c.CurrentUserName = userName
+		return nil
+	})
+}
 
-	err := write(cfg)
-	return err
+// Stores the user and session token from a successful login, or clears both
+// when given empty strings
+func (cfg Config) SetSession(userName, token string) error {
+	return updateProfile(cfg.Profile(), func(c *Config) error {
+		c.CurrentUserName = userName
+		c.SessionToken = token
+		return nil
+	})
 }
 
-func getConfigFilePath() (string, error) {
+// Changes the stored settings of a profile. Only what update changes is
+// written, so values that came from the environment stay out of the file.
+// The file is created if there isn't one yet.
+func updateProfile(name string, update func(c *Config) error) error {
+	f, err := readFile()
+	if errors.Is(err, ErrNoConfigFile) {
+		f, err = configFile{}, nil
+	}
+	if err != nil {
+		return err
+	}
+
+	config, ok := f.profile(name)
+	if !ok {
+		return fmt.Errorf("No profile named `%s`", name)
+	}
+
+	err = update(&config)
+	if err != nil {
+		return err
+	}
+
+	f.setProfile(name, config)
+
+	return writeFile(f)
+}
+
+// $XDG_CONFIG_HOME/gator/config.json, falling back to ~/.config when
+// XDG_CONFIG_HOME isn't set
+func Path() (string, error) {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("Failed to get users home dir: %v\n", err)
+		}
+		dir = path.Join(home, ".config")
+	}
+
+	return path.Join(dir, configDirName, configFileName), nil
+}
+
+func legacyPath() (string, error) {
 	home, err := os.UserHomeDir()
 	if err != nil {
 		return "", fmt.Errorf("Failed to get users home dir: %v\n", err)
 	}
 
-	filepath := path.Join(home, configFileName)
+	return path.Join(home, legacyConfigFileName), nil
+}
 
-	return filepath, nil
+// Reads the config file, moving it from the legacy location and upgrading
+// its layout first if needed
+func readFile() (configFile, error) {
+	filepath, err := Path()
+	if err != nil {
+		return configFile{}, err
+	}
+
+	data, err := os.ReadFile(filepath)
+	if errors.Is(err, os.ErrNotExist) {
+		return migrateLegacyFile(filepath)
+	}
+	if err != nil {
+		return configFile{}, fmt.Errorf("Failed to read `%s`: %v\n", filepath, err)
+	}
+
+	f, err := parseFile(data)
+	if err != nil {
+		return configFile{}, fmt.Errorf("Invalid config `%s`: %v", filepath, err)
+	}
+
+	if f.Version < currentVersion {
+		upgrade(&f)
+		err = writeFile(f)
+		if err != nil {
+			return configFile{}, err
+		}
+	}
+
+	return f, nil
+}
+
+func migrateLegacyFile(filepath string) (configFile, error) {
+	legacy, err := legacyPath()
+	if err != nil {
+		return configFile{}, err
+	}
+
+	data, err := os.ReadFile(legacy)
+	if errors.Is(err, os.ErrNotExist) {
+		return configFile{}, fmt.Errorf("%w: create `%s` containing at least {\"db_url\": \"...\"}", ErrNoConfigFile, filepath)
+	}
+	if err != nil {
+		return configFile{}, fmt.Errorf("Failed to read `%s`: %v\n", legacy, err)
+	}
+
+	f, err := parseFile(data)
+	if err != nil {
+		return configFile{}, fmt.Errorf("Invalid config `%s`: %v", legacy, err)
+	}
+	upgrade(&f)
+
+	err = writeFile(f)
+	if err != nil {
+		return configFile{}, err
+	}
+
+	err = os.Remove(legacy)
+	if err != nil {
+		return configFile{}, fmt.Errorf("Copied config to `%s`, but failed to remove `%s`: %v", filepath, legacy, err)
+	}
+
+	fmt.Fprintf(os.Stderr, "Moved config from %s to %s\n", legacy, filepath)
+
+	return f, nil
+}
+
+func parseFile(data []byte) (configFile, error) {
+	var f configFile
+	err := json.Unmarshal(data, &f)
+	if err != nil {
+		return configFile{}, fmt.Errorf("Failed to unmarshal json: %v", err)
+	}
+
+	if f.Version > currentVersion {
+		return configFile{}, fmt.Errorf("Written by a newer version of gator (config version %d, this build supports up to %d)", f.Version, currentVersion)
+	}
+
+	return f, nil
+}
+
+func upgrade(f *configFile) {
+	for f.Version < currentVersion {
+		upgrades[f.Version](f)
+		f.Version++
+	}
 }
 
-func write(cfg Config) error {
-	filepath, err := getConfigFilePath()
+// Replaces the config file in one step, so a crash never leaves it half
+// written. The file holds a session token, so it's private to the user.
+func writeFile(f configFile) error {
+	filepath, err := Path()
 	if err != nil {
 		return err
 	}
 
-	data, err := json.Marshal(cfg)
+	f.Version = currentVersion
+	data, err := json.Marshal(f)
 	if err != nil {
 		return fmt.Errorf("Failed to marshal config: %v\n", err)
 	}
 
-	err = os.WriteFile(filepath, data, 0666)
+	dir := path.Dir(filepath)
+	err = os.MkdirAll(dir, 0700)
+	if err != nil {
+		return fmt.Errorf("Failed to create `%s`: %v", dir, err)
+	}
+
+	// CreateTemp makes the file 0600
+	tmp, err := os.CreateTemp(dir, ".config-*.json")
+	if err != nil {
+		return fmt.Errorf("Failed to write to `%s`: %v\n", filepath, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	closeErr := tmp.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("Failed to write to `%s`: %v\n", filepath, err)
+	}
+
+	err = os.Rename(tmp.Name(), filepath)
 	if err != nil {
 		return fmt.Errorf("Failed to write to `%s`: %v\n", filepath, err)
 	}
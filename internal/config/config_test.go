@@ -0,0 +1,38 @@
+package config
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSetCreatesConfigFile(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv(profileEnvVar, "")
+
+	_, err := ReadProfile("")
+	if !errors.Is(err, ErrNoConfigFile) {
+		t.Fatalf("Reading a missing config returned %v, want ErrNoConfigFile", err)
+	}
+
+	cfg, err := ReadProfileOrEmpty("")
+	if err != nil {
+		t.Fatalf("Failed to read missing config as empty: %v", err)
+	}
+	if cfg.Profile() != DefaultProfile || cfg.DbURL != "" {
+		t.Errorf("Missing config read as %+v", cfg)
+	}
+
+	_, err = cfg.Set("db_url", "sqlite:///tmp/gator.db")
+	if err != nil {
+		t.Fatalf("Failed to set db_url without a config file: %v", err)
+	}
+
+	cfg, err = ReadProfile("")
+	if err != nil {
+		t.Fatalf("Failed to read the created config: %v", err)
+	}
+	if cfg.DbURL != "sqlite:///tmp/gator.db" {
+		t.Errorf("Created config has db_url %q", cfg.DbURL)
+	}
+}
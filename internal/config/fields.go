@@ -0,0 +1,239 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// A setting that can be read, set and overridden from the environment. Keys
+// follow the JSON layout, with nested settings joined by dots.
+type Field struct {
+	Key string
+	// Overrides the file's value when set
+	EnvVar string
+	// Hidden when listing settings
+	Secret bool
+
+	get func(cfg *Config) string
+	set func(cfg *Config, value string) error
+}
+
+var fields = []Field{
+	stringField("db_url", false, func(cfg *Config) *string { return &cfg.DbURL }),
+	stringField("current_user_name", false, func(cfg *Config) *string { return &cfg.CurrentUserName }),
+	stringField("session_token", true, func(cfg *Config) *string { return &cfg.SessionToken }),
+	stringField("log_level", false, func(cfg *Config) *string { return &cfg.LogLevel }),
+	stringField("log_format", false, func(cfg *Config) *string { return &cfg.LogFormat }),
+	intField("fetch_history_days", func(cfg *Config) *int { return &cfg.FetchHistoryDays }),
+	stringField("smtp.host", false, func(cfg *Config) *string { return &smtp(cfg).Host }),
+	intField("smtp.port", func(cfg *Config) *int { return &smtp(cfg).Port }),
+	stringField("smtp.username", false, func(cfg *Config) *string { return &smtp(cfg).Username }),
+	stringField("smtp.password", true, func(cfg *Config) *string { return &smtp(cfg).Password }),
+	stringField("smtp.from", false, func(cfg *Config) *string { return &smtp(cfg).From }),
+	intField("retention.keep_last", func(cfg *Config) *int { return &retention(cfg).KeepLast }),
+	intField("retention.keep_days", func(cfg *Config) *int { return &retention(cfg).KeepDays }),
+}
+
+// Every setting, in the order they appear in the file
+func Fields() []Field {
+	return fields
+}
+
+func findField(key string) (Field, error) {
+	for _, f := range fields {
+		if f.Key == key {
+			return f, nil
+		}
+	}
+
+	return Field{}, fmt.Errorf("Unknown setting `%s`", key)
+}
+
+func envVar(key string) string {
+	return "GATOR_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+func stringField(key string, secret bool, ptr func(cfg *Config) *string) Field {
+	return Field{
+		Key: key,
+		EnvVar: envVar(key),
+		Secret: secret,
+		get: func(cfg *Config) string {
+			c := *cfg
+			return *ptr(&c)
+		},
+		set: func(cfg *Config, value string) error {
+			*ptr(cfg) = value
+			cfg.dropEmptySections()
+			return nil
+		},
+	}
+}
+
+func intField(key string, ptr func(cfg *Config) *int) Field {
+	return Field{
+		Key: key,
+		EnvVar: envVar(key),
+		get: func(cfg *Config) string {
+			c := *cfg
+			n := *ptr(&c)
+			if n == 0 {
+				return ""
+			}
+			return strconv.Itoa(n)
+		},
+		set: func(cfg *Config, value string) error {
+			n := 0
+			if value != "" {
+				var err error
+				n, err = strconv.Atoi(value)
+				if err != nil {
+					return fmt.Errorf("`%s` must be a whole number, got `%s`", key, value)
+				}
+			}
+			*ptr(cfg) = n
+			cfg.dropEmptySections()
+			return nil
+		},
+	}
+}
+
+// The SMTP section, created if missing. Getters work on a copy, so reading
+// never adds a section.
+func smtp(cfg *Config) *SMTPConfig {
+	if cfg.SMTP == nil {
+		cfg.SMTP = &SMTPConfig{}
+	} else {
+		copied := *cfg.SMTP
+		cfg.SMTP = &copied
+	}
+
+	return cfg.SMTP
+}
+
+func retention(cfg *Config) *RetentionPolicy {
+	if cfg.Retention == nil {
+		cfg.Retention = &RetentionPolicy{}
+	} else {
+		copied := *cfg.Retention
+		cfg.Retention = &copied
+	}
+
+	return cfg.Retention
+}
+
+// Sections with every setting cleared are removed, so clearing them all is
+// the same as never having set them
+func (cfg *Config) dropEmptySections() {
+	if cfg.SMTP != nil && *cfg.SMTP == (SMTPConfig{}) {
+		cfg.SMTP = nil
+	}
+	if cfg.Retention != nil && *cfg.Retention == (RetentionPolicy{}) {
+		cfg.Retention = nil
+	}
+}
+
+func (cfg *Config) applyEnv() error {
+	for _, f := range fields {
+		value, ok := os.LookupEnv(f.EnvVar)
+		if !ok {
+			continue
+		}
+
+		err := f.set(cfg, value)
+		if err != nil {
+			return fmt.Errorf("Invalid %s: %v", f.EnvVar, err)
+		}
+	}
+
+	return nil
+}
+
+// The effective value of a setting, including environment overrides
+func (cfg Config) Get(key string) (string, error) {
+	f, err := findField(key)
+	if err != nil {
+		return "", err
+	}
+
+	return f.get(&cfg), nil
+}
+
+// Stores a setting in the profile the config was read from, returning the
+// profile's new stored settings. An empty value clears it. The result isn't
+// validated, since sections like smtp are filled in one setting at a time.
+func (cfg Config) Set(key, value string) (Config, error) {
+	f, err := findField(key)
+	if err != nil {
+		return Config{}, err
+	}
+
+	updated := Config{}
+	err = updateProfile(cfg.Profile(), func(c *Config) error {
+		err := f.set(c, value)
+		updated = *c
+		return err
+	})
+
+	return updated, err
+}
+
+// Checks every setting, reporting all problems at once
+func (cfg Config) Validate() error {
+	problems := []error{}
+
+	if cfg.DbURL == "" {
+		problems = append(problems, fmt.Errorf("db_url is required"))
+	} else {
+		u, err := url.Parse(cfg.DbURL)
+		if err != nil {
+			problems = append(problems, fmt.Errorf("db_url is not a valid URL: %v", err))
+		} else if u.Scheme == "sqlite" {
+			if u.Opaque == "" && u.Host == "" && u.Path == "" {
+				problems = append(problems, fmt.Errorf("db_url must name a database file, like sqlite:///path/to/gator.db"))
+			}
+		} else if u.Scheme != "postgres" && u.Scheme != "postgresql" {
+			problems = append(problems, fmt.Errorf("db_url must start with postgres:// or sqlite:"))
+		}
+	}
+
+	if cfg.LogLevel != "" {
+		var level slog.Level
+		err := level.UnmarshalText([]byte(cfg.LogLevel))
+		if err != nil {
+			problems = append(problems, fmt.Errorf("log_level must be debug, info, warn or error"))
+		}
+	}
+
+	format := strings.ToLower(cfg.LogFormat)
+	if format != "" && format != "text" && format != "json" {
+		problems = append(problems, fmt.Errorf("log_format must be text or json"))
+	}
+
+	if cfg.FetchHistoryDays < 0 {
+		problems = append(problems, fmt.Errorf("fetch_history_days must not be negative"))
+	}
+
+	if cfg.SMTP != nil {
+		if cfg.SMTP.Host == "" {
+			problems = append(problems, fmt.Errorf("smtp.host is required when smtp is configured"))
+		}
+		if cfg.SMTP.Port <= 0 || cfg.SMTP.Port > 65535 {
+			problems = append(problems, fmt.Errorf("smtp.port must be between 1 and 65535"))
+		}
+		if cfg.SMTP.From == "" {
+			problems = append(problems, fmt.Errorf("smtp.from is required when smtp is configured"))
+		}
+	}
+
+	if cfg.Retention != nil && (cfg.Retention.KeepLast < 0 || cfg.Retention.KeepDays < 0) {
+		problems = append(problems, fmt.Errorf("retention.keep_last and retention.keep_days must not be negative"))
+	}
+
+	return errors.Join(problems...)
+}
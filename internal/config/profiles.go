@@ -0,0 +1,118 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+)
+
+// The profile whose settings sit at the top level of the file
+const DefaultProfile = "default"
+
+const profileEnvVar = "GATOR_PROFILE"
+
+// The name of the profile the settings came from
+func (cfg Config) Profile() string {
+	if cfg.profile == "" {
+		return DefaultProfile
+	}
+
+	return cfg.profile
+}
+
+// All profile names, default first
+func Profiles() ([]string, error) {
+	f, err := readFile()
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	for name := range f.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return append([]string{DefaultProfile}, names...), nil
+}
+
+// Makes the named profile the one used when none is given
+func UseProfile(name string) error {
+	f, err := readFile()
+	if err != nil {
+		return err
+	}
+
+	_, ok := f.profile(name)
+	if !ok {
+		return fmt.Errorf("No profile named `%s`", name)
+	}
+
+	f.CurrentProfile = name
+	if name == DefaultProfile {
+		f.CurrentProfile = ""
+	}
+
+	return writeFile(f)
+}
+
+// Adds a profile pointing at another database
+func AddProfile(name, dbURL string) error {
+	f, err := readFile()
+	if err != nil {
+		return err
+	}
+
+	_, ok := f.profile(name)
+	if ok {
+		return fmt.Errorf("Profile `%s` already exists", name)
+	}
+
+	f.setProfile(name, Config{DbURL: dbURL})
+
+	return writeFile(f)
+}
+
+// Removes a profile. The default profile can't be removed.
+func RemoveProfile(name string) error {
+	if name == DefaultProfile {
+		return fmt.Errorf("Cannot remove the default profile")
+	}
+
+	f, err := readFile()
+	if err != nil {
+		return err
+	}
+
+	_, ok := f.Profiles[name]
+	if !ok {
+		return fmt.Errorf("No profile named `%s`", name)
+	}
+
+	delete(f.Profiles, name)
+	if f.CurrentProfile == name {
+		f.CurrentProfile = ""
+	}
+
+	return writeFile(f)
+}
+
+func (f configFile) profile(name string) (Config, bool) {
+	if name == DefaultProfile {
+		return f.Config, true
+	}
+
+	config, ok := f.Profiles[name]
+	return config, ok
+}
+
+func (f *configFile) setProfile(name string, cfg Config) {
+	if name == DefaultProfile {
+		f.Config = cfg
+		return
+	}
+
+	if f.Profiles == nil {
+		f.Profiles = map[string]Config{}
+	}
+	f.Profiles[name] = cfg
+}
@@ -0,0 +1,119 @@
+package config
+
+import (
+	"os"
+	"path"
+	"slices"
+	"testing"
+)
+
+func TestProfiles(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv(profileEnvVar, "")
+
+	filepath, err := Path()
+	if err != nil {
+		t.Fatalf("Failed to get config path: %v", err)
+	}
+	err = os.MkdirAll(path.Dir(filepath), 0700)
+	if err != nil {
+		t.Fatalf("Failed to create config dir: %v", err)
+	}
+	err = os.WriteFile(filepath, []byte(`{"db_url": "postgres://localhost/personal", "current_user_name": "alice"}`), 0600)
+	if err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	dbURL := func(name string) string {
+		t.Helper()
+
+		cfg, err := ReadProfile(name)
+		if err != nil {
+			t.Fatalf("Failed to read profile %q: %v", name, err)
+		}
+		return cfg.DbURL
+	}
+
+	err = AddProfile("team", "postgres://db.example.com/team")
+	if err != nil {
+		t.Fatalf("Failed to add profile: %v", err)
+	}
+	if AddProfile("team", "postgres://elsewhere/team") == nil {
+		t.Error("Added a profile twice")
+	}
+	if AddProfile(DefaultProfile, "postgres://elsewhere/default") == nil {
+		t.Error("Added a profile over the default one")
+	}
+
+	names, err := Profiles()
+	if err != nil {
+		t.Fatalf("Failed to list profiles: %v", err)
+	}
+	if want := []string{DefaultProfile, "team"}; !slices.Equal(names, want) {
+		t.Errorf("Profiles %q, want %q", names, want)
+	}
+
+	if got := dbURL(""); got != "postgres://localhost/personal" {
+		t.Errorf("Default profile has db_url %q", got)
+	}
+
+	err = UseProfile("team")
+	if err != nil {
+		t.Fatalf("Failed to switch profile: %v", err)
+	}
+	if got := dbURL(""); got != "postgres://db.example.com/team" {
+		t.Errorf("Switched profile has db_url %q", got)
+	}
+	if UseProfile("staging") == nil {
+		t.Error("Switched to a missing profile")
+	}
+
+	// GATOR_PROFILE overrides the switched profile, and a name overrides both
+	t.Setenv(profileEnvVar, DefaultProfile)
+	if got := dbURL(""); got != "postgres://localhost/personal" {
+		t.Errorf("GATOR_PROFILE=default read db_url %q", got)
+	}
+	if got := dbURL("team"); got != "postgres://db.example.com/team" {
+		t.Errorf("Named profile read db_url %q", got)
+	}
+	t.Setenv(profileEnvVar, "")
+
+	// Sessions are per profile
+	cfg, err := ReadProfile("team")
+	if err != nil {
+		t.Fatalf("Failed to read profile: %v", err)
+	}
+	if cfg.Profile() != "team" || cfg.CurrentUserName != "" {
+		t.Errorf("Profile `team` read as %q with user %q", cfg.Profile(), cfg.CurrentUserName)
+	}
+	err = cfg.SetSession("bob", "token")
+	if err != nil {
+		t.Fatalf("Failed to set session: %v", err)
+	}
+	personal, err := ReadProfile(DefaultProfile)
+	if err != nil {
+		t.Fatalf("Failed to read profile: %v", err)
+	}
+	if personal.CurrentUserName != "alice" || personal.SessionToken != "" {
+		t.Errorf("Default profile has user %q and token %q after signing in to another", personal.CurrentUserName, personal.SessionToken)
+	}
+
+	if RemoveProfile(DefaultProfile) == nil {
+		t.Error("Removed the default profile")
+	}
+	if RemoveProfile("staging") == nil {
+		t.Error("Removed a missing profile")
+	}
+	err = RemoveProfile("team")
+	if err != nil {
+		t.Fatalf("Failed to remove profile: %v", err)
+	}
+	if got := dbURL(""); got != "postgres://localhost/personal" {
+		t.Errorf("After removing the current profile, read db_url %q, want the default", got)
+	}
+	_, err = ReadProfile("team")
+	if err == nil {
+		t.Error("Read a removed profile")
+	}
+}
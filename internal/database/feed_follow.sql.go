@@ -16,9 +16,9 @@ const createFeedFollow = `-- name: CreateFeedFollow :one
 WITH inserted_feed_follows AS (
     INSERT INTO feed_follows (id, created_at, updated_at, user_id, feed_id)
     VALUES ($1, NOW(), NOW(), $2, $3)
-    RETURNING id, created_at, updated_at, user_id, feed_id
+    RETURNING id, created_at, updated_at, user_id, feed_id, folder_id, position, title, notes, priority
 )
-SELECT inserted_feed_follows.id, inserted_feed_follows.created_at, inserted_feed_follows.updated_at, inserted_feed_follows.user_id, inserted_feed_follows.feed_id, feeds.name AS feed_name, users.name AS user_name FROM inserted_feed_follows
+SELECT inserted_feed_follows.id, inserted_feed_follows.created_at, inserted_feed_follows.updated_at, inserted_feed_follows.user_id, inserted_feed_follows.feed_id, inserted_feed_follows.folder_id, inserted_feed_follows.position, inserted_feed_follows.title, inserted_feed_follows.notes, inserted_feed_follows.priority, feeds.name AS feed_name, users.name AS user_name FROM inserted_feed_follows
 INNER JOIN feeds ON inserted_feed_follows.feed_id = feeds.id
 INNER JOIN users ON inserted_feed_follows.user_id = users.id
 `
@@ -35,6 +35,11 @@ type CreateFeedFollowRow struct {
 	UpdatedAt time.Time
 	UserID    uuid.UUID
 	FeedID    uuid.UUID
+	FolderID  uuid.NullUUID
+	Position  int32
+	Title     string
+	Notes     string
+	Priority  int32
 	FeedName  string
 	UserName  string
 }
@@ -48,6 +53,11 @@ func (q *Queries) CreateFeedFollow(ctx context.Context, arg CreateFeedFollowPara
 		&i.UpdatedAt,
 		&i.UserID,
 		&i.FeedID,
+		&i.FolderID,
+		&i.Position,
+		&i.Title,
+		&i.Notes,
+		&i.Priority,
 		&i.FeedName,
 		&i.UserName,
 	)
@@ -57,7 +67,7 @@ func (q *Queries) CreateFeedFollow(ctx context.Context, arg CreateFeedFollowPara
 const deleteFeedFollow = `-- name: DeleteFeedFollow :one
 DELETE FROM feed_follows
 WHERE feed_id = $1 AND user_id = $2
-RETURNING id, created_at, updated_at, user_id, feed_id
+RETURNING id, created_at, updated_at, user_id, feed_id, folder_id, position, title, notes, priority
 `
 
 type DeleteFeedFollowParams struct {
@@ -74,16 +84,50 @@ func (q *Queries) DeleteFeedFollow(ctx context.Context, arg DeleteFeedFollowPara
 		&i.UpdatedAt,
 		&i.UserID,
 		&i.FeedID,
+		&i.FolderID,
+		&i.Position,
+		&i.Title,
+		&i.Notes,
+		&i.Priority,
+	)
+	return i, err
+}
+
+const getFeedFollow = `-- name: GetFeedFollow :one
+SELECT id, created_at, updated_at, user_id, feed_id, folder_id, position, title, notes, priority FROM feed_follows
+WHERE user_id = $1 AND feed_id = $2
+`
+
+type GetFeedFollowParams struct {
+	UserID uuid.UUID
+	FeedID uuid.UUID
+}
+
+func (q *Queries) GetFeedFollow(ctx context.Context, arg GetFeedFollowParams) (FeedFollow, error) {
+	row := q.db.QueryRowContext(ctx, getFeedFollow, arg.UserID, arg.FeedID)
+	var i FeedFollow
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.FeedID,
+		&i.FolderID,
+		&i.Position,
+		&i.Title,
+		&i.Notes,
+		&i.Priority,
 	)
 	return i, err
 }
 
 const getFeedFollowsForUser = `-- name: GetFeedFollowsForUser :many
-SELECT feed_follows.id, feed_follows.created_at, feed_follows.updated_at, feed_follows.user_id, feed_follows.feed_id, users.name AS user_name, feeds.name AS feed_name 
+SELECT feed_follows.id, feed_follows.created_at, feed_follows.updated_at, feed_follows.user_id, feed_follows.feed_id, feed_follows.folder_id, feed_follows.position, feed_follows.title, feed_follows.notes, feed_follows.priority, users.name AS user_name, COALESCE(NULLIF(feed_follows.title, ''), feeds.name)::text AS feed_name, feeds.url AS feed_url
 FROM feed_follows
 INNER JOIN users ON feed_follows.user_id = users.id
 INNER JOIN feeds ON feed_follows.feed_id = feeds.id
 WHERE users.name = $1
+ORDER BY feed_follows.position, feeds.name
 `
 
 type GetFeedFollowsForUserRow struct {
@@ -92,8 +136,14 @@ type GetFeedFollowsForUserRow struct {
 	UpdatedAt time.Time
 	UserID    uuid.UUID
 	FeedID    uuid.UUID
+	FolderID  uuid.NullUUID
+	Position  int32
+	Title     string
+	Notes     string
+	Priority  int32
 	UserName  string
 	FeedName  string
+	FeedUrl   string
 }
 
 func (q *Queries) GetFeedFollowsForUser(ctx context.Context, name string) ([]GetFeedFollowsForUserRow, error) {
@@ -111,8 +161,14 @@ func (q *Queries) GetFeedFollowsForUser(ctx context.Context, name string) ([]Get
 			&i.UpdatedAt,
 			&i.UserID,
 			&i.FeedID,
+			&i.FolderID,
+			&i.Position,
+			&i.Title,
+			&i.Notes,
+			&i.Priority,
 			&i.UserName,
 			&i.FeedName,
+			&i.FeedUrl,
 		); err != nil {
 			return nil, err
 		}
@@ -126,3 +182,114 @@ func (q *Queries) GetFeedFollowsForUser(ctx context.Context, name string) ([]Get
 	}
 	return items, nil
 }
+
+const moveFeedFollow = `-- name: MoveFeedFollow :execrows
+UPDATE feed_follows
+SET folder_id = $3, position = $4, updated_at = NOW()
+WHERE user_id = $1 AND feed_id = $2
+`
+
+type MoveFeedFollowParams struct {
+	UserID   uuid.UUID
+	FeedID   uuid.UUID
+	FolderID uuid.NullUUID
+	Position int32
+}
+
+func (q *Queries) MoveFeedFollow(ctx context.Context, arg MoveFeedFollowParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, moveFeedFollow,
+		arg.UserID,
+		arg.FeedID,
+		arg.FolderID,
+		arg.Position,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const nextFeedFollowPosition = `-- name: NextFeedFollowPosition :one
+SELECT COALESCE(MAX(position) + 1, 0)::integer AS position FROM feed_follows
+WHERE user_id = $1 AND folder_id IS NOT DISTINCT FROM $2
+`
+
+type NextFeedFollowPositionParams struct {
+	UserID   uuid.UUID
+	FolderID uuid.NullUUID
+}
+
+func (q *Queries) NextFeedFollowPosition(ctx context.Context, arg NextFeedFollowPositionParams) (int32, error) {
+	row := q.db.QueryRowContext(ctx, nextFeedFollowPosition, arg.UserID, arg.FolderID)
+	var position int32
+	err := row.Scan(&position)
+	return position, err
+}
+
+const shiftFeedFollowPositions = `-- name: ShiftFeedFollowPositions :exec
+UPDATE feed_follows
+SET position = position + 1
+WHERE user_id = $1 AND folder_id IS NOT DISTINCT FROM $2 AND position >= $3
+`
+
+type ShiftFeedFollowPositionsParams struct {
+	UserID   uuid.UUID
+	FolderID uuid.NullUUID
+	Position int32
+}
+
+func (q *Queries) ShiftFeedFollowPositions(ctx context.Context, arg ShiftFeedFollowPositionsParams) error {
+	_, err := q.db.ExecContext(ctx, shiftFeedFollowPositions, arg.UserID, arg.FolderID, arg.Position)
+	return err
+}
+
+const updateFeedFollowDetails = `-- name: UpdateFeedFollowDetails :exec
+UPDATE feed_follows
+SET title = $3, notes = $4, priority = $5, updated_at = NOW()
+WHERE user_id = $1 AND feed_id = $2
+`
+
+type UpdateFeedFollowDetailsParams struct {
+	UserID   uuid.UUID
+	FeedID   uuid.UUID
+	Title    string
+	Notes    string
+	Priority int32
+}
+
+func (q *Queries) UpdateFeedFollowDetails(ctx context.Context, arg UpdateFeedFollowDetailsParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedFollowDetails,
+		arg.UserID,
+		arg.FeedID,
+		arg.Title,
+		arg.Notes,
+		arg.Priority,
+	)
+	return err
+}
+
+const upsertFeedFollow = `-- name: UpsertFeedFollow :exec
+INSERT INTO feed_follows (id, created_at, updated_at, user_id, feed_id, folder_id, position)
+VALUES ($1, NOW(), NOW(), $2, $3, $4, $5)
+ON CONFLICT (user_id, feed_id) DO UPDATE
+SET folder_id = EXCLUDED.folder_id, position = EXCLUDED.position, updated_at = NOW()
+`
+
+type UpsertFeedFollowParams struct {
+	ID       uuid.UUID
+	UserID   uuid.UUID
+	FeedID   uuid.UUID
+	FolderID uuid.NullUUID
+	Position int32
+}
+
+func (q *Queries) UpsertFeedFollow(ctx context.Context, arg UpsertFeedFollowParams) error {
+	_, err := q.db.ExecContext(ctx, upsertFeedFollow,
+		arg.ID,
+		arg.UserID,
+		arg.FeedID,
+		arg.FolderID,
+		arg.Position,
+	)
+	return err
+}
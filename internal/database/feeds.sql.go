@@ -13,10 +13,34 @@ import (
 	"github.com/google/uuid"
 )
 
+const countFeedPosts = `-- name: CountFeedPosts :one
+SELECT COUNT(*) FROM posts WHERE feed_id = $1
+`
+
+func (q *Queries) CountFeedPosts(ctx context.Context, feedID uuid.UUID) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countFeedPosts, feedID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countFeedsDue = `-- name: CountFeedsDue :one
+SELECT COUNT(*) FROM feeds
+WHERE paused_at IS NULL
+AND (last_fetched_at IS NULL OR last_fetched_at < $1)
+`
+
+func (q *Queries) CountFeedsDue(ctx context.Context, fetchedBefore sql.NullTime) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countFeedsDue, fetchedBefore)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createFeed = `-- name: CreateFeed :one
 INSERT INTO feeds (id, created_at, updated_at, name, url, user_id)
 VALUES ($1, NOW(), NOW(), $2, $3, $4)
-RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, hub_url, self_url, paused_at
 `
 
 type CreateFeedParams struct {
@@ -42,12 +66,24 @@ func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, e
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.HubUrl,
+		&i.SelfUrl,
+		&i.PausedAt,
 	)
 	return i, err
 }
 
+const deleteFeed = `-- name: DeleteFeed :exec
+DELETE FROM feeds WHERE id = $1
+`
+
+func (q *Queries) DeleteFeed(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteFeed, id)
+	return err
+}
+
 const getFeed = `-- name: GetFeed :one
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at FROM feeds WHERE url = $1
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, hub_url, self_url, paused_at FROM feeds WHERE url = $1
 `
 
 func (q *Queries) GetFeed(ctx context.Context, url string) (Feed, error) {
@@ -61,12 +97,15 @@ func (q *Queries) GetFeed(ctx context.Context, url string) (Feed, error) {
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.HubUrl,
+		&i.SelfUrl,
+		&i.PausedAt,
 	)
 	return i, err
 }
 
 const getFeeds = `-- name: GetFeeds :many
-SELECT feeds.id, feeds.created_at, feeds.updated_at, feeds.name, feeds.url, feeds.user_id, feeds.last_fetched_at, users.name AS user_name FROM feeds
+SELECT feeds.id, feeds.created_at, feeds.updated_at, feeds.name, feeds.url, feeds.user_id, feeds.last_fetched_at, feeds.hub_url, feeds.self_url, feeds.paused_at, users.name AS user_name FROM feeds
 INNER JOIN users ON feeds.user_id = users.id
 `
 
@@ -78,6 +117,9 @@ type GetFeedsRow struct {
 	Url           string
 	UserID        uuid.UUID
 	LastFetchedAt sql.NullTime
+	HubUrl        sql.NullString
+	SelfUrl       sql.NullString
+	PausedAt      sql.NullTime
 	UserName      string
 }
 
@@ -98,6 +140,9 @@ func (q *Queries) GetFeeds(ctx context.Context) ([]GetFeedsRow, error) {
 			&i.Url,
 			&i.UserID,
 			&i.LastFetchedAt,
+			&i.HubUrl,
+			&i.SelfUrl,
+			&i.PausedAt,
 			&i.UserName,
 		); err != nil {
 			return nil, err
@@ -114,7 +159,8 @@ func (q *Queries) GetFeeds(ctx context.Context) ([]GetFeedsRow, error) {
 }
 
 const getNextFeedToFetch = `-- name: GetNextFeedToFetch :one
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at FROM feeds
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, hub_url, self_url, paused_at FROM feeds
+WHERE paused_at IS NULL
 ORDER BY last_fetched_at ASC NULLS FIRST
 LIMIT 1
 `
@@ -130,6 +176,9 @@ func (q *Queries) GetNextFeedToFetch(ctx context.Context) (Feed, error) {
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.HubUrl,
+		&i.SelfUrl,
+		&i.PausedAt,
 	)
 	return i, err
 }
@@ -144,3 +193,86 @@ func (q *Queries) MarkFeedFetched(ctx context.Context, id uuid.UUID) error {
 	_, err := q.db.ExecContext(ctx, markFeedFetched, id)
 	return err
 }
+
+const renameFeed = `-- name: RenameFeed :exec
+UPDATE feeds
+SET name = $2, updated_at = NOW()
+WHERE id = $1
+`
+
+type RenameFeedParams struct {
+	ID   uuid.UUID
+	Name string
+}
+
+func (q *Queries) RenameFeed(ctx context.Context, arg RenameFeedParams) error {
+	_, err := q.db.ExecContext(ctx, renameFeed, arg.ID, arg.Name)
+	return err
+}
+
+const setFeedOwner = `-- name: SetFeedOwner :exec
+UPDATE feeds
+SET user_id = $2, updated_at = NOW()
+WHERE id = $1
+`
+
+type SetFeedOwnerParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) SetFeedOwner(ctx context.Context, arg SetFeedOwnerParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedOwner, arg.ID, arg.UserID)
+	return err
+}
+
+const setFeedPaused = `-- name: SetFeedPaused :exec
+UPDATE feeds
+SET paused_at = $2, updated_at = NOW()
+WHERE id = $1
+`
+
+type SetFeedPausedParams struct {
+	ID       uuid.UUID
+	PausedAt sql.NullTime
+}
+
+func (q *Queries) SetFeedPaused(ctx context.Context, arg SetFeedPausedParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedPaused, arg.ID, arg.PausedAt)
+	return err
+}
+
+const transferFeeds = `-- name: TransferFeeds :execrows
+UPDATE feeds
+SET user_id = $1, updated_at = NOW()
+WHERE user_id = $2
+`
+
+type TransferFeedsParams struct {
+	ToUserID   uuid.UUID
+	FromUserID uuid.UUID
+}
+
+func (q *Queries) TransferFeeds(ctx context.Context, arg TransferFeedsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, transferFeeds, arg.ToUserID, arg.FromUserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const updateFeedURL = `-- name: UpdateFeedURL :exec
+UPDATE feeds
+SET url = $2, hub_url = NULL, self_url = NULL, last_fetched_at = NULL, updated_at = NOW()
+WHERE id = $1
+`
+
+type UpdateFeedURLParams struct {
+	ID  uuid.UUID
+	Url string
+}
+
+func (q *Queries) UpdateFeedURL(ctx context.Context, arg UpdateFeedURLParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedURL, arg.ID, arg.Url)
+	return err
+}
@@ -0,0 +1,2185 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Keeps everything in memory, for tests. It behaves like the SQL stores,
+// including unique constraints, cascading deletes, filter rules and retention
+// policies, with full-text search approximated by matching words anywhere.
+type MemoryStore struct {
+	mu sync.Mutex
+	memoryTables
+	locks map[int64]bool
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+type memoryTables struct {
+	users             []User
+	sessions          []Session
+	feeds             []Feed
+	follows           []FeedFollow
+	folders           []Folder
+	posts             []Post
+	reads             map[postRead]time.Time
+	savedPosts        []SavedPost
+	filterRules       []FilterRule
+	alertRules        []AlertRule
+	postAlerts        []PostAlert
+	fetches           []FeedFetch
+	retentionPolicies []FeedRetentionPolicy
+	websubSubs        []WebsubSubscription
+	auditEvents       []AuditEvent
+}
+
+// A copy of the tables to roll back to. Rows are values, and the slices in
+// them are replaced rather than changed in place, so copying the tables is
+// enough.
+func (t memoryTables) clone() memoryTables {
+	return memoryTables{
+		users:             slices.Clone(t.users),
+		sessions:          slices.Clone(t.sessions),
+		feeds:             slices.Clone(t.feeds),
+		follows:           slices.Clone(t.follows),
+		folders:           slices.Clone(t.folders),
+		posts:             slices.Clone(t.posts),
+		reads:             maps.Clone(t.reads),
+		savedPosts:        slices.Clone(t.savedPosts),
+		filterRules:       slices.Clone(t.filterRules),
+		alertRules:        slices.Clone(t.alertRules),
+		postAlerts:        slices.Clone(t.postAlerts),
+		fetches:           slices.Clone(t.fetches),
+		retentionPolicies: slices.Clone(t.retentionPolicies),
+		websubSubs:        slices.Clone(t.websubSubs),
+		auditEvents:       slices.Clone(t.auditEvents),
+	}
+}
+
+type postRead struct {
+	userID uuid.UUID
+	postID uuid.UUID
+}
+
+func NewMemory() *MemoryStore {
+	return &MemoryStore{
+		memoryTables: memoryTables{reads: map[postRead]time.Time{}},
+		locks:        map[int64]bool{},
+	}
+}
+
+// Runs fn against the store, restoring everything as it was if fn fails.
+// Unlike a SQL transaction, other callers see fn's changes as it makes them.
+func (m *MemoryStore) InTx(ctx context.Context, fn func(q Store) error) error {
+	m.mu.Lock()
+	saved := m.memoryTables.clone()
+	m.mu.Unlock()
+
+	err := fn(m)
+	if err != nil {
+		m.mu.Lock()
+		m.memoryTables = saved
+		m.mu.Unlock()
+	}
+
+	return err
+}
+
+// There are no connections to hold, so this is the store itself
+func (m *MemoryStore) Conn(ctx context.Context) (Store, func(), error) {
+	return m, func() {}, nil
+}
+
+func memoryNow() time.Time {
+	return time.Now().UTC()
+}
+
+func (m *MemoryStore) user(id uuid.UUID) (*User, bool) {
+	i := slices.IndexFunc(m.users, func(u User) bool { return u.ID == id })
+	if i < 0 {
+		return nil, false
+	}
+	return &m.users[i], true
+}
+
+func (m *MemoryStore) feed(id uuid.UUID) (*Feed, bool) {
+	i := slices.IndexFunc(m.feeds, func(f Feed) bool { return f.ID == id })
+	if i < 0 {
+		return nil, false
+	}
+	return &m.feeds[i], true
+}
+
+func (m *MemoryStore) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if slices.ContainsFunc(m.users, func(u User) bool { return u.Name == arg.Name }) {
+		return User{}, fmt.Errorf("%w: users.name", ErrUniqueViolation)
+	}
+
+	role := "member"
+	if len(m.users) == 0 {
+		role = "admin"
+	}
+
+	now := memoryNow()
+	user := User{
+		ID:           arg.ID,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+		Name:         arg.Name,
+		PasswordHash: arg.PasswordHash,
+		Role:         role,
+	}
+	m.users = append(m.users, user)
+
+	return user, nil
+}
+
+func (m *MemoryStore) GetUser(ctx context.Context, name string) (User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, u := range m.users {
+		if u.Name == name {
+			return u, nil
+		}
+	}
+
+	return User{}, sql.ErrNoRows
+}
+
+func (m *MemoryStore) GetUsers(ctx context.Context) ([]User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return slices.Clone(m.users), nil
+}
+
+func (m *MemoryStore) CountAdmins(ctx context.Context) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var count int64
+	for _, u := range m.users {
+		if u.Role == "admin" {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+func (m *MemoryStore) SetUserPassword(ctx context.Context, arg SetUserPasswordParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if u, ok := m.user(arg.ID); ok {
+		u.PasswordHash = arg.PasswordHash
+		u.UpdatedAt = memoryNow()
+	}
+
+	return nil
+}
+
+func (m *MemoryStore) SetUserRole(ctx context.Context, arg SetUserRoleParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if u, ok := m.user(arg.ID); ok {
+		u.Role = arg.Role
+		u.UpdatedAt = memoryNow()
+	}
+
+	return nil
+}
+
+func (m *MemoryStore) RenameUser(ctx context.Context, arg RenameUserParams) (User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	u, ok := m.user(arg.ID)
+	if !ok {
+		return User{}, sql.ErrNoRows
+	}
+	if slices.ContainsFunc(m.users, func(other User) bool { return other.Name == arg.Name && other.ID != arg.ID }) {
+		return User{}, fmt.Errorf("%w: users.name", ErrUniqueViolation)
+	}
+
+	u.Name = arg.Name
+	u.UpdatedAt = memoryNow()
+
+	return *u, nil
+}
+
+func (m *MemoryStore) DeleteUser(ctx context.Context, id uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.deleteUsers(func(u User) bool { return u.ID == id })
+
+	return nil
+}
+
+func (m *MemoryStore) ResetUsers(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.deleteUsers(func(User) bool { return true })
+
+	return nil
+}
+
+// Deletes users along with their feeds, follows, folders, reads and rules, as
+// the foreign keys cascade in the SQL schemas
+func (m *MemoryStore) deleteUsers(match func(User) bool) {
+	deleted := map[uuid.UUID]bool{}
+	m.users = slices.DeleteFunc(m.users, func(u User) bool {
+		deleted[u.ID] = match(u)
+		return deleted[u.ID]
+	})
+
+	m.sessions = slices.DeleteFunc(m.sessions, func(s Session) bool { return deleted[s.UserID] })
+	m.follows = slices.DeleteFunc(m.follows, func(f FeedFollow) bool { return deleted[f.UserID] })
+	m.folders = slices.DeleteFunc(m.folders, func(f Folder) bool { return deleted[f.UserID] })
+	m.savedPosts = slices.DeleteFunc(m.savedPosts, func(p SavedPost) bool { return deleted[p.UserID] })
+	m.filterRules = slices.DeleteFunc(m.filterRules, func(r FilterRule) bool { return deleted[r.UserID] })
+	m.deleteAlertRules(func(r AlertRule) bool { return deleted[r.UserID] })
+	for r := range m.reads {
+		if deleted[r.userID] {
+			delete(m.reads, r)
+		}
+	}
+	m.deleteFeeds(func(f Feed) bool { return deleted[f.UserID] })
+}
+
+func (m *MemoryStore) CreateSession(ctx context.Context, arg CreateSessionParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.user(arg.UserID); !ok {
+		return fmt.Errorf("User `%s` does not exist", arg.UserID)
+	}
+	if slices.ContainsFunc(m.sessions, func(s Session) bool { return s.TokenHash == arg.TokenHash }) {
+		return fmt.Errorf("%w: sessions.token_hash", ErrUniqueViolation)
+	}
+
+	now := memoryNow()
+	m.sessions = append(m.sessions, Session{
+		ID:         arg.ID,
+		CreatedAt:  now,
+		LastUsedAt: now,
+		ExpiresAt:  arg.ExpiresAt,
+		UserID:     arg.UserID,
+		TokenHash:  arg.TokenHash,
+	})
+
+	return nil
+}
+
+// The user of an unexpired session, marking the session used
+func (m *MemoryStore) GetUserBySession(ctx context.Context, tokenHash string) (User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := memoryNow()
+	for i, s := range m.sessions {
+		if s.TokenHash != tokenHash || !s.ExpiresAt.After(now) {
+			continue
+		}
+		u, ok := m.user(s.UserID)
+		if !ok {
+			break
+		}
+		m.sessions[i].LastUsedAt = now
+		return *u, nil
+	}
+
+	return User{}, sql.ErrNoRows
+}
+
+func (m *MemoryStore) DeleteSession(ctx context.Context, tokenHash string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sessions = slices.DeleteFunc(m.sessions, func(s Session) bool { return s.TokenHash == tokenHash })
+
+	return nil
+}
+
+func (m *MemoryStore) DeleteOtherSessions(ctx context.Context, arg DeleteOtherSessionsParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sessions = slices.DeleteFunc(m.sessions, func(s Session) bool {
+		return s.UserID == arg.UserID && s.TokenHash != arg.TokenHash
+	})
+
+	return nil
+}
+
+func (m *MemoryStore) DeleteExpiredSessions(ctx context.Context) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := memoryNow()
+	before := len(m.sessions)
+	m.sessions = slices.DeleteFunc(m.sessions, func(s Session) bool { return !s.ExpiresAt.After(now) })
+
+	return int64(before - len(m.sessions)), nil
+}
+
+func (m *MemoryStore) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.user(arg.UserID); !ok {
+		return Feed{}, fmt.Errorf("Feed owner `%s` does not exist", arg.UserID)
+	}
+	if slices.ContainsFunc(m.feeds, func(f Feed) bool { return f.Url == arg.Url }) {
+		return Feed{}, fmt.Errorf("%w: feeds.url", ErrUniqueViolation)
+	}
+
+	now := memoryNow()
+	feed := Feed{
+		ID:        arg.ID,
+		CreatedAt: now,
+		UpdatedAt: now,
+		Name:      arg.Name,
+		Url:       arg.Url,
+		UserID:    arg.UserID,
+	}
+	m.feeds = append(m.feeds, feed)
+
+	return feed, nil
+}
+
+func (m *MemoryStore) GetFeed(ctx context.Context, url string) (Feed, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, f := range m.feeds {
+		if f.Url == url {
+			return f, nil
+		}
+	}
+
+	return Feed{}, sql.ErrNoRows
+}
+
+func (m *MemoryStore) GetFeedByID(ctx context.Context, id uuid.UUID) (Feed, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	f, ok := m.feed(id)
+	if !ok {
+		return Feed{}, sql.ErrNoRows
+	}
+
+	return *f, nil
+}
+
+func (m *MemoryStore) GetFeeds(ctx context.Context) ([]GetFeedsRow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rows := []GetFeedsRow{}
+	for _, f := range m.feeds {
+		u, ok := m.user(f.UserID)
+		if !ok {
+			continue
+		}
+		rows = append(rows, GetFeedsRow{
+			ID:            f.ID,
+			CreatedAt:     f.CreatedAt,
+			UpdatedAt:     f.UpdatedAt,
+			Name:          f.Name,
+			Url:           f.Url,
+			UserID:        f.UserID,
+			LastFetchedAt: f.LastFetchedAt,
+			HubUrl:        f.HubUrl,
+			SelfUrl:       f.SelfUrl,
+			PausedAt:      f.PausedAt,
+			UserName:      u.Name,
+		})
+	}
+
+	return rows, nil
+}
+
+// The unpaused feed fetched longest ago, never-fetched feeds first
+func (m *MemoryStore) GetNextFeedToFetch(ctx context.Context) (Feed, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var next *Feed
+	for i := range m.feeds {
+		f := &m.feeds[i]
+		if f.PausedAt.Valid {
+			continue
+		}
+		if next == nil || !f.LastFetchedAt.Valid && next.LastFetchedAt.Valid {
+			next = f
+			continue
+		}
+		if f.LastFetchedAt.Valid && next.LastFetchedAt.Valid && f.LastFetchedAt.Time.Before(next.LastFetchedAt.Time) {
+			next = f
+		}
+	}
+
+	if next == nil {
+		return Feed{}, sql.ErrNoRows
+	}
+
+	return *next, nil
+}
+
+func (m *MemoryStore) MarkFeedFetched(ctx context.Context, id uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if f, ok := m.feed(id); ok {
+		now := memoryNow()
+		f.LastFetchedAt = sql.NullTime{Time: now, Valid: true}
+		f.UpdatedAt = now
+	}
+
+	return nil
+}
+
+func (m *MemoryStore) CountFeedsDue(ctx context.Context, fetchedBefore sql.NullTime) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var count int64
+	for _, f := range m.feeds {
+		if f.PausedAt.Valid {
+			continue
+		}
+		if !f.LastFetchedAt.Valid || fetchedBefore.Valid && f.LastFetchedAt.Time.Before(fetchedBefore.Time) {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+func (m *MemoryStore) CountFeedPosts(ctx context.Context, feedID uuid.UUID) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var count int64
+	for _, p := range m.posts {
+		if p.FeedID == feedID {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+// Applies change to the feed with the given ID, if there is one
+func (m *MemoryStore) updateFeed(id uuid.UUID, change func(f *Feed)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if f, ok := m.feed(id); ok {
+		change(f)
+		f.UpdatedAt = memoryNow()
+	}
+}
+
+func (m *MemoryStore) RenameFeed(ctx context.Context, arg RenameFeedParams) error {
+	m.updateFeed(arg.ID, func(f *Feed) {
+		f.Name = arg.Name
+	})
+
+	return nil
+}
+
+func (m *MemoryStore) UpdateFeedURL(ctx context.Context, arg UpdateFeedURLParams) error {
+	m.mu.Lock()
+	taken := slices.ContainsFunc(m.feeds, func(f Feed) bool { return f.Url == arg.Url && f.ID != arg.ID })
+	m.mu.Unlock()
+	if taken {
+		return fmt.Errorf("%w: feeds.url", ErrUniqueViolation)
+	}
+
+	m.updateFeed(arg.ID, func(f *Feed) {
+		f.Url = arg.Url
+		f.HubUrl = sql.NullString{}
+		f.SelfUrl = sql.NullString{}
+		f.LastFetchedAt = sql.NullTime{}
+	})
+
+	return nil
+}
+
+func (m *MemoryStore) SetFeedPaused(ctx context.Context, arg SetFeedPausedParams) error {
+	m.updateFeed(arg.ID, func(f *Feed) {
+		f.PausedAt = arg.PausedAt
+	})
+
+	return nil
+}
+
+func (m *MemoryStore) SetFeedOwner(ctx context.Context, arg SetFeedOwnerParams) error {
+	m.updateFeed(arg.ID, func(f *Feed) {
+		f.UserID = arg.UserID
+	})
+
+	return nil
+}
+
+func (m *MemoryStore) SetFeedWebSubLinks(ctx context.Context, arg SetFeedWebSubLinksParams) error {
+	m.updateFeed(arg.ID, func(f *Feed) {
+		f.HubUrl = arg.HubUrl
+		f.SelfUrl = arg.SelfUrl
+	})
+
+	return nil
+}
+
+func (m *MemoryStore) TransferFeeds(ctx context.Context, arg TransferFeedsParams) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var count int64
+	for i := range m.feeds {
+		if m.feeds[i].UserID == arg.FromUserID {
+			m.feeds[i].UserID = arg.ToUserID
+			m.feeds[i].UpdatedAt = memoryNow()
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+func (m *MemoryStore) DeleteFeed(ctx context.Context, id uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.deleteFeeds(func(f Feed) bool { return f.ID == id })
+
+	return nil
+}
+
+// Deletes feeds along with their follows, posts, filter rules, fetch
+// history, retention policies and WebSub subscriptions
+func (m *MemoryStore) deleteFeeds(match func(Feed) bool) {
+	deleted := map[uuid.UUID]bool{}
+	m.feeds = slices.DeleteFunc(m.feeds, func(f Feed) bool {
+		deleted[f.ID] = match(f)
+		return deleted[f.ID]
+	})
+
+	m.follows = slices.DeleteFunc(m.follows, func(f FeedFollow) bool { return deleted[f.FeedID] })
+	m.filterRules = slices.DeleteFunc(m.filterRules, func(r FilterRule) bool {
+		return r.FeedID.Valid && deleted[r.FeedID.UUID]
+	})
+	m.fetches = slices.DeleteFunc(m.fetches, func(f FeedFetch) bool { return deleted[f.FeedID] })
+	m.retentionPolicies = slices.DeleteFunc(m.retentionPolicies, func(p FeedRetentionPolicy) bool { return deleted[p.FeedID] })
+	m.websubSubs = slices.DeleteFunc(m.websubSubs, func(s WebsubSubscription) bool { return deleted[s.FeedID] })
+	m.deletePosts(func(p Post) bool { return deleted[p.FeedID] })
+}
+
+func (m *MemoryStore) CreateFeedFollow(ctx context.Context, arg CreateFeedFollowParams) (CreateFeedFollowRow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	u, ok := m.user(arg.UserID)
+	if !ok {
+		return CreateFeedFollowRow{}, fmt.Errorf("User `%s` does not exist", arg.UserID)
+	}
+	f, ok := m.feed(arg.FeedID)
+	if !ok {
+		return CreateFeedFollowRow{}, fmt.Errorf("Feed `%s` does not exist", arg.FeedID)
+	}
+	if m.followIndex(arg.UserID, arg.FeedID) >= 0 {
+		return CreateFeedFollowRow{}, fmt.Errorf("%w: feed_follows.user_id, feed_follows.feed_id", ErrUniqueViolation)
+	}
+
+	now := memoryNow()
+	follow := FeedFollow{
+		ID:        arg.ID,
+		CreatedAt: now,
+		UpdatedAt: now,
+		UserID:    arg.UserID,
+		FeedID:    arg.FeedID,
+	}
+	m.follows = append(m.follows, follow)
+
+	return CreateFeedFollowRow{
+		ID:        follow.ID,
+		CreatedAt: follow.CreatedAt,
+		UpdatedAt: follow.UpdatedAt,
+		UserID:    follow.UserID,
+		FeedID:    follow.FeedID,
+		FolderID:  follow.FolderID,
+		Position:  follow.Position,
+		Title:     follow.Title,
+		Notes:     follow.Notes,
+		Priority:  follow.Priority,
+		FeedName:  f.Name,
+		UserName:  u.Name,
+	}, nil
+}
+
+// Follows the feed in the given folder and position, or moves the follow
+// there if the user already follows it
+func (m *MemoryStore) UpsertFeedFollow(ctx context.Context, arg UpsertFeedFollowParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.user(arg.UserID); !ok {
+		return fmt.Errorf("User `%s` does not exist", arg.UserID)
+	}
+	if _, ok := m.feed(arg.FeedID); !ok {
+		return fmt.Errorf("Feed `%s` does not exist", arg.FeedID)
+	}
+	if arg.FolderID.Valid {
+		if _, ok := m.folder(arg.FolderID.UUID); !ok {
+			return fmt.Errorf("Folder `%s` does not exist", arg.FolderID.UUID)
+		}
+	}
+
+	now := memoryNow()
+	i := m.followIndex(arg.UserID, arg.FeedID)
+	if i < 0 {
+		m.follows = append(m.follows, FeedFollow{ID: arg.ID, CreatedAt: now, UserID: arg.UserID, FeedID: arg.FeedID})
+		i = len(m.follows) - 1
+	}
+
+	m.follows[i].FolderID = arg.FolderID
+	m.follows[i].Position = arg.Position
+	m.follows[i].UpdatedAt = now
+
+	return nil
+}
+
+// One past the last position in the folder, or 0 if it's empty
+func (m *MemoryStore) NextFeedFollowPosition(ctx context.Context, arg NextFeedFollowPositionParams) (int32, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var next int32
+	for _, f := range m.follows {
+		if f.UserID == arg.UserID && sameNullUUID(f.FolderID, arg.FolderID) && f.Position >= next {
+			next = f.Position + 1
+		}
+	}
+
+	return next, nil
+}
+
+// Makes room at the position by moving the follows from there on down one
+func (m *MemoryStore) ShiftFeedFollowPositions(ctx context.Context, arg ShiftFeedFollowPositionsParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, f := range m.follows {
+		if f.UserID == arg.UserID && sameNullUUID(f.FolderID, arg.FolderID) && f.Position >= arg.Position {
+			m.follows[i].Position++
+		}
+	}
+
+	return nil
+}
+
+// Whether two nullable IDs are the same, counting two NULLs as the same, as
+// IS NOT DISTINCT FROM does
+func sameNullUUID(a, b uuid.NullUUID) bool {
+	return a.Valid == b.Valid && (!a.Valid || a.UUID == b.UUID)
+}
+
+func (m *MemoryStore) followIndex(userID, feedID uuid.UUID) int {
+	return slices.IndexFunc(m.follows, func(f FeedFollow) bool {
+		return f.UserID == userID && f.FeedID == feedID
+	})
+}
+
+func (m *MemoryStore) GetFeedFollow(ctx context.Context, arg GetFeedFollowParams) (FeedFollow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	i := m.followIndex(arg.UserID, arg.FeedID)
+	if i < 0 {
+		return FeedFollow{}, sql.ErrNoRows
+	}
+
+	return m.follows[i], nil
+}
+
+// Ordered by position, then by feed name, like the SQL stores
+func (m *MemoryStore) GetFeedFollowsForUser(ctx context.Context, name string) ([]GetFeedFollowsForUserRow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rows := []GetFeedFollowsForUserRow{}
+	feedNames := map[uuid.UUID]string{}
+	for _, follow := range m.follows {
+		u, ok := m.user(follow.UserID)
+		if !ok || u.Name != name {
+			continue
+		}
+		f, ok := m.feed(follow.FeedID)
+		if !ok {
+			continue
+		}
+
+		feedName := follow.Title
+		if feedName == "" {
+			feedName = f.Name
+		}
+		feedNames[f.ID] = f.Name
+
+		rows = append(rows, GetFeedFollowsForUserRow{
+			ID:        follow.ID,
+			CreatedAt: follow.CreatedAt,
+			UpdatedAt: follow.UpdatedAt,
+			UserID:    follow.UserID,
+			FeedID:    follow.FeedID,
+			FolderID:  follow.FolderID,
+			Position:  follow.Position,
+			Title:     follow.Title,
+			Notes:     follow.Notes,
+			Priority:  follow.Priority,
+			UserName:  u.Name,
+			FeedName:  feedName,
+			FeedUrl:   f.Url,
+		})
+	}
+
+	slices.SortStableFunc(rows, func(a, b GetFeedFollowsForUserRow) int {
+		if a.Position != b.Position {
+			return int(a.Position - b.Position)
+		}
+		return strings.Compare(feedNames[a.FeedID], feedNames[b.FeedID])
+	})
+
+	return rows, nil
+}
+
+func (m *MemoryStore) UpdateFeedFollowDetails(ctx context.Context, arg UpdateFeedFollowDetailsParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	i := m.followIndex(arg.UserID, arg.FeedID)
+	if i >= 0 {
+		m.follows[i].Title = arg.Title
+		m.follows[i].Notes = arg.Notes
+		m.follows[i].Priority = arg.Priority
+		m.follows[i].UpdatedAt = memoryNow()
+	}
+
+	return nil
+}
+
+func (m *MemoryStore) DeleteFeedFollow(ctx context.Context, arg DeleteFeedFollowParams) (FeedFollow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	i := m.followIndex(arg.UserID, arg.FeedID)
+	if i < 0 {
+		return FeedFollow{}, sql.ErrNoRows
+	}
+
+	follow := m.follows[i]
+	m.follows = slices.Delete(m.follows, i, i+1)
+
+	return follow, nil
+}
+
+func (m *MemoryStore) folder(id uuid.UUID) (*Folder, bool) {
+	i := slices.IndexFunc(m.folders, func(f Folder) bool { return f.ID == id })
+	if i < 0 {
+		return nil, false
+	}
+	return &m.folders[i], true
+}
+
+// Names are unique among a folder's siblings
+func (m *MemoryStore) CreateFolder(ctx context.Context, arg CreateFolderParams) (Folder, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.user(arg.UserID); !ok {
+		return Folder{}, fmt.Errorf("User `%s` does not exist", arg.UserID)
+	}
+	if arg.ParentID.Valid {
+		if _, ok := m.folder(arg.ParentID.UUID); !ok {
+			return Folder{}, fmt.Errorf("Folder `%s` does not exist", arg.ParentID.UUID)
+		}
+	}
+	if slices.ContainsFunc(m.folders, func(f Folder) bool {
+		return f.UserID == arg.UserID && f.ParentID == arg.ParentID && f.Name == arg.Name
+	}) {
+		return Folder{}, fmt.Errorf("%w: folders.name", ErrUniqueViolation)
+	}
+
+	now := memoryNow()
+	folder := Folder{
+		ID:        arg.ID,
+		CreatedAt: now,
+		UpdatedAt: now,
+		UserID:    arg.UserID,
+		ParentID:  arg.ParentID,
+		Name:      arg.Name,
+		Position:  arg.Position,
+	}
+	m.folders = append(m.folders, folder)
+
+	return folder, nil
+}
+
+// Ordered by position, then by name, like the SQL stores
+func (m *MemoryStore) GetFoldersForUser(ctx context.Context, userID uuid.UUID) ([]Folder, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	folders := []Folder{}
+	for _, f := range m.folders {
+		if f.UserID == userID {
+			folders = append(folders, f)
+		}
+	}
+
+	slices.SortStableFunc(folders, func(a, b Folder) int {
+		if a.Position != b.Position {
+			return int(a.Position - b.Position)
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return folders, nil
+}
+
+func (m *MemoryStore) MoveFeedFollow(ctx context.Context, arg MoveFeedFollowParams) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if arg.FolderID.Valid {
+		if _, ok := m.folder(arg.FolderID.UUID); !ok {
+			return 0, fmt.Errorf("Folder `%s` does not exist", arg.FolderID.UUID)
+		}
+	}
+
+	i := m.followIndex(arg.UserID, arg.FeedID)
+	if i < 0 {
+		return 0, nil
+	}
+
+	m.follows[i].FolderID = arg.FolderID
+	m.follows[i].Position = arg.Position
+	m.follows[i].UpdatedAt = memoryNow()
+
+	return 1, nil
+}
+
+func (m *MemoryStore) MoveFolder(ctx context.Context, arg MoveFolderParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if arg.ParentID.Valid {
+		if _, ok := m.folder(arg.ParentID.UUID); !ok {
+			return fmt.Errorf("Folder `%s` does not exist", arg.ParentID.UUID)
+		}
+	}
+
+	f, ok := m.folder(arg.ID)
+	if !ok || f.UserID != arg.UserID {
+		return nil
+	}
+	if slices.ContainsFunc(m.folders, func(other Folder) bool {
+		return other.ID != f.ID && other.UserID == f.UserID && sameNullUUID(other.ParentID, arg.ParentID) && other.Name == f.Name
+	}) {
+		return fmt.Errorf("%w: folders.name", ErrUniqueViolation)
+	}
+
+	f.ParentID = arg.ParentID
+	f.Position = arg.Position
+	f.UpdatedAt = memoryNow()
+
+	return nil
+}
+
+// Deletes the folder with its subfolders. The feeds in them are left followed,
+// outside any folder.
+func (m *MemoryStore) DeleteFolder(ctx context.Context, arg DeleteFolderParams) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	f, ok := m.folder(arg.ID)
+	if !ok || f.UserID != arg.UserID {
+		return 0, nil
+	}
+
+	deleted := map[uuid.UUID]bool{f.ID: true}
+	for changed := true; changed; {
+		changed = false
+		for _, other := range m.folders {
+			if !deleted[other.ID] && other.ParentID.Valid && deleted[other.ParentID.UUID] {
+				deleted[other.ID] = true
+				changed = true
+			}
+		}
+	}
+
+	m.folders = slices.DeleteFunc(m.folders, func(f Folder) bool { return deleted[f.ID] })
+	for i, f := range m.follows {
+		if f.FolderID.Valid && deleted[f.FolderID.UUID] {
+			m.follows[i].FolderID = uuid.NullUUID{}
+		}
+	}
+
+	return 1, nil
+}
+
+// One past the last position among the folder's subfolders, or 0 if it has
+// none
+func (m *MemoryStore) NextFolderPosition(ctx context.Context, arg NextFolderPositionParams) (int32, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var next int32
+	for _, f := range m.folders {
+		if f.UserID == arg.UserID && sameNullUUID(f.ParentID, arg.ParentID) && f.Position >= next {
+			next = f.Position + 1
+		}
+	}
+
+	return next, nil
+}
+
+// Makes room at the position by moving the subfolders from there on down one
+func (m *MemoryStore) ShiftFolderPositions(ctx context.Context, arg ShiftFolderPositionsParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, f := range m.folders {
+		if f.UserID == arg.UserID && sameNullUUID(f.ParentID, arg.ParentID) && f.Position >= arg.Position {
+			m.folders[i].Position++
+		}
+	}
+
+	return nil
+}
+
+func (m *MemoryStore) CreatePost(ctx context.Context, arg CreatePostParams) (Post, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.feed(arg.FeedID); !ok {
+		return Post{}, fmt.Errorf("Feed `%s` does not exist", arg.FeedID)
+	}
+	if slices.ContainsFunc(m.posts, func(p Post) bool { return p.Url == arg.Url }) {
+		return Post{}, fmt.Errorf("%w: posts.url", ErrUniqueViolation)
+	}
+
+	now := memoryNow()
+	post := Post{
+		ID:          arg.ID,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		Title:       arg.Title,
+		Url:         arg.Url,
+		Description: arg.Description,
+		PublishedAt: arg.PublishedAt,
+		FeedID:      arg.FeedID,
+		Content:     arg.Content,
+		Author:      arg.Author,
+		Categories:  slices.Clone(arg.Categories),
+	}
+	m.posts = append(m.posts, post)
+
+	return post, nil
+}
+
+func (m *MemoryStore) UpdatePost(ctx context.Context, arg UpdatePostParams) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	i := slices.IndexFunc(m.posts, func(p Post) bool { return p.Url == arg.Url && p.FeedID == arg.FeedID })
+	if i < 0 {
+		return 0, nil
+	}
+
+	p := &m.posts[i]
+	samePublishedAt := p.PublishedAt.Valid == arg.PublishedAt.Valid && p.PublishedAt.Time.Equal(arg.PublishedAt.Time)
+	if p.Title == arg.Title && p.Description == arg.Description && samePublishedAt &&
+		p.Content == arg.Content && p.Author == arg.Author && slices.Equal(p.Categories, arg.Categories) {
+		return 0, nil
+	}
+
+	p.Title = arg.Title
+	p.Description = arg.Description
+	p.PublishedAt = arg.PublishedAt
+	p.Content = arg.Content
+	p.Author = arg.Author
+	p.Categories = slices.Clone(arg.Categories)
+	p.UpdatedAt = memoryNow()
+
+	return 1, nil
+}
+
+// Deletes posts along with everyone's reads of them and their alerts. Saved
+// copies of them stay, no longer linked to the post.
+func (m *MemoryStore) deletePosts(match func(Post) bool) {
+	deleted := map[uuid.UUID]bool{}
+	m.posts = slices.DeleteFunc(m.posts, func(p Post) bool {
+		deleted[p.ID] = match(p)
+		return deleted[p.ID]
+	})
+	m.postAlerts = slices.DeleteFunc(m.postAlerts, func(a PostAlert) bool { return deleted[a.PostID] })
+	for i, p := range m.savedPosts {
+		if p.PostID.Valid && deleted[p.PostID.UUID] {
+			m.savedPosts[i].PostID = uuid.NullUUID{}
+		}
+	}
+
+	for r := range m.reads {
+		if deleted[r.postID] {
+			delete(m.reads, r)
+		}
+	}
+}
+
+func (m *MemoryStore) GetPostByID(ctx context.Context, id uuid.UUID) (Post, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, p := range m.posts {
+		if p.ID == id {
+			return p, nil
+		}
+	}
+
+	return Post{}, sql.ErrNoRows
+}
+
+func (m *MemoryStore) GetPostByURL(ctx context.Context, url string) (Post, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, p := range m.posts {
+		if p.Url == url {
+			return p, nil
+		}
+	}
+
+	return Post{}, sql.ErrNoRows
+}
+
+func (m *MemoryStore) GetLastPostTimes(ctx context.Context) ([]GetLastPostTimesRow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	last := map[uuid.UUID]time.Time{}
+	for _, p := range m.posts {
+		if p.CreatedAt.After(last[p.FeedID]) {
+			last[p.FeedID] = p.CreatedAt
+		}
+	}
+
+	rows := []GetLastPostTimesRow{}
+	for feedID, createdAt := range last {
+		rows = append(rows, GetLastPostTimesRow{FeedID: feedID, CreatedAt: createdAt})
+	}
+	slices.SortFunc(rows, func(a, b GetLastPostTimesRow) int { return strings.Compare(a.FeedID.String(), b.FeedID.String()) })
+
+	return rows, nil
+}
+
+// Posts in the feeds the user follows, newest first with unpublished posts
+// leading, as in the SQL stores
+func (m *MemoryStore) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams) ([]Post, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	posts := []Post{}
+	for _, p := range m.posts {
+		if m.followIndex(arg.UserID, p.FeedID) >= 0 {
+			posts = append(posts, p)
+		}
+	}
+
+	slices.SortStableFunc(posts, func(a, b Post) int {
+		switch {
+		case a.PublishedAt.Valid != b.PublishedAt.Valid:
+			if !a.PublishedAt.Valid {
+				return -1
+			}
+			return 1
+		default:
+			return b.PublishedAt.Time.Compare(a.PublishedAt.Time)
+		}
+	})
+
+	return limitPosts(posts, arg.Limit), nil
+}
+
+func (m *MemoryStore) BrowsePostsNewestFirst(ctx context.Context, arg BrowsePostsNewestFirstParams) ([]Post, error) {
+	return m.browsePosts(arg, true)
+}
+
+func (m *MemoryStore) BrowsePostsOldestFirst(ctx context.Context, arg BrowsePostsOldestFirstParams) ([]Post, error) {
+	return m.browsePosts(BrowsePostsNewestFirstParams(arg), false)
+}
+
+func (m *MemoryStore) browsePosts(arg BrowsePostsNewestFirstParams, newestFirst bool) ([]Post, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Posts sort by publication time, falling back to creation time, then ID
+	compare := func(a, b Post) int {
+		c := postSortTime(a).Compare(postSortTime(b))
+		if c == 0 {
+			c = strings.Compare(a.ID.String(), b.ID.String())
+		}
+		if newestFirst {
+			return -c
+		}
+		return c
+	}
+
+	var cursor Post
+	if arg.CursorPublishedAt.Valid {
+		cursor = Post{ID: arg.CursorID.UUID, PublishedAt: arg.CursorPublishedAt}
+	}
+
+	posts := []Post{}
+	for _, p := range m.posts {
+		i := m.followIndex(arg.UserID, p.FeedID)
+		if i < 0 {
+			continue
+		}
+		if arg.FeedIds != nil && !slices.Contains(arg.FeedIds, p.FeedID) {
+			continue
+		}
+		if arg.Since.Valid && postSortTime(p).Before(arg.Since.Time) {
+			continue
+		}
+		if arg.Until.Valid && !postSortTime(p).Before(arg.Until.Time) {
+			continue
+		}
+		if arg.UnreadOnly {
+			if _, read := m.reads[postRead{userID: arg.UserID, postID: p.ID}]; read {
+				continue
+			}
+		}
+		if arg.MinPriority.Valid && m.follows[i].Priority < arg.MinPriority.Int32 {
+			continue
+		}
+		if arg.ApplyFilters {
+			visible, err := m.postVisible(arg.UserID, p)
+			if err != nil {
+				return nil, err
+			}
+			if !visible {
+				continue
+			}
+		}
+		if arg.CursorPublishedAt.Valid && compare(p, cursor) <= 0 {
+			continue
+		}
+		posts = append(posts, p)
+	}
+
+	slices.SortFunc(posts, compare)
+
+	return limitPosts(posts, arg.RowLimit), nil
+}
+
+// Whether the user's filter rules let the post through, as post_visible
+// decides in the SQL stores: it must match none of their exclude rules and,
+// if any include rules apply to its feed, at least one of those
+func (m *MemoryStore) postVisible(userID uuid.UUID, p Post) (bool, error) {
+	included, hasInclude := false, false
+	for _, r := range m.filterRules {
+		if r.UserID != userID || r.FeedID.Valid && r.FeedID.UUID != p.FeedID {
+			continue
+		}
+
+		matches, err := postMatches(r.Field, r.Pattern, r.IsRegex, p.Title, p.Description, p.Content, p.Author, p.Categories)
+		if err != nil {
+			return false, fmt.Errorf("Filter rule `%s`: %v", r.Name, err)
+		}
+
+		switch r.Action {
+		case "exclude":
+			if matches {
+				return false, nil
+			}
+		case "include":
+			hasInclude = true
+			included = included || matches
+		}
+	}
+
+	return !hasInclude || included, nil
+}
+
+func postSortTime(p Post) time.Time {
+	if p.PublishedAt.Valid {
+		return p.PublishedAt.Time
+	}
+	return p.CreatedAt
+}
+
+func limitPosts(posts []Post, limit int32) []Post {
+	if limit >= 0 && len(posts) > int(limit) {
+		return posts[:limit]
+	}
+	return posts
+}
+
+func (m *MemoryStore) MarkPostRead(ctx context.Context, arg MarkPostReadParams) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !slices.ContainsFunc(m.posts, func(p Post) bool { return p.ID == arg.PostID }) {
+		return 0, fmt.Errorf("Post `%s` does not exist", arg.PostID)
+	}
+
+	return m.markRead(arg.UserID, func(p Post) bool { return p.ID == arg.PostID }), nil
+}
+
+func (m *MemoryStore) MarkPostUnread(ctx context.Context, arg MarkPostUnreadParams) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.markUnread(arg.UserID, func(p Post) bool { return p.ID == arg.PostID }), nil
+}
+
+// Marks every post matching as read by the user, returning how many weren't
+// already
+func (m *MemoryStore) markRead(userID uuid.UUID, match func(Post) bool) int64 {
+	var count int64
+	for _, p := range m.posts {
+		r := postRead{userID: userID, postID: p.ID}
+		if _, ok := m.reads[r]; ok || !match(p) {
+			continue
+		}
+		m.reads[r] = memoryNow()
+		count++
+	}
+
+	return count
+}
+
+// Marks every post matching as unread by the user, returning how many were
+// read
+func (m *MemoryStore) markUnread(userID uuid.UUID, match func(Post) bool) int64 {
+	var count int64
+	for _, p := range m.posts {
+		r := postRead{userID: userID, postID: p.ID}
+		if _, ok := m.reads[r]; !ok || !match(p) {
+			continue
+		}
+		delete(m.reads, r)
+		count++
+	}
+
+	return count
+}
+
+func (m *MemoryStore) MarkFeedRead(ctx context.Context, arg MarkFeedReadParams) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.markRead(arg.UserID, func(p Post) bool { return p.FeedID == arg.FeedID }), nil
+}
+
+func (m *MemoryStore) MarkFeedUnread(ctx context.Context, arg MarkFeedUnreadParams) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.markUnread(arg.UserID, func(p Post) bool { return p.FeedID == arg.FeedID }), nil
+}
+
+// Marks the posts published before the time in the feeds the user follows
+func (m *MemoryStore) MarkPostsReadBefore(ctx context.Context, arg MarkPostsReadBeforeParams) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.markRead(arg.UserID, func(p Post) bool {
+		return m.followIndex(arg.UserID, p.FeedID) >= 0 && postSortTime(p).Before(arg.Before)
+	}), nil
+}
+
+func (m *MemoryStore) MarkPostsUnreadBefore(ctx context.Context, arg MarkPostsUnreadBeforeParams) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.markUnread(arg.UserID, func(p Post) bool { return postSortTime(p).Before(arg.Before) }), nil
+}
+
+// Saving a post the user already saved keeps its note and tags unless new
+// ones are given
+func (m *MemoryStore) SavePost(ctx context.Context, arg SavePostParams) (SavedPost, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.user(arg.UserID); !ok {
+		return SavedPost{}, fmt.Errorf("User `%s` does not exist", arg.UserID)
+	}
+
+	now := memoryNow()
+	i := slices.IndexFunc(m.savedPosts, func(p SavedPost) bool { return p.UserID == arg.UserID && p.Url == arg.Url })
+	if i >= 0 {
+		saved := &m.savedPosts[i]
+		if arg.Note != "" {
+			saved.Note = arg.Note
+		}
+		if len(arg.Tags) > 0 {
+			saved.Tags = slices.Clone(arg.Tags)
+		}
+		saved.UpdatedAt = now
+		return *saved, nil
+	}
+
+	saved := SavedPost{
+		ID:          arg.ID,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		UserID:      arg.UserID,
+		PostID:      arg.PostID,
+		Title:       arg.Title,
+		Url:         arg.Url,
+		Description: arg.Description,
+		PublishedAt: arg.PublishedAt,
+		FeedName:    arg.FeedName,
+		Note:        arg.Note,
+		Tags:        slices.Clone(arg.Tags),
+	}
+	if saved.Tags == nil {
+		saved.Tags = []string{}
+	}
+	m.savedPosts = append(m.savedPosts, saved)
+
+	return saved, nil
+}
+
+// Newest first, like the SQL stores
+func (m *MemoryStore) GetSavedPostsForUser(ctx context.Context, userID uuid.UUID) ([]SavedPost, error) {
+	return m.savedPostsFor(func(p SavedPost) bool { return p.UserID == userID }), nil
+}
+
+func (m *MemoryStore) GetSavedPostsForUserByTag(ctx context.Context, arg GetSavedPostsForUserByTagParams) ([]SavedPost, error) {
+	return m.savedPostsFor(func(p SavedPost) bool {
+		return p.UserID == arg.UserID && slices.Contains(p.Tags, arg.Tag)
+	}), nil
+}
+
+func (m *MemoryStore) savedPostsFor(match func(SavedPost) bool) []SavedPost {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Saved in the same instant, the later one comes first
+	saved := []SavedPost{}
+	for i := len(m.savedPosts) - 1; i >= 0; i-- {
+		if match(m.savedPosts[i]) {
+			saved = append(saved, m.savedPosts[i])
+		}
+	}
+
+	slices.SortStableFunc(saved, func(a, b SavedPost) int { return b.CreatedAt.Compare(a.CreatedAt) })
+
+	return saved
+}
+
+// Unsaves by the ID of the saved copy or of the post it was saved from
+func (m *MemoryStore) UnsavePostByID(ctx context.Context, arg UnsavePostByIDParams) (int64, error) {
+	return m.unsave(func(p SavedPost) bool {
+		return p.UserID == arg.UserID && (p.ID == arg.ID || p.PostID.Valid && p.PostID.UUID == arg.ID)
+	}), nil
+}
+
+func (m *MemoryStore) UnsavePostByURL(ctx context.Context, arg UnsavePostByURLParams) (int64, error) {
+	return m.unsave(func(p SavedPost) bool { return p.UserID == arg.UserID && p.Url == arg.Url }), nil
+}
+
+func (m *MemoryStore) unsave(match func(SavedPost) bool) int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	before := len(m.savedPosts)
+	m.savedPosts = slices.DeleteFunc(m.savedPosts, match)
+
+	return int64(before - len(m.savedPosts))
+}
+
+// Approximates the SQL stores' full-text search: a post matches when every
+// word or phrase of the query appears somewhere in its title, description or
+// content, ignoring case, and ranks higher the more often they do
+func (m *MemoryStore) SearchPosts(ctx context.Context, arg SearchPostsParams) ([]SearchPostsRow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	clauses := searchClauses(arg.Query)
+
+	rows := []SearchPostsRow{}
+	for _, p := range m.posts {
+		if arg.FollowedOnly && m.followIndex(arg.UserID, p.FeedID) < 0 {
+			continue
+		}
+		if arg.Since.Valid && postSortTime(p).Before(arg.Since.Time) {
+			continue
+		}
+		if arg.Until.Valid && !postSortTime(p).Before(arg.Until.Time) {
+			continue
+		}
+		f, ok := m.feed(p.FeedID)
+		if !ok {
+			continue
+		}
+
+		rank, ok := searchRank(clauses, p.Title+" "+p.Description+" "+p.Content)
+		if !ok {
+			continue
+		}
+
+		rows = append(rows, SearchPostsRow{
+			ID:          p.ID,
+			Title:       p.Title,
+			Url:         p.Url,
+			PublishedAt: p.PublishedAt,
+			FeedName:    f.Name,
+			Rank:        rank,
+			Snippet:     p.Title,
+		})
+	}
+
+	slices.SortStableFunc(rows, func(a, b SearchPostsRow) int {
+		if a.Rank != b.Rank {
+			if a.Rank > b.Rank {
+				return -1
+			}
+			return 1
+		}
+		return b.PublishedAt.Time.Compare(a.PublishedAt.Time)
+	})
+
+	if arg.RowLimit >= 0 && len(rows) > int(arg.RowLimit) {
+		rows = rows[:arg.RowLimit]
+	}
+
+	return rows, nil
+}
+
+// How often the terms of the query's clauses appear in text, and whether it
+// matches at all
+func searchRank(clauses []searchClause, text string) (float32, bool) {
+	text = strings.ToLower(text)
+
+	var rank float32
+	included := false
+	for _, c := range clauses {
+		var count int
+		for _, term := range c.terms {
+			count += strings.Count(text, strings.ToLower(term))
+		}
+
+		switch {
+		case c.excluded && count > 0:
+			return 0, false
+		case c.excluded:
+		case count == 0:
+			return 0, false
+		default:
+			included = true
+			rank += float32(count)
+		}
+	}
+
+	return rank, included
+}
+
+func (m *MemoryStore) CreateFilterRule(ctx context.Context, arg CreateFilterRuleParams) (FilterRule, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.user(arg.UserID); !ok {
+		return FilterRule{}, fmt.Errorf("User `%s` does not exist", arg.UserID)
+	}
+	if arg.FeedID.Valid {
+		if _, ok := m.feed(arg.FeedID.UUID); !ok {
+			return FilterRule{}, fmt.Errorf("Feed `%s` does not exist", arg.FeedID.UUID)
+		}
+	}
+	if slices.ContainsFunc(m.filterRules, func(r FilterRule) bool { return r.UserID == arg.UserID && r.Name == arg.Name }) {
+		return FilterRule{}, fmt.Errorf("%w: filter_rules.user_id, filter_rules.name", ErrUniqueViolation)
+	}
+
+	now := memoryNow()
+	rule := FilterRule{
+		ID:        arg.ID,
+		CreatedAt: now,
+		UpdatedAt: now,
+		UserID:    arg.UserID,
+		Name:      arg.Name,
+		Action:    arg.Action,
+		Field:     arg.Field,
+		Pattern:   arg.Pattern,
+		IsRegex:   arg.IsRegex,
+		FeedID:    arg.FeedID,
+	}
+	m.filterRules = append(m.filterRules, rule)
+
+	return rule, nil
+}
+
+// Ordered by name, like the SQL stores
+func (m *MemoryStore) GetFilterRulesForUser(ctx context.Context, userID uuid.UUID) ([]GetFilterRulesForUserRow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rows := []GetFilterRulesForUserRow{}
+	for _, r := range m.filterRules {
+		if r.UserID != userID {
+			continue
+		}
+
+		feedName := sql.NullString{}
+		if f, ok := m.feed(r.FeedID.UUID); r.FeedID.Valid && ok {
+			feedName = sql.NullString{String: f.Name, Valid: true}
+		}
+
+		rows = append(rows, GetFilterRulesForUserRow{
+			ID:        r.ID,
+			CreatedAt: r.CreatedAt,
+			UpdatedAt: r.UpdatedAt,
+			UserID:    r.UserID,
+			Name:      r.Name,
+			Action:    r.Action,
+			Field:     r.Field,
+			Pattern:   r.Pattern,
+			IsRegex:   r.IsRegex,
+			FeedID:    r.FeedID,
+			FeedName:  feedName,
+		})
+	}
+
+	slices.SortFunc(rows, func(a, b GetFilterRulesForUserRow) int { return strings.Compare(a.Name, b.Name) })
+
+	return rows, nil
+}
+
+func (m *MemoryStore) DeleteFilterRule(ctx context.Context, arg DeleteFilterRuleParams) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	before := len(m.filterRules)
+	m.filterRules = slices.DeleteFunc(m.filterRules, func(r FilterRule) bool {
+		return r.UserID == arg.UserID && r.Name == arg.Name
+	})
+
+	return int64(before - len(m.filterRules)), nil
+}
+
+func (m *MemoryStore) GetFilterRuleByName(ctx context.Context, arg GetFilterRuleByNameParams) (FilterRule, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, r := range m.filterRules {
+		if r.UserID == arg.UserID && r.Name == arg.Name {
+			return r, nil
+		}
+	}
+
+	return FilterRule{}, sql.ErrNoRows
+}
+
+// The posts in the user's feeds a rule would hide, or with HideMatches unset
+// the ones it would let through, newest first, each row counting them all
+func (m *MemoryStore) PreviewFilterRule(ctx context.Context, arg PreviewFilterRuleParams) ([]PreviewFilterRuleRow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	posts := []Post{}
+	for _, p := range m.posts {
+		if m.followIndex(arg.UserID, p.FeedID) < 0 || arg.FeedID.Valid && p.FeedID != arg.FeedID.UUID {
+			continue
+		}
+
+		matches, err := postMatches(arg.Field, arg.Pattern, arg.IsRegex, p.Title, p.Description, p.Content, p.Author, p.Categories)
+		if err != nil {
+			return nil, err
+		}
+		if matches == arg.HideMatches {
+			posts = append(posts, p)
+		}
+	}
+
+	slices.SortStableFunc(posts, func(a, b Post) int { return postSortTime(b).Compare(postSortTime(a)) })
+
+	rows := []PreviewFilterRuleRow{}
+	for _, p := range limitPosts(posts, arg.RowLimit) {
+		f, _ := m.feed(p.FeedID)
+		rows = append(rows, PreviewFilterRuleRow{
+			ID:          p.ID,
+			Title:       p.Title,
+			Url:         p.Url,
+			PublishedAt: p.PublishedAt,
+			FeedName:    f.Name,
+			Total:       int64(len(posts)),
+		})
+	}
+
+	return rows, nil
+}
+
+// Filter rules run with Go's regexp here, as on SQLite
+func (m *MemoryStore) CheckFilterRegex(ctx context.Context, pattern string) error {
+	_, err := regexp.Compile("(?i)" + pattern)
+	return err
+}
+
+func (m *MemoryStore) CreateAlertRule(ctx context.Context, arg CreateAlertRuleParams) (AlertRule, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.user(arg.UserID); !ok {
+		return AlertRule{}, fmt.Errorf("User `%s` does not exist", arg.UserID)
+	}
+	if slices.ContainsFunc(m.alertRules, func(r AlertRule) bool { return r.UserID == arg.UserID && r.Name == arg.Name }) {
+		return AlertRule{}, fmt.Errorf("%w: alert_rules.user_id, alert_rules.name", ErrUniqueViolation)
+	}
+
+	now := memoryNow()
+	rule := AlertRule{
+		ID:        arg.ID,
+		CreatedAt: now,
+		UpdatedAt: now,
+		UserID:    arg.UserID,
+		Name:      arg.Name,
+		Kind:      arg.Kind,
+		Pattern:   arg.Pattern,
+		Notifier:  arg.Notifier,
+		Target:    arg.Target,
+	}
+	m.alertRules = append(m.alertRules, rule)
+
+	return rule, nil
+}
+
+// The rules of every user following the feed
+func (m *MemoryStore) GetAlertRulesForFeed(ctx context.Context, feedID uuid.UUID) ([]AlertRule, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rules := []AlertRule{}
+	for _, r := range m.alertRules {
+		if m.followIndex(r.UserID, feedID) >= 0 {
+			rules = append(rules, r)
+		}
+	}
+
+	return rules, nil
+}
+
+// Tagging a post with the same rule twice does nothing
+func (m *MemoryStore) CreatePostAlert(ctx context.Context, arg CreatePostAlertParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !slices.ContainsFunc(m.posts, func(p Post) bool { return p.ID == arg.PostID }) {
+		return fmt.Errorf("Post `%s` does not exist", arg.PostID)
+	}
+	if !slices.ContainsFunc(m.alertRules, func(r AlertRule) bool { return r.ID == arg.RuleID }) {
+		return fmt.Errorf("Alert rule `%s` does not exist", arg.RuleID)
+	}
+	if slices.ContainsFunc(m.postAlerts, func(a PostAlert) bool { return a.PostID == arg.PostID && a.RuleID == arg.RuleID }) {
+		return nil
+	}
+
+	m.postAlerts = append(m.postAlerts, PostAlert{PostID: arg.PostID, RuleID: arg.RuleID, CreatedAt: memoryNow()})
+
+	return nil
+}
+
+// Ordered by name, like the SQL stores
+func (m *MemoryStore) GetAlertRulesForUser(ctx context.Context, userID uuid.UUID) ([]AlertRule, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rules := []AlertRule{}
+	for _, r := range m.alertRules {
+		if r.UserID == userID {
+			rules = append(rules, r)
+		}
+	}
+
+	slices.SortFunc(rules, func(a, b AlertRule) int { return strings.Compare(a.Name, b.Name) })
+
+	return rules, nil
+}
+
+func (m *MemoryStore) DeleteAlertRule(ctx context.Context, arg DeleteAlertRuleParams) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	before := len(m.alertRules)
+	m.deleteAlertRules(func(r AlertRule) bool { return r.UserID == arg.UserID && r.Name == arg.Name })
+
+	return int64(before - len(m.alertRules)), nil
+}
+
+// The posts the user's rules tagged, most recently tagged first
+func (m *MemoryStore) GetAlertedPostsForUser(ctx context.Context, arg GetAlertedPostsForUserParams) ([]GetAlertedPostsForUserRow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rows := []GetAlertedPostsForUserRow{}
+	for i := len(m.postAlerts) - 1; i >= 0; i-- {
+		a := m.postAlerts[i]
+		ri := slices.IndexFunc(m.alertRules, func(r AlertRule) bool { return r.ID == a.RuleID })
+		pi := slices.IndexFunc(m.posts, func(p Post) bool { return p.ID == a.PostID })
+		if ri < 0 || pi < 0 || m.alertRules[ri].UserID != arg.UserID {
+			continue
+		}
+
+		p := m.posts[pi]
+		rows = append(rows, GetAlertedPostsForUserRow{
+			ID:          p.ID,
+			CreatedAt:   p.CreatedAt,
+			UpdatedAt:   p.UpdatedAt,
+			Title:       p.Title,
+			Url:         p.Url,
+			Description: p.Description,
+			PublishedAt: p.PublishedAt,
+			FeedID:      p.FeedID,
+			Content:     p.Content,
+			Author:      p.Author,
+			Categories:  p.Categories,
+			RuleName:    m.alertRules[ri].Name,
+			AlertedAt:   a.CreatedAt,
+		})
+	}
+
+	slices.SortStableFunc(rows, func(a, b GetAlertedPostsForUserRow) int { return b.AlertedAt.Compare(a.AlertedAt) })
+
+	if arg.Limit >= 0 && len(rows) > int(arg.Limit) {
+		rows = rows[:arg.Limit]
+	}
+
+	return rows, nil
+}
+
+// Deletes alert rules along with the alerts they raised
+func (m *MemoryStore) deleteAlertRules(match func(AlertRule) bool) {
+	deleted := map[uuid.UUID]bool{}
+	m.alertRules = slices.DeleteFunc(m.alertRules, func(r AlertRule) bool {
+		deleted[r.ID] = match(r)
+		return deleted[r.ID]
+	})
+
+	m.postAlerts = slices.DeleteFunc(m.postAlerts, func(a PostAlert) bool { return deleted[a.RuleID] })
+}
+
+func (m *MemoryStore) CreateFeedFetch(ctx context.Context, arg CreateFeedFetchParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.feed(arg.FeedID); !ok {
+		return fmt.Errorf("Feed `%s` does not exist", arg.FeedID)
+	}
+
+	m.fetches = append(m.fetches, FeedFetch{
+		ID:         arg.ID,
+		FeedID:     arg.FeedID,
+		FetchedAt:  memoryNow(),
+		StatusCode: arg.StatusCode,
+		DurationMs: arg.DurationMs,
+		Bytes:      arg.Bytes,
+		ItemsSeen:  arg.ItemsSeen,
+		NewPosts:   arg.NewPosts,
+		Error:      arg.Error,
+	})
+
+	return nil
+}
+
+// Oldest first, like the SQL stores
+func (m *MemoryStore) GetFeedFetchesSince(ctx context.Context, fetchedAt time.Time) ([]FeedFetch, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	fetches := []FeedFetch{}
+	for _, f := range m.fetches {
+		if !f.FetchedAt.Before(fetchedAt) {
+			fetches = append(fetches, f)
+		}
+	}
+
+	slices.SortStableFunc(fetches, func(a, b FeedFetch) int { return a.FetchedAt.Compare(b.FetchedAt) })
+
+	return fetches, nil
+}
+
+func (m *MemoryStore) DeleteFeedFetchesBefore(ctx context.Context, fetchedAt time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	before := len(m.fetches)
+	m.fetches = slices.DeleteFunc(m.fetches, func(f FeedFetch) bool { return f.FetchedAt.Before(fetchedAt) })
+
+	return int64(before - len(m.fetches)), nil
+}
+
+func (m *MemoryStore) GetLastFeedFetches(ctx context.Context) ([]FeedFetch, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.lastFetches(func(f FeedFetch) bool { return true }), nil
+}
+
+func (m *MemoryStore) GetLastSuccessfulFeedFetches(ctx context.Context) ([]FeedFetch, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.lastFetches(func(f FeedFetch) bool { return !f.Error.Valid }), nil
+}
+
+// The latest fetch of each feed among those keep accepts
+func (m *MemoryStore) lastFetches(keep func(f FeedFetch) bool) []FeedFetch {
+	last := map[uuid.UUID]FeedFetch{}
+	for _, f := range m.fetches {
+		if l, ok := last[f.FeedID]; keep(f) && (!ok || f.FetchedAt.After(l.FetchedAt)) {
+			last[f.FeedID] = f
+		}
+	}
+
+	fetches := slices.Collect(maps.Values(last))
+	slices.SortFunc(fetches, func(a, b FeedFetch) int { return strings.Compare(a.FeedID.String(), b.FeedID.String()) })
+
+	return fetches
+}
+
+// Feeds with a hub that aren't paused
+func (m *MemoryStore) GetPushFeeds(ctx context.Context) ([]Feed, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	feeds := []Feed{}
+	for _, f := range m.feeds {
+		if f.HubUrl.Valid && !f.PausedAt.Valid {
+			feeds = append(feeds, f)
+		}
+	}
+
+	return feeds, nil
+}
+
+// A feed has one subscription; requesting it again leaves it unverified
+// until the hub confirms
+func (m *MemoryStore) UpsertWebSubSubscription(ctx context.Context, arg UpsertWebSubSubscriptionParams) (WebsubSubscription, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.feed(arg.FeedID); !ok {
+		return WebsubSubscription{}, fmt.Errorf("Feed `%s` does not exist", arg.FeedID)
+	}
+
+	now := memoryNow()
+	i := slices.IndexFunc(m.websubSubs, func(s WebsubSubscription) bool { return s.FeedID == arg.FeedID })
+	if i < 0 {
+		m.websubSubs = append(m.websubSubs, WebsubSubscription{ID: arg.ID, CreatedAt: now, FeedID: arg.FeedID})
+		i = len(m.websubSubs) - 1
+	}
+
+	sub := &m.websubSubs[i]
+	sub.HubUrl = arg.HubUrl
+	sub.TopicUrl = arg.TopicUrl
+	sub.Secret = arg.Secret
+	sub.VerifiedAt = sql.NullTime{}
+	sub.UpdatedAt = now
+
+	return *sub, nil
+}
+
+func (m *MemoryStore) GetWebSubSubscription(ctx context.Context, feedID uuid.UUID) (WebsubSubscription, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, s := range m.websubSubs {
+		if s.FeedID == feedID {
+			return s, nil
+		}
+	}
+
+	return WebsubSubscription{}, sql.ErrNoRows
+}
+
+func (m *MemoryStore) MarkWebSubSubscriptionVerified(ctx context.Context, arg MarkWebSubSubscriptionVerifiedParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.websubSubs {
+		if m.websubSubs[i].FeedID == arg.FeedID {
+			now := memoryNow()
+			m.websubSubs[i].VerifiedAt = sql.NullTime{Time: now, Valid: true}
+			m.websubSubs[i].LeaseExpiresAt = arg.LeaseExpiresAt
+			m.websubSubs[i].UpdatedAt = now
+		}
+	}
+
+	return nil
+}
+
+func (m *MemoryStore) DeleteWebSubSubscription(ctx context.Context, feedID uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.websubSubs = slices.DeleteFunc(m.websubSubs, func(s WebsubSubscription) bool { return s.FeedID == feedID })
+
+	return nil
+}
+
+// A feed has at most one policy; setting it again replaces it
+func (m *MemoryStore) SetFeedRetentionPolicy(ctx context.Context, arg SetFeedRetentionPolicyParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.feed(arg.FeedID); !ok {
+		return fmt.Errorf("Feed `%s` does not exist", arg.FeedID)
+	}
+
+	now := memoryNow()
+	i := slices.IndexFunc(m.retentionPolicies, func(p FeedRetentionPolicy) bool { return p.FeedID == arg.FeedID })
+	if i < 0 {
+		m.retentionPolicies = append(m.retentionPolicies, FeedRetentionPolicy{FeedID: arg.FeedID, CreatedAt: now})
+		i = len(m.retentionPolicies) - 1
+	}
+
+	m.retentionPolicies[i].KeepLast = arg.KeepLast
+	m.retentionPolicies[i].KeepDays = arg.KeepDays
+	m.retentionPolicies[i].UpdatedAt = now
+
+	return nil
+}
+
+func (m *MemoryStore) DeleteFeedRetentionPolicy(ctx context.Context, feedID uuid.UUID) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	before := len(m.retentionPolicies)
+	m.retentionPolicies = slices.DeleteFunc(m.retentionPolicies, func(p FeedRetentionPolicy) bool { return p.FeedID == feedID })
+
+	return int64(before - len(m.retentionPolicies)), nil
+}
+
+// Ordered by feed name, like the SQL stores
+func (m *MemoryStore) GetFeedRetentionPolicies(ctx context.Context) ([]GetFeedRetentionPoliciesRow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rows := []GetFeedRetentionPoliciesRow{}
+	for _, p := range m.retentionPolicies {
+		f, ok := m.feed(p.FeedID)
+		if !ok {
+			continue
+		}
+		rows = append(rows, GetFeedRetentionPoliciesRow{
+			FeedID:    p.FeedID,
+			CreatedAt: p.CreatedAt,
+			UpdatedAt: p.UpdatedAt,
+			KeepLast:  p.KeepLast,
+			KeepDays:  p.KeepDays,
+			FeedName:  f.Name,
+			FeedUrl:   f.Url,
+		})
+	}
+
+	slices.SortStableFunc(rows, func(a, b GetFeedRetentionPoliciesRow) int { return strings.Compare(a.FeedName, b.FeedName) })
+
+	return rows, nil
+}
+
+// The posts retention would delete, as prunable_posts picks them in the SQL
+// stores: past the newest keep_last of their feed or older than keep_days,
+// by the feed's own policy where it sets them and the defaults given where it
+// doesn't, and never a post someone saved
+func (m *MemoryStore) prunablePosts(keepLast, keepDays sql.NullInt32, feedID uuid.NullUUID) []Post {
+	saved := map[uuid.UUID]bool{}
+	for _, s := range m.savedPosts {
+		if s.PostID.Valid {
+			saved[s.PostID.UUID] = true
+		}
+	}
+
+	now := memoryNow()
+	prunable := []Post{}
+	for _, f := range m.feeds {
+		if feedID.Valid && f.ID != feedID.UUID {
+			continue
+		}
+
+		last, days := keepLast, keepDays
+		if i := slices.IndexFunc(m.retentionPolicies, func(p FeedRetentionPolicy) bool { return p.FeedID == f.ID }); i >= 0 {
+			if p := m.retentionPolicies[i]; p.KeepLast.Valid {
+				last = p.KeepLast
+			}
+			if p := m.retentionPolicies[i]; p.KeepDays.Valid {
+				days = p.KeepDays
+			}
+		}
+		if !last.Valid && !days.Valid {
+			continue
+		}
+
+		posts := []Post{}
+		for _, p := range m.posts {
+			if p.FeedID == f.ID {
+				posts = append(posts, p)
+			}
+		}
+		slices.SortFunc(posts, func(a, b Post) int {
+			if c := postSortTime(b).Compare(postSortTime(a)); c != 0 {
+				return c
+			}
+			return strings.Compare(b.ID.String(), a.ID.String())
+		})
+
+		for i, p := range posts {
+			if last.Valid && i < int(last.Int32) {
+				continue
+			}
+			if days.Valid && !postSortTime(p).Before(now.AddDate(0, 0, -int(days.Int32))) {
+				continue
+			}
+			if saved[p.ID] {
+				continue
+			}
+			prunable = append(prunable, p)
+		}
+	}
+
+	return prunable
+}
+
+// How many posts retention would delete from each feed, by feed name
+func (m *MemoryStore) CountPrunablePosts(ctx context.Context, arg CountPrunablePostsParams) ([]CountPrunablePostsRow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rows := []CountPrunablePostsRow{}
+	for _, p := range m.prunablePosts(arg.KeepLast, arg.KeepDays, arg.FeedID) {
+		f, _ := m.feed(p.FeedID)
+		i := slices.IndexFunc(rows, func(r CountPrunablePostsRow) bool { return r.FeedName == f.Name })
+		if i < 0 {
+			rows = append(rows, CountPrunablePostsRow{FeedName: f.Name})
+			i = len(rows) - 1
+		}
+		rows[i].Posts++
+	}
+
+	slices.SortFunc(rows, func(a, b CountPrunablePostsRow) int { return strings.Compare(a.FeedName, b.FeedName) })
+
+	return rows, nil
+}
+
+// Deletes up to a batch of the posts retention would delete, returning the
+// feed ID and name of each
+func (m *MemoryStore) PrunePosts(ctx context.Context, arg PrunePostsParams) ([]PrunePostsRow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	prunable := m.prunablePosts(arg.KeepLast, arg.KeepDays, arg.FeedID)
+	if arg.BatchSize >= 0 && len(prunable) > int(arg.BatchSize) {
+		prunable = prunable[:arg.BatchSize]
+	}
+
+	deleted := map[uuid.UUID]bool{}
+	rows := []PrunePostsRow{}
+	for _, p := range prunable {
+		deleted[p.ID] = true
+		f, _ := m.feed(p.FeedID)
+		rows = append(rows, PrunePostsRow{FeedID: p.FeedID, FeedName: f.Name})
+	}
+	m.deletePosts(func(p Post) bool { return deleted[p.ID] })
+
+	return rows, nil
+}
+
+func (m *MemoryStore) CreateAuditEvent(ctx context.Context, arg CreateAuditEventParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.auditEvents = append(m.auditEvents, AuditEvent{
+		ID:        arg.ID,
+		CreatedAt: memoryNow(),
+		ActorID:   arg.ActorID,
+		ActorName: arg.ActorName,
+		Command:   arg.Command,
+		Args:      slices.Clone(arg.Args),
+		Outcome:   arg.Outcome,
+		Error:     arg.Error,
+	})
+
+	return nil
+}
+
+// Newest first, like the SQL stores
+func (m *MemoryStore) GetAuditEvents(ctx context.Context, arg GetAuditEventsParams) ([]AuditEvent, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	events := []AuditEvent{}
+	for i := len(m.auditEvents) - 1; i >= 0; i-- {
+		e := m.auditEvents[i]
+		switch {
+		case arg.ActorID.Valid && e.ActorID != arg.ActorID:
+		case arg.ActorName.Valid && e.ActorName != arg.ActorName.String:
+		case arg.Command.Valid && e.Command != arg.Command.String:
+		case arg.Since.Valid && e.CreatedAt.Before(arg.Since.Time):
+		case arg.Until.Valid && !e.CreatedAt.Before(arg.Until.Time):
+		case arg.FailuresOnly && e.Outcome != "failure":
+		default:
+			events = append(events, e)
+		}
+	}
+
+	slices.SortStableFunc(events, func(a, b AuditEvent) int { return b.CreatedAt.Compare(a.CreatedAt) })
+
+	if arg.RowLimit >= 0 && len(events) > int(arg.RowLimit) {
+		events = events[:arg.RowLimit]
+	}
+
+	return events, nil
+}
+
+// Locks are held by the store, as the SQL stores' are by their connection
+func (m *MemoryStore) TryAdvisoryLock(ctx context.Context, key int64) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.locks[key] {
+		return false, nil
+	}
+	m.locks[key] = true
+
+	return true, nil
+}
+
+func (m *MemoryStore) AdvisoryUnlock(ctx context.Context, key int64) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	held := m.locks[key]
+	delete(m.locks, key)
+
+	return held, nil
+}
@@ -0,0 +1,92 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestMemoryInTx(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory()
+
+	err := m.InTx(ctx, func(q Store) error {
+		_, err := q.CreateUser(ctx, CreateUserParams{ID: uuid.New(), Name: "alice"})
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Committed transaction failed: %v", err)
+	}
+
+	failure := errors.New("failed")
+	err = m.InTx(ctx, func(q Store) error {
+		_, err := q.CreateUser(ctx, CreateUserParams{ID: uuid.New(), Name: "bob"})
+		if err != nil {
+			return err
+		}
+		_, err = q.RenameUser(ctx, RenameUserParams{ID: m.users[0].ID, Name: "carol"})
+		if err != nil {
+			return err
+		}
+		return failure
+	})
+	if err != failure {
+		t.Fatalf("Failed transaction returned %v", err)
+	}
+
+	users, _ := m.GetUsers(ctx)
+	if len(users) != 1 || users[0].Name != "alice" {
+		t.Errorf("After rollback users are %+v, want only alice", users)
+	}
+}
+
+func TestMemoryAdvisoryLock(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory()
+
+	q, release, err := m.Conn(ctx)
+	if err != nil {
+		t.Fatalf("Conn failed: %v", err)
+	}
+	defer release()
+
+	if locked, _ := q.TryAdvisoryLock(ctx, 42); !locked {
+		t.Error("First lock was refused")
+	}
+	if locked, _ := m.TryAdvisoryLock(ctx, 42); locked {
+		t.Error("Second lock was granted")
+	}
+	if unlocked, _ := q.AdvisoryUnlock(ctx, 42); !unlocked {
+		t.Error("Unlock of a held lock returned false")
+	}
+	if locked, _ := m.TryAdvisoryLock(ctx, 42); !locked {
+		t.Error("Lock after unlock was refused")
+	}
+}
+
+func TestMemoryLastFetches(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory()
+	feedID := uuid.New()
+	now := time.Now()
+
+	m.fetches = []FeedFetch{
+		{ID: uuid.New(), FeedID: feedID, FetchedAt: now.Add(-48 * time.Hour)},
+		{ID: uuid.New(), FeedID: feedID, FetchedAt: now, Error: sql.NullString{String: "timeout", Valid: true}},
+		{ID: uuid.New(), FeedID: feedID, FetchedAt: now.Add(-72 * time.Hour)},
+	}
+
+	last, _ := m.GetLastFeedFetches(ctx)
+	if len(last) != 1 || last[0].Error.String != "timeout" {
+		t.Errorf("Last fetches %+v, want the failed one", last)
+	}
+
+	lastSuccess, _ := m.GetLastSuccessfulFeedFetches(ctx)
+	if len(lastSuccess) != 1 || !lastSuccess[0].FetchedAt.Equal(now.Add(-48*time.Hour)) {
+		t.Errorf("Last successful fetches %+v, want the one from two days ago", lastSuccess)
+	}
+}
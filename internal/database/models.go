@@ -11,6 +11,29 @@ import (
 	"github.com/google/uuid"
 )
 
+type AlertRule struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	UserID    uuid.UUID
+	Name      string
+	Kind      string
+	Pattern   string
+	Notifier  string
+	Target    string
+}
+
+type AuditEvent struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	ActorID   uuid.NullUUID
+	ActorName string
+	Command   string
+	Args      []string
+	Outcome   string
+	Error     string
+}
+
 type Feed struct {
 	ID            uuid.UUID
 	CreatedAt     time.Time
@@ -19,6 +42,21 @@ type Feed struct {
 	Url           string
 	UserID        uuid.UUID
 	LastFetchedAt sql.NullTime
+	HubUrl        sql.NullString
+	SelfUrl       sql.NullString
+	PausedAt      sql.NullTime
+}
+
+type FeedFetch struct {
+	ID         uuid.UUID
+	FeedID     uuid.UUID
+	FetchedAt  time.Time
+	StatusCode sql.NullInt32
+	DurationMs int32
+	Bytes      int64
+	ItemsSeen  int32
+	NewPosts   int32
+	Error      sql.NullString
 }
 
 type FeedFollow struct {
@@ -27,22 +65,112 @@ type FeedFollow struct {
 	UpdatedAt time.Time
 	UserID    uuid.UUID
 	FeedID    uuid.UUID
+	FolderID  uuid.NullUUID
+	Position  int32
+	Title     string
+	Notes     string
+	Priority  int32
+}
+
+type FeedRetentionPolicy struct {
+	FeedID    uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	KeepLast  sql.NullInt32
+	KeepDays  sql.NullInt32
+}
+
+type FilterRule struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	UserID    uuid.UUID
+	Name      string
+	Action    string
+	Field     string
+	Pattern   string
+	IsRegex   bool
+	FeedID    uuid.NullUUID
+}
+
+type Folder struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	UserID    uuid.UUID
+	ParentID  uuid.NullUUID
+	Name      string
+	Position  int32
 }
 
 type Post struct {
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	Title        string
+	Url          string
+	Description  string
+	PublishedAt  sql.NullTime
+	FeedID       uuid.UUID
+	Content      string
+	SearchVector interface{}
+	Author       string
+	Categories   []string
+}
+
+type PostAlert struct {
+	PostID    uuid.UUID
+	RuleID    uuid.UUID
+	CreatedAt time.Time
+}
+
+type PostRead struct {
+	UserID uuid.UUID
+	PostID uuid.UUID
+	ReadAt time.Time
+}
+
+type SavedPost struct {
 	ID          uuid.UUID
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
+	UserID      uuid.UUID
+	PostID      uuid.NullUUID
 	Title       string
 	Url         string
 	Description string
 	PublishedAt sql.NullTime
-	FeedID      uuid.UUID
+	FeedName    string
+	Note        string
+	Tags        []string
+}
+
+type Session struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	LastUsedAt time.Time
+	ExpiresAt  time.Time
+	UserID     uuid.UUID
+	TokenHash  string
 }
 
 type User struct {
-	ID        uuid.UUID
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	Name      string
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	Name         string
+	PasswordHash sql.NullString
+	Role         string
+}
+
+type WebsubSubscription struct {
+	ID             uuid.UUID
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	FeedID         uuid.UUID
+	HubUrl         string
+	TopicUrl       string
+	Secret         string
+	VerifiedAt     sql.NullTime
+	LeaseExpiresAt sql.NullTime
 }
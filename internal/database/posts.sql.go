@@ -8,16 +8,180 @@ package database
 import (
 	"context"
 	"database/sql"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+const browsePostsNewestFirst = `-- name: BrowsePostsNewestFirst :many
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.content, posts.search_vector, posts.author, posts.categories FROM posts
+INNER JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+WHERE feed_follows.user_id = $1
+AND ($2::uuid[] IS NULL OR posts.feed_id = ANY($2::uuid[]))
+AND ($3::timestamp IS NULL OR COALESCE(posts.published_at, posts.created_at) >= $3::timestamp)
+AND ($4::timestamp IS NULL OR COALESCE(posts.published_at, posts.created_at) < $4::timestamp)
+AND (NOT $5::bool OR NOT EXISTS (
+    SELECT 1 FROM post_reads
+    WHERE post_reads.post_id = posts.id AND post_reads.user_id = $1
+))
+AND (NOT $6::bool OR post_visible($1, posts))
+AND ($7::integer IS NULL OR feed_follows.priority >= $7::integer)
+AND (
+    $8::timestamp IS NULL
+    OR (COALESCE(posts.published_at, posts.created_at), posts.id) < ($8::timestamp, $9::uuid)
+)
+ORDER BY COALESCE(posts.published_at, posts.created_at) DESC, posts.id DESC
+LIMIT $10
+`
+
+type BrowsePostsNewestFirstParams struct {
+	UserID            uuid.UUID
+	FeedIds           []uuid.UUID
+	Since             sql.NullTime
+	Until             sql.NullTime
+	UnreadOnly        bool
+	ApplyFilters      bool
+	MinPriority       sql.NullInt32
+	CursorPublishedAt sql.NullTime
+	CursorID          uuid.NullUUID
+	RowLimit          int32
+}
+
+func (q *Queries) BrowsePostsNewestFirst(ctx context.Context, arg BrowsePostsNewestFirstParams) ([]Post, error) {
+	rows, err := q.db.QueryContext(ctx, browsePostsNewestFirst,
+		arg.UserID,
+		pq.Array(arg.FeedIds),
+		arg.Since,
+		arg.Until,
+		arg.UnreadOnly,
+		arg.ApplyFilters,
+		arg.MinPriority,
+		arg.CursorPublishedAt,
+		arg.CursorID,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Post
+	for rows.Next() {
+		var i Post
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.Content,
+			&i.SearchVector,
+			&i.Author,
+			pq.Array(&i.Categories),
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const browsePostsOldestFirst = `-- name: BrowsePostsOldestFirst :many
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.content, posts.search_vector, posts.author, posts.categories FROM posts
+INNER JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+WHERE feed_follows.user_id = $1
+AND ($2::uuid[] IS NULL OR posts.feed_id = ANY($2::uuid[]))
+AND ($3::timestamp IS NULL OR COALESCE(posts.published_at, posts.created_at) >= $3::timestamp)
+AND ($4::timestamp IS NULL OR COALESCE(posts.published_at, posts.created_at) < $4::timestamp)
+AND (NOT $5::bool OR NOT EXISTS (
+    SELECT 1 FROM post_reads
+    WHERE post_reads.post_id = posts.id AND post_reads.user_id = $1
+))
+AND (NOT $6::bool OR post_visible($1, posts))
+AND ($7::integer IS NULL OR feed_follows.priority >= $7::integer)
+AND (
+    $8::timestamp IS NULL
+    OR (COALESCE(posts.published_at, posts.created_at), posts.id) > ($8::timestamp, $9::uuid)
+)
+ORDER BY COALESCE(posts.published_at, posts.created_at) ASC, posts.id ASC
+LIMIT $10
+`
+
+type BrowsePostsOldestFirstParams struct {
+	UserID            uuid.UUID
+	FeedIds           []uuid.UUID
+	Since             sql.NullTime
+	Until             sql.NullTime
+	UnreadOnly        bool
+	ApplyFilters      bool
+	MinPriority       sql.NullInt32
+	CursorPublishedAt sql.NullTime
+	CursorID          uuid.NullUUID
+	RowLimit          int32
+}
+
+func (q *Queries) BrowsePostsOldestFirst(ctx context.Context, arg BrowsePostsOldestFirstParams) ([]Post, error) {
+	rows, err := q.db.QueryContext(ctx, browsePostsOldestFirst,
+		arg.UserID,
+		pq.Array(arg.FeedIds),
+		arg.Since,
+		arg.Until,
+		arg.UnreadOnly,
+		arg.ApplyFilters,
+		arg.MinPriority,
+		arg.CursorPublishedAt,
+		arg.CursorID,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Post
+	for rows.Next() {
+		var i Post
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.Content,
+			&i.SearchVector,
+			&i.Author,
+			pq.Array(&i.Categories),
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const createPost = `-- name: CreatePost :one
-INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id)
+INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id, content, author, categories)
 VALUES (
-    $1, NOW(), NOW(), $2, $3, $4, $5, $6
+    $1, NOW(), NOW(), $2, $3, $4, $5, $6, $7, $8, $9
 )
-RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id
+RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id, content, search_vector, author, categories
 `
 
 type CreatePostParams struct {
@@ -27,6 +191,9 @@ type CreatePostParams struct {
 	Description string
 	PublishedAt sql.NullTime
 	FeedID      uuid.UUID
+	Content     string
+	Author      string
+	Categories  []string
 }
 
 func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, error) {
@@ -37,7 +204,92 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, e
 		arg.Description,
 		arg.PublishedAt,
 		arg.FeedID,
+		arg.Content,
+		arg.Author,
+		pq.Array(arg.Categories),
+	)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Url,
+		&i.Description,
+		&i.PublishedAt,
+		&i.FeedID,
+		&i.Content,
+		&i.SearchVector,
+		&i.Author,
+		pq.Array(&i.Categories),
+	)
+	return i, err
+}
+
+const getLastPostTimes = `-- name: GetLastPostTimes :many
+SELECT DISTINCT ON (feed_id) feed_id, created_at FROM posts
+ORDER BY feed_id, created_at DESC
+`
+
+type GetLastPostTimesRow struct {
+	FeedID    uuid.UUID
+	CreatedAt time.Time
+}
+
+// When each feed last got a new post
+func (q *Queries) GetLastPostTimes(ctx context.Context) ([]GetLastPostTimesRow, error) {
+	rows, err := q.db.QueryContext(ctx, getLastPostTimes)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetLastPostTimesRow
+	for rows.Next() {
+		var i GetLastPostTimesRow
+		if err := rows.Scan(&i.FeedID, &i.CreatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPostByID = `-- name: GetPostByID :one
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, content, search_vector, author, categories FROM posts WHERE id = $1
+`
+
+func (q *Queries) GetPostByID(ctx context.Context, id uuid.UUID) (Post, error) {
+	row := q.db.QueryRowContext(ctx, getPostByID, id)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Url,
+		&i.Description,
+		&i.PublishedAt,
+		&i.FeedID,
+		&i.Content,
+		&i.SearchVector,
+		&i.Author,
+		pq.Array(&i.Categories),
 	)
+	return i, err
+}
+
+const getPostByURL = `-- name: GetPostByURL :one
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, content, search_vector, author, categories FROM posts WHERE url = $1
+`
+
+func (q *Queries) GetPostByURL(ctx context.Context, url string) (Post, error) {
+	row := q.db.QueryRowContext(ctx, getPostByURL, url)
 	var i Post
 	err := row.Scan(
 		&i.ID,
@@ -48,12 +300,16 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, e
 		&i.Description,
 		&i.PublishedAt,
 		&i.FeedID,
+		&i.Content,
+		&i.SearchVector,
+		&i.Author,
+		pq.Array(&i.Categories),
 	)
 	return i, err
 }
 
 const getPostsForUser = `-- name: GetPostsForUser :many
-SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id FROM posts 
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.content, posts.search_vector, posts.author, posts.categories FROM posts 
 INNER JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
 WHERE feed_follows.user_id = $1
 ORDER BY published_at DESC
@@ -83,6 +339,95 @@ func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams
 			&i.Description,
 			&i.PublishedAt,
 			&i.FeedID,
+			&i.Content,
+			&i.SearchVector,
+			&i.Author,
+			pq.Array(&i.Categories),
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const searchPosts = `-- name: SearchPosts :many
+SELECT
+    posts.id,
+    posts.title,
+    posts.url,
+    posts.published_at,
+    feeds.name AS feed_name,
+    ts_rank_cd(posts.search_vector, query)::real AS rank,
+    ts_headline(
+        'english',
+        posts.title || ' ' || posts.description || ' ' || posts.content,
+        query,
+        'MaxFragments=2, MinWords=5, MaxWords=20, StartSel=**, StopSel=**'
+    )::text AS snippet
+FROM posts
+INNER JOIN feeds ON feeds.id = posts.feed_id
+CROSS JOIN websearch_to_tsquery('english', $1) AS query
+WHERE posts.search_vector @@ query
+AND (NOT $2::bool OR EXISTS (
+    SELECT 1 FROM feed_follows
+    WHERE feed_follows.feed_id = posts.feed_id AND feed_follows.user_id = $3
+))
+AND ($4::timestamp IS NULL OR COALESCE(posts.published_at, posts.created_at) >= $4::timestamp)
+AND ($5::timestamp IS NULL OR COALESCE(posts.published_at, posts.created_at) < $5::timestamp)
+ORDER BY rank DESC, posts.published_at DESC
+LIMIT $6
+`
+
+type SearchPostsParams struct {
+	Query        string
+	FollowedOnly bool
+	UserID       uuid.UUID
+	Since        sql.NullTime
+	Until        sql.NullTime
+	RowLimit     int32
+}
+
+type SearchPostsRow struct {
+	ID          uuid.UUID
+	Title       string
+	Url         string
+	PublishedAt sql.NullTime
+	FeedName    string
+	Rank        float32
+	Snippet     string
+}
+
+func (q *Queries) SearchPosts(ctx context.Context, arg SearchPostsParams) ([]SearchPostsRow, error) {
+	rows, err := q.db.QueryContext(ctx, searchPosts,
+		arg.Query,
+		arg.FollowedOnly,
+		arg.UserID,
+		arg.Since,
+		arg.Until,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchPostsRow
+	for rows.Next() {
+		var i SearchPostsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.Url,
+			&i.PublishedAt,
+			&i.FeedName,
+			&i.Rank,
+			&i.Snippet,
 		); err != nil {
 			return nil, err
 		}
@@ -96,3 +441,39 @@ func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams
 	}
 	return items, nil
 }
+
+const updatePost = `-- name: UpdatePost :execrows
+UPDATE posts
+SET title = $3, description = $4, published_at = $5, content = $6, author = $7, categories = $8, updated_at = NOW()
+WHERE url = $1 AND feed_id = $2
+AND (title, description, published_at, content, author, categories) IS DISTINCT FROM ($3, $4, $5, $6, $7, $8)
+`
+
+type UpdatePostParams struct {
+	Url         string
+	FeedID      uuid.UUID
+	Title       string
+	Description string
+	PublishedAt sql.NullTime
+	Content     string
+	Author      string
+	Categories  []string
+}
+
+// Refreshes a feed's post from its item, if the item has changed since
+func (q *Queries) UpdatePost(ctx context.Context, arg UpdatePostParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, updatePost,
+		arg.Url,
+		arg.FeedID,
+		arg.Title,
+		arg.Description,
+		arg.PublishedAt,
+		arg.Content,
+		arg.Author,
+		pq.Array(arg.Categories),
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
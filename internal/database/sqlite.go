@@ -0,0 +1,542 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// Runs the sqlc-generated queries against a SQLite database. Each query's
+// SQL is swapped for the query of the same name in sql/sqlite/queries, and
+// its arguments converted to the way SQLite stores them.
+type SQLiteQueries struct {
+	*Queries
+	db   sqliteDB
+	path string
+}
+
+// The queries implemented in Go rather than SQL
+var sqliteGoQueries = map[string]bool{
+	"AdvisoryUnlock":   true,
+	"CheckFilterRegex": true,
+	"GetUserBySession": true,
+	"TryAdvisoryLock":  true,
+}
+
+// Queries against db, the SQLite database file at path. queries holds the
+// .sql files of sql/sqlite/queries, which must have every query Querier does.
+func NewSQLite(db DBTX, path string, queries fs.FS) (*SQLiteQueries, error) {
+	sqls, err := readSQLiteQueries(queries)
+	if err != nil {
+		return nil, err
+	}
+
+	querier := reflect.TypeOf((*Querier)(nil)).Elem()
+	for i := 0; i < querier.NumMethod(); i++ {
+		name := querier.Method(i).Name
+		if _, ok := sqls[name]; !ok && !sqliteGoQueries[name] {
+			return nil, fmt.Errorf("Query `%s` has no SQLite version", name)
+		}
+	}
+
+	return newSQLite(sqliteDB{db: db, queries: sqls}, path), nil
+}
+
+func newSQLite(db sqliteDB, path string) *SQLiteQueries {
+	return &SQLiteQueries{Queries: New(db), db: db, path: path}
+}
+
+// The same queries run through db instead
+func (q *SQLiteQueries) with(db DBTX) Store {
+	return newSQLite(sqliteDB{db: db, queries: q.db.queries}, q.path)
+}
+
+// Shadows Queries.WithTx, which would run the Postgres SQL
+func (q *SQLiteQueries) WithTx(tx *sql.Tx) Store {
+	return q.with(tx)
+}
+
+func (q *SQLiteQueries) InTx(ctx context.Context, fn func(q Store) error) error {
+	return inTx(ctx, q.db.db, q.with, fn)
+}
+
+func (q *SQLiteQueries) Conn(ctx context.Context) (Store, func(), error) {
+	return conn(ctx, q.db.db, q.with)
+}
+
+// The name of a query, from its `-- name: ` header
+func queryName(sql string) string {
+	header, _, _ := strings.Cut(strings.TrimPrefix(sql, "-- name: "), "\n")
+	name, _, _ := strings.Cut(header, " ")
+	return name
+}
+
+// The queries in the .sql files of queries, by name
+func readSQLiteQueries(queries fs.FS) (map[string]string, error) {
+	paths, err := fs.Glob(queries, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, errors.New("No SQLite query files found")
+	}
+
+	sqls := map[string]string{}
+	for _, path := range paths {
+		data, err := fs.ReadFile(queries, path)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read `%s`: %v", path, err)
+		}
+
+		blocks := strings.Split(string(data), "-- name: ")
+		for _, block := range blocks[1:] {
+			sql := "-- name: " + block
+			name := queryName(sql)
+			if _, ok := sqls[name]; ok {
+				return nil, fmt.Errorf("Query `%s` is defined twice", name)
+			}
+			sqls[name] = sql
+		}
+	}
+
+	return sqls, nil
+}
+
+// Passes each sqlc-generated query on to db as its SQLite version
+type sqliteDB struct {
+	db      DBTX
+	queries map[string]string
+}
+
+// NewSQLite checked every query has a SQLite version, so only the queries
+// SQLiteQueries runs by name alone can be missing, and those are there too
+func (db sqliteDB) query(query string, args []interface{}) (string, []interface{}) {
+	if sql, ok := db.queries[queryName(query)]; ok {
+		query = sql
+	}
+
+	converted := make([]interface{}, len(args))
+	for i, arg := range args {
+		converted[i] = sqliteArg(arg)
+	}
+
+	return query, converted
+}
+
+func (db sqliteDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	query, args = db.query(query, args)
+	return db.db.ExecContext(ctx, query, args...)
+}
+
+func (db sqliteDB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	query, _ = db.query(query, nil)
+	return db.db.PrepareContext(ctx, query)
+}
+
+func (db sqliteDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	query, args = db.query(query, args)
+	return db.db.QueryContext(ctx, query, args...)
+}
+
+func (db sqliteDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	query, args = db.query(query, args)
+	return db.db.QueryRowContext(ctx, query, args...)
+}
+
+// SQLite's full-text search reads FTS5 queries rather than web search ones
+func (q *SQLiteQueries) SearchPosts(ctx context.Context, arg SearchPostsParams) ([]SearchPostsRow, error) {
+	arg.Query = ftsQuery(arg.Query)
+	return q.Queries.SearchPosts(ctx, arg)
+}
+
+// SQLite can't return rows of another table from an UPDATE, so the session is
+// touched first and its user read after
+func (q *SQLiteQueries) GetUserBySession(ctx context.Context, tokenHash string) (User, error) {
+	var userID uuid.UUID
+	err := q.db.QueryRowContext(ctx, "-- name: TouchSession :one", tokenHash).Scan(&userID)
+	if err != nil {
+		return User{}, err
+	}
+
+	row := q.db.QueryRowContext(ctx, "-- name: GetUserByID :one", userID)
+	var i User
+	err = row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.PasswordHash,
+		&i.Role,
+	)
+	return i, err
+}
+
+// SQLite has no advisory locks, so they're taken with flock on a file beside
+// the database, one per key. Every process using the database sees them, and
+// the kernel releases them if the process dies.
+var sqliteLocks = struct {
+	sync.Mutex
+	files map[string]*os.File
+}{files: map[string]*os.File{}}
+
+func (q *SQLiteQueries) lockPath(key int64) string {
+	return fmt.Sprintf("%s.lock-%x", q.path, key)
+}
+
+func (q *SQLiteQueries) TryAdvisoryLock(ctx context.Context, key int64) (bool, error) {
+	sqliteLocks.Lock()
+	defer sqliteLocks.Unlock()
+
+	path := q.lockPath(key)
+	if sqliteLocks.files[path] != nil {
+		return false, nil
+	}
+
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return false, fmt.Errorf("Failed to open lock file `%s`: %v", path, err)
+	}
+
+	err = syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if errors.Is(err, syscall.EWOULDBLOCK) {
+		f.Close()
+		return false, nil
+	}
+	if err != nil {
+		f.Close()
+		return false, fmt.Errorf("Failed to lock `%s`: %v", path, err)
+	}
+
+	sqliteLocks.files[path] = f
+
+	return true, nil
+}
+
+// Closing the file releases the flock. The file itself is left in place, as
+// removing it could let two processes lock different files of the same name.
+func (q *SQLiteQueries) AdvisoryUnlock(ctx context.Context, key int64) (bool, error) {
+	sqliteLocks.Lock()
+	defer sqliteLocks.Unlock()
+
+	path := q.lockPath(key)
+	f := sqliteLocks.files[path]
+	if f == nil {
+		return false, nil
+	}
+
+	delete(sqliteLocks.files, path)
+
+	return true, f.Close()
+}
+
+// Times are stored as UTC text in the format of SQLite's
+// strftime('%Y-%m-%d %H:%M:%f'), so they sort and compare as strings
+const sqliteTimeFormat = "2006-01-02 15:04:05.000"
+
+// Formats written by other tools
+var sqliteTimeFormats = []string{
+	sqliteTimeFormat,
+	"2006-01-02 15:04:05.999999999-07:00",
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05",
+	time.DateOnly,
+}
+
+func parseSQLiteTime(s string) (time.Time, error) {
+	for _, layout := range sqliteTimeFormats {
+		t, err := time.Parse(layout, s)
+		if err == nil {
+			return t.UTC(), nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("Invalid time `%s`", s)
+}
+
+// Converts an argument of a sqlc-generated query to the way SQLite stores it:
+// times as text, and pq.Array arrays as JSON, with a nil slice NULL
+func sqliteArg(arg interface{}) interface{} {
+	switch v := arg.(type) {
+	case time.Time:
+		return v.UTC().Format(sqliteTimeFormat)
+	case sql.NullTime:
+		if !v.Valid {
+			return nil
+		}
+		return v.Time.UTC().Format(sqliteTimeFormat)
+	case *pq.StringArray:
+		if *v == nil {
+			return nil
+		}
+		return sqliteJSON{v: []string(*v)}
+	case pq.GenericArray:
+		a := reflect.ValueOf(v.A)
+		if a.Kind() == reflect.Slice && a.IsNil() {
+			return nil
+		}
+		return sqliteJSON{v: v.A}
+	}
+
+	return arg
+}
+
+type sqliteJSON struct {
+	v interface{}
+}
+
+func (j sqliteJSON) Value() (driver.Value, error) {
+	data, err := json.Marshal(j.v)
+	if err != nil {
+		return nil, err
+	}
+
+	return string(data), nil
+}
+
+// The columns holding arrays, stored as JSON
+var sqliteArrayColumns = map[string]bool{
+	"args":       true,
+	"categories": true,
+	"tags":       true,
+}
+
+// Converts a value SQLite returned for column to what the sqlc-generated code
+// scans from Postgres: a time for a column named *_at, and an array literal
+// for pq.Array to scan for one holding an array
+func sqliteValue(column string, v driver.Value) (driver.Value, error) {
+	var text string
+	switch v := v.(type) {
+	case string:
+		text = v
+	case []byte:
+		text = string(v)
+	default:
+		return v, nil
+	}
+
+	switch {
+	case strings.HasSuffix(column, "_at"):
+		return parseSQLiteTime(text)
+	case sqliteArrayColumns[column]:
+		var a []string
+		err := json.Unmarshal([]byte(text), &a)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid array in `%s`: %v", column, err)
+		}
+		return pq.StringArray(a).Value()
+	}
+
+	return v, nil
+}
+
+// A group of words or phrases of a search, any of which matches it, or which
+// must all not match if it's excluded
+type searchClause struct {
+	terms    []string
+	excluded bool
+}
+
+// Reads a web search style query as Postgres' websearch_to_tsquery does:
+// every word or "quoted phrase" must match, `or` between two of them allows
+// either, and a leading - excludes one
+func searchClauses(query string) []searchClause {
+	clauses := []searchClause{}
+	or := false
+	for _, tok := range searchTokens(query) {
+		if !tok.phrase && strings.EqualFold(tok.text, "or") {
+			or = len(clauses) > 0
+			continue
+		}
+
+		excluded := false
+		text := tok.text
+		if !tok.phrase && strings.HasPrefix(text, "-") {
+			excluded = true
+			text = text[1:]
+		}
+		if tok.excluded {
+			excluded = true
+		}
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+
+		last := len(clauses) - 1
+		if or && !excluded && !clauses[last].excluded {
+			clauses[last].terms = append(clauses[last].terms, text)
+		} else {
+			clauses = append(clauses, searchClause{terms: []string{text}, excluded: excluded})
+		}
+		or = false
+	}
+
+	return clauses
+}
+
+// Turns a web search style query into the FTS5 query matching the same posts
+func ftsQuery(query string) string {
+	included := []string{}
+	excluded := []string{}
+	for _, c := range searchClauses(query) {
+		terms := make([]string, len(c.terms))
+		for i, term := range c.terms {
+			terms[i] = `"` + strings.ReplaceAll(term, `"`, `""`) + `"`
+		}
+
+		expr := terms[0]
+		if len(terms) > 1 {
+			expr = "(" + strings.Join(terms, " OR ") + ")"
+		}
+
+		if c.excluded {
+			excluded = append(excluded, expr)
+		} else {
+			included = append(included, expr)
+		}
+	}
+
+	// FTS5 can only exclude from something, so a query of only exclusions
+	// matches nothing
+	if len(included) == 0 {
+		return `""`
+	}
+
+	q := strings.Join(included, " ")
+	for _, e := range excluded {
+		q += " NOT " + e
+	}
+
+	return q
+}
+
+type searchToken struct {
+	text     string
+	phrase   bool
+	excluded bool
+}
+
+// Splits a query on whitespace, keeping "quoted phrases" together
+func searchTokens(query string) []searchToken {
+	tokens := []searchToken{}
+	for query != "" {
+		query = strings.TrimLeft(query, " \t\r\n")
+
+		excluded := false
+		rest := query
+		if strings.HasPrefix(rest, `-"`) {
+			excluded = true
+			rest = rest[1:]
+		}
+
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.Index(rest[1:], `"`)
+			if end < 0 {
+				tokens = append(tokens, searchToken{text: rest[1:], phrase: true, excluded: excluded})
+				break
+			}
+			tokens = append(tokens, searchToken{text: rest[1 : end+1], phrase: true, excluded: excluded})
+			query = rest[end+2:]
+			continue
+		}
+
+		end := strings.IndexAny(query, " \t\r\n")
+		if end < 0 {
+			end = len(query)
+		}
+		if end > 0 {
+			tokens = append(tokens, searchToken{text: query[:end]})
+		}
+		query = query[end:]
+	}
+
+	return tokens
+}
+
+var filterRegexps sync.Map
+
+// SQLite has no regexes of its own: filter_matches runs them with Go's
+// regexp, so that is what decides whether a pattern is valid
+func (q *SQLiteQueries) CheckFilterRegex(ctx context.Context, pattern string) error {
+	_, err := regexp.Compile("(?i)" + pattern)
+	return err
+}
+
+// Whether a post matches a filter pattern on one of its fields, as the
+// filter_matches function does on Postgres. Registered with the SQLite
+// driver under the same name; categories is the stored JSON array.
+func SQLiteFilterMatches(field, pattern string, isRegex bool, title, description, content, author, categories string) (bool, error) {
+	cats := []string{}
+	if categories != "" {
+		err := json.Unmarshal([]byte(categories), &cats)
+		if err != nil {
+			return false, fmt.Errorf("Invalid categories: %v", err)
+		}
+	}
+
+	return postMatches(field, pattern, isRegex, title, description, content, author, cats)
+}
+
+// The matching behind SQLiteFilterMatches, also used by MemoryStore
+func postMatches(field, pattern string, isRegex bool, title, description, content, author string, cats []string) (bool, error) {
+	values := []string{}
+	switch field {
+	case "title":
+		values = []string{title}
+	case "content":
+		values = []string{description, content}
+	case "author":
+		values = []string{author}
+	case "category":
+		values = cats
+	default:
+		values = append([]string{title, description, content, author}, cats...)
+	}
+
+	var re *regexp.Regexp
+	if isRegex {
+		cached, ok := filterRegexps.Load(pattern)
+		if ok {
+			re = cached.(*regexp.Regexp)
+		} else {
+			var err error
+			re, err = regexp.Compile("(?i)" + pattern)
+			if err != nil {
+				return false, err
+			}
+			filterRegexps.Store(pattern, re)
+		}
+	}
+
+	for _, v := range values {
+		switch {
+		case isRegex:
+			if re.MatchString(v) {
+				return true, nil
+			}
+		case field == "category":
+			if strings.EqualFold(v, pattern) {
+				return true, nil
+			}
+		default:
+			if strings.Contains(strings.ToLower(v), strings.ToLower(pattern)) {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
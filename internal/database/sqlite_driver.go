@@ -0,0 +1,176 @@
+//go:build sqlite
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+func init() {
+	sqlite.MustRegisterDeterministicScalarFunction("filter_matches", 8, filterMatches)
+}
+
+// Opens the SQLite database file at path, creating it if needed
+func OpenSQLite(path string) (*sql.DB, error) {
+	pragmas := url.Values{}
+	pragmas.Add("_pragma", "foreign_keys(1)")
+	pragmas.Add("_pragma", "busy_timeout(5000)")
+	pragmas.Add("_pragma", "journal_mode(wal)")
+	// Writers take the write lock up front rather than failing to upgrade
+	// to it halfway through a transaction
+	pragmas.Set("_txlock", "immediate")
+
+	connector, err := sqlite.NewConnector("file:" + path + "?" + pragmas.Encode())
+	if err != nil {
+		return nil, err
+	}
+
+	return sql.OpenDB(sqliteConnector{connector}), nil
+}
+
+// Whether err is SQLite refusing a row that would break a unique constraint
+func IsSQLiteUniqueViolation(err error) bool {
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+
+	code := sqliteErr.Code()
+	return code == sqlite3.SQLITE_CONSTRAINT_UNIQUE || code == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY
+}
+
+// Hands out connections whose rows are converted by sqliteValue
+type sqliteConnector struct {
+	driver.Connector
+}
+
+// What the driver's connections and statements implement
+type sqliteDriverConn interface {
+	driver.Conn
+	driver.ConnBeginTx
+	driver.ConnPrepareContext
+	driver.ExecerContext
+	driver.QueryerContext
+	driver.Pinger
+	driver.SessionResetter
+	driver.Validator
+}
+
+type sqliteDriverStmt interface {
+	driver.Stmt
+	driver.StmtExecContext
+	driver.StmtQueryContext
+}
+
+func (c sqliteConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sqliteConn, ok := conn.(sqliteDriverConn)
+	if !ok {
+		conn.Close()
+		return nil, fmt.Errorf("Unexpected SQLite connection %T", conn)
+	}
+
+	return sqliteConnection{sqliteConn}, nil
+}
+
+type sqliteConnection struct {
+	sqliteDriverConn
+}
+
+func (c sqliteConnection) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	stmt, err := c.sqliteDriverConn.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	sqliteStmt, ok := stmt.(sqliteDriverStmt)
+	if !ok {
+		stmt.Close()
+		return nil, fmt.Errorf("Unexpected SQLite statement %T", stmt)
+	}
+
+	return sqliteStatement{sqliteStmt}, nil
+}
+
+func (c sqliteConnection) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c sqliteConnection) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	rows, err := c.sqliteDriverConn.QueryContext(ctx, query, args)
+	if err != nil {
+		return nil, err
+	}
+
+	return newSQLiteRows(rows), nil
+}
+
+type sqliteStatement struct {
+	sqliteDriverStmt
+}
+
+func (s sqliteStatement) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	rows, err := s.sqliteDriverStmt.QueryContext(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+
+	return newSQLiteRows(rows), nil
+}
+
+type sqliteRows struct {
+	driver.Rows
+	columns []string
+}
+
+func newSQLiteRows(rows driver.Rows) *sqliteRows {
+	return &sqliteRows{Rows: rows, columns: rows.Columns()}
+}
+
+func (r *sqliteRows) Next(dest []driver.Value) error {
+	err := r.Rows.Next(dest)
+	if err != nil {
+		return err
+	}
+
+	for i, v := range dest {
+		dest[i], err = sqliteValue(r.columns[i], v)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func filterMatches(ctx *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+	strs := make([]string, len(args))
+	for i, arg := range args {
+		switch v := arg.(type) {
+		case nil:
+		case string:
+			strs[i] = v
+		case []byte:
+			strs[i] = string(v)
+		case int64:
+			strs[i] = fmt.Sprint(v)
+		default:
+			return nil, fmt.Errorf("filter_matches: unexpected argument %T", arg)
+		}
+	}
+
+	isRegex := args[2] == int64(1) || args[2] == true
+	return SQLiteFilterMatches(strs[0], strs[1], isRegex, strs[3], strs[4], strs[5], strs[6], strs[7])
+}
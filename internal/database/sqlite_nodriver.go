@@ -0,0 +1,18 @@
+//go:build !sqlite
+
+package database
+
+import (
+	"database/sql"
+	"errors"
+)
+
+// Builds without the sqlite tag leave out the SQLite driver, keeping the
+// default build to Postgres
+func OpenSQLite(path string) (*sql.DB, error) {
+	return nil, errors.New("gator was built without SQLite support: rebuild it with `go build -tags sqlite`")
+}
+
+func IsSQLiteUniqueViolation(err error) bool {
+	return false
+}
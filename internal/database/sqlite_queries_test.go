@@ -0,0 +1,113 @@
+package database
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+const sqliteQueriesDir = "../../sql/sqlite/queries"
+
+// The query constants sqlc generated from sql/queries, by name
+func readGeneratedQueries(t *testing.T) map[string]string {
+	t.Helper()
+
+	paths, err := filepath.Glob("*.sql.go")
+	if err != nil || len(paths) == 0 {
+		t.Fatalf("Failed to find generated queries: %v", err)
+	}
+
+	queries := map[string]string{}
+	fset := token.NewFileSet()
+	for _, path := range paths {
+		f, err := parser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			t.Fatalf("Failed to parse `%s`: %v", path, err)
+		}
+
+		ast.Inspect(f, func(n ast.Node) bool {
+			lit, ok := n.(*ast.BasicLit)
+			if !ok || lit.Kind != token.STRING {
+				return true
+			}
+			value, err := strconv.Unquote(lit.Value)
+			if err != nil || !strings.HasPrefix(value, "-- name: ") {
+				return true
+			}
+
+			queries[queryName(value)] = value
+			return true
+		})
+	}
+
+	return queries
+}
+
+var (
+	postgresParam = regexp.MustCompile(`\$(\d+)`)
+	sqliteParam   = regexp.MustCompile(`\?(\d+)`)
+)
+
+// The highest numbered parameter of a query
+func maxParam(re *regexp.Regexp, sql string) int {
+	max := 0
+	for _, m := range re.FindAllStringSubmatch(sql, -1) {
+		n, _ := strconv.Atoi(m[1])
+		if n > max {
+			max = n
+		}
+	}
+	return max
+}
+
+// The generated code passes arguments in the order of the Postgres query's
+// parameters, so its SQLite version must take as many
+func TestSQLiteQueriesMatchGenerated(t *testing.T) {
+	sqls, err := readSQLiteQueries(os.DirFS(sqliteQueriesDir))
+	if err != nil {
+		t.Fatalf("Failed to read SQLite queries: %v", err)
+	}
+
+	for name, query := range readGeneratedQueries(t) {
+		sql, ok := sqls[name]
+		if !ok {
+			if !sqliteGoQueries[name] {
+				t.Errorf("Query `%s` has no SQLite version in %s", name, sqliteQueriesDir)
+			}
+			continue
+		}
+
+		want := maxParam(postgresParam, query)
+		if got := maxParam(sqliteParam, sql); got != want {
+			t.Errorf("SQLite `%s` takes %d parameters, want %d", name, got, want)
+		}
+	}
+}
+
+func TestNewSQLite(t *testing.T) {
+	_, err := NewSQLite(nil, "", os.DirFS(sqliteQueriesDir))
+	if err != nil {
+		t.Fatalf("NewSQLite failed: %v", err)
+	}
+
+	dir := t.TempDir()
+	err = os.WriteFile(filepath.Join(dir, "users.sql"), []byte("-- name: GetUsers :many\nSELECT * FROM users;\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = NewSQLite(nil, "", os.DirFS(dir))
+	if err == nil || !strings.Contains(err.Error(), "has no SQLite version") {
+		t.Errorf("NewSQLite with missing queries returned %v", err)
+	}
+
+	_, err = NewSQLite(nil, "", os.DirFS(t.TempDir()))
+	if err == nil {
+		t.Error("NewSQLite with no query files succeeded")
+	}
+}
@@ -0,0 +1,292 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"os"
+	"path/filepath"
+	"slices"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+func newTestSQLite(t *testing.T, path string) *SQLiteQueries {
+	t.Helper()
+
+	q, err := NewSQLite(nil, path, os.DirFS(sqliteQueriesDir))
+	if err != nil {
+		t.Fatalf("NewSQLite failed: %v", err)
+	}
+	return q
+}
+
+func TestSQLiteAdvisoryLock(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "gator.db")
+
+	first := newTestSQLite(t, path)
+	second := newTestSQLite(t, path)
+
+	locked, err := first.TryAdvisoryLock(ctx, 42)
+	if err != nil || !locked {
+		t.Fatalf("First lock returned %v, %v; want it granted", locked, err)
+	}
+
+	locked, err = second.TryAdvisoryLock(ctx, 42)
+	if err != nil || locked {
+		t.Errorf("Second lock returned %v, %v; want it refused", locked, err)
+	}
+
+	locked, err = second.TryAdvisoryLock(ctx, 7)
+	if err != nil || !locked {
+		t.Errorf("Lock on another key returned %v, %v; want it granted", locked, err)
+	}
+	second.AdvisoryUnlock(ctx, 7)
+
+	unlocked, err := first.AdvisoryUnlock(ctx, 42)
+	if err != nil || !unlocked {
+		t.Fatalf("Unlock returned %v, %v", unlocked, err)
+	}
+
+	locked, err = second.TryAdvisoryLock(ctx, 42)
+	if err != nil || !locked {
+		t.Errorf("Lock after unlock returned %v, %v; want it granted", locked, err)
+	}
+	second.AdvisoryUnlock(ctx, 42)
+
+	unlocked, err = first.AdvisoryUnlock(ctx, 42)
+	if err != nil || unlocked {
+		t.Errorf("Unlocking a lock not held returned %v, %v", unlocked, err)
+	}
+}
+
+func TestSQLiteAdvisoryLockHeldElsewhere(t *testing.T) {
+	ctx := context.Background()
+	q := newTestSQLite(t, filepath.Join(t.TempDir(), "gator.db"))
+
+	// Stand in for another process holding the lock
+	f, err := os.OpenFile(q.lockPath(42), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		t.Fatalf("Failed to create lock file: %v", err)
+	}
+	err = syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if err != nil {
+		t.Fatalf("Failed to lock: %v", err)
+	}
+
+	locked, err := q.TryAdvisoryLock(ctx, 42)
+	if err != nil || locked {
+		t.Errorf("Lock held by another process returned %v, %v; want it refused", locked, err)
+	}
+
+	// The kernel drops the lock when its holder goes away
+	f.Close()
+
+	locked, err = q.TryAdvisoryLock(ctx, 42)
+	if err != nil || !locked {
+		t.Errorf("Lock after its holder closed returned %v, %v; want it granted", locked, err)
+	}
+	q.AdvisoryUnlock(ctx, 42)
+}
+
+func TestFTSQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{query: "go", want: `"go"`},
+		{query: "go rust", want: `"go" "rust"`},
+		{query: `"generic types" go`, want: `"generic types" "go"`},
+		{query: "go or rust", want: `("go" OR "rust")`},
+		{query: "go OR rust or zig", want: `("go" OR "rust" OR "zig")`},
+		{query: "go -java", want: `"go" NOT "java"`},
+		{query: `go -"null pointer"`, want: `"go" NOT "null pointer"`},
+		{query: "or go", want: `"go"`},
+		{query: "go or -java", want: `"go" NOT "java"`},
+		{query: `say "hi`, want: `"say" "hi"`},
+		{query: `quote"d`, want: `"quote""d"`},
+		{query: "-java", want: `""`},
+		{query: "   ", want: `""`},
+		{query: "- go", want: `"go"`},
+	}
+	for _, tt := range tests {
+		if got := ftsQuery(tt.query); got != tt.want {
+			t.Errorf("ftsQuery(%q) = %s, want %s", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestSearchTokens(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []searchToken
+	}{
+		{query: "", want: []searchToken{}},
+		{query: " go\trust\n", want: []searchToken{{text: "go"}, {text: "rust"}}},
+		{query: `"a b" c`, want: []searchToken{{text: "a b", phrase: true}, {text: "c"}}},
+		{query: `-"a b" -c`, want: []searchToken{{text: "a b", phrase: true, excluded: true}, {text: "-c"}}},
+		{query: `"open`, want: []searchToken{{text: "open", phrase: true}}},
+	}
+	for _, tt := range tests {
+		if got := searchTokens(tt.query); !slices.Equal(got, tt.want) {
+			t.Errorf("searchTokens(%q) = %+v, want %+v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestSQLiteArg(t *testing.T) {
+	local := time.FixedZone("UTC+2", 2*60*60)
+	at := time.Date(2024, time.February, 29, 23, 59, 58, 123_000_000, local)
+	id := uuid.MustParse("6ba7b810-9dad-11d1-80b4-00c04fd430c8")
+
+	tests := []struct {
+		arg  interface{}
+		want driver.Value
+	}{
+		{arg: at, want: "2024-02-29 21:59:58.123"},
+		{arg: sql.NullTime{Time: at, Valid: true}, want: "2024-02-29 21:59:58.123"},
+		{arg: sql.NullTime{}, want: nil},
+		{arg: pq.Array([]string{"go", `say "hi"`}), want: `["go","say \"hi\""]`},
+		{arg: pq.Array([]string{}), want: `[]`},
+		{arg: pq.Array([]string(nil)), want: nil},
+		{arg: pq.Array([]uuid.UUID{id}), want: `["6ba7b810-9dad-11d1-80b4-00c04fd430c8"]`},
+		{arg: pq.Array([]uuid.UUID(nil)), want: nil},
+		{arg: "go", want: "go"},
+		{arg: int32(7), want: int32(7)},
+	}
+	for _, tt := range tests {
+		got := sqliteArg(tt.arg)
+		if valuer, ok := got.(driver.Valuer); ok {
+			var err error
+			got, err = valuer.Value()
+			if err != nil {
+				t.Errorf("Value of %v failed: %v", tt.arg, err)
+				continue
+			}
+		}
+		if got != tt.want {
+			t.Errorf("sqliteArg(%v) = %v, want %v", tt.arg, got, tt.want)
+		}
+	}
+}
+
+func TestSQLiteValue(t *testing.T) {
+	at := time.Date(2024, time.February, 29, 21, 59, 58, 123_000_000, time.UTC)
+
+	// Times written by gator, by SQLite itself or by the driver
+	for _, src := range []driver.Value{"2024-02-29 21:59:58.123", []byte("2024-02-29T21:59:58.123Z"), "2024-02-29 23:59:58.123+02:00"} {
+		got, err := sqliteValue("created_at", src)
+		if err != nil || got != at {
+			t.Errorf("Converting %v gave %v, %v; want %v", src, got, err, at)
+		}
+	}
+	for _, src := range []driver.Value{"2024-02-29 21:59:58", "2024-02-29"} {
+		got, err := sqliteValue("published_at", src)
+		if tm, ok := got.(time.Time); err != nil || !ok || tm.Location() != time.UTC {
+			t.Errorf("Converting %v gave %v, %v; want a UTC time", src, got, err)
+		}
+	}
+	if _, err := sqliteValue("created_at", "last tuesday"); err == nil {
+		t.Error("Converting an invalid time succeeded")
+	}
+
+	tests := []struct {
+		column string
+		src    driver.Value
+		want   driver.Value
+	}{
+		{column: "categories", src: `["go","say \"hi\""]`, want: `{"go","say \"hi\""}`},
+		{column: "tags", src: []byte(`[]`), want: `{}`},
+		{column: "args", src: nil, want: nil},
+		{column: "updated_at", src: nil, want: nil},
+		{column: "title", src: "go", want: "go"},
+		{column: "priority", src: int64(3), want: int64(3)},
+	}
+	for _, tt := range tests {
+		got, err := sqliteValue(tt.column, tt.src)
+		if err != nil || got != tt.want {
+			t.Errorf("sqliteValue(%s, %v) = %v, %v; want %v", tt.column, tt.src, got, err, tt.want)
+		}
+	}
+
+	// The array literal is what pq.Array scans
+	value, err := sqliteValue("categories", `["go","say \"hi\"","a,b"]`)
+	if err != nil {
+		t.Fatalf("Converting an array failed: %v", err)
+	}
+	var scanned []string
+	err = pq.Array(&scanned).Scan(value)
+	if err != nil || !slices.Equal(scanned, []string{"go", `say "hi"`, "a,b"}) {
+		t.Errorf("Scanning %v gave %q, %v", value, scanned, err)
+	}
+
+	if _, err := sqliteValue("tags", "go"); err == nil {
+		t.Error("Converting an invalid array succeeded")
+	}
+}
+
+func TestSQLiteFilterMatches(t *testing.T) {
+	const (
+		title       = "Go 1.22 Released"
+		description = "Range over integers"
+		content     = "<p>Loop variables are per-iteration</p>"
+		author      = "The Go Team"
+		categories  = `["Releases","Programming Languages"]`
+	)
+
+	tests := []struct {
+		field   string
+		pattern string
+		isRegex bool
+		want    bool
+	}{
+		{field: "title", pattern: "released", want: true},
+		{field: "title", pattern: "integers", want: false},
+		{field: "content", pattern: "INTEGERS", want: true},
+		{field: "content", pattern: "per-iteration", want: true},
+		{field: "author", pattern: "go team", want: true},
+		{field: "category", pattern: "releases", want: true},
+		{field: "category", pattern: "release", want: false},
+		{field: "any", pattern: "programming", want: true},
+		{field: "any", pattern: "rust", want: false},
+		{field: "title", pattern: `^go 1\.\d+`, isRegex: true, want: true},
+		{field: "category", pattern: "^prog", isRegex: true, want: true},
+		{field: "author", pattern: "^go", isRegex: true, want: false},
+	}
+	for _, tt := range tests {
+		got, err := SQLiteFilterMatches(tt.field, tt.pattern, tt.isRegex, title, description, content, author, categories)
+		if err != nil || got != tt.want {
+			t.Errorf("Matching %s against %q (regex %v) = %v, %v; want %v", tt.field, tt.pattern, tt.isRegex, got, err, tt.want)
+		}
+	}
+
+	_, err := SQLiteFilterMatches("title", "(", true, title, description, content, author, categories)
+	if err == nil {
+		t.Error("Matching an invalid regex succeeded")
+	}
+
+	got, err := SQLiteFilterMatches("category", "releases", false, title, description, content, author, "")
+	if err != nil || got {
+		t.Errorf("Matching a post without categories = %v, %v", got, err)
+	}
+}
+
+func TestSQLiteCheckFilterRegex(t *testing.T) {
+	q := newTestSQLite(t, "")
+
+	for _, pattern := range []string{`^go 1\.\d+`, `(rust|zig)`, `\bai\b`} {
+		if err := q.CheckFilterRegex(context.Background(), pattern); err != nil {
+			t.Errorf("Valid regex %q was rejected: %v", pattern, err)
+		}
+	}
+	for _, pattern := range []string{`(`, `[a-`, `a**`} {
+		if err := q.CheckFilterRegex(context.Background(), pattern); err == nil {
+			t.Errorf("Invalid regex %q was accepted", pattern)
+		}
+	}
+}
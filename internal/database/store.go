@@ -0,0 +1,81 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// The queries gator runs, whichever database they run against
+type Store interface {
+	Querier
+	// Runs fn with the queries in one transaction, committed if fn returns
+	// nil and rolled back otherwise
+	InTx(ctx context.Context, fn func(q Store) error) error
+	// The queries on a connection of their own, e.g. to hold a session-level
+	// lock on, until release is called
+	Conn(ctx context.Context) (q Store, release func(), err error)
+}
+
+var _ Store = (*Queries)(nil)
+var _ Store = (*SQLiteQueries)(nil)
+
+// What InTx and Conn need of the database the queries run against
+type connPool interface {
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+	Conn(ctx context.Context) (*sql.Conn, error)
+}
+
+var errNotPool = errors.New("Queries aren't running against a connection pool")
+
+func (q *Queries) InTx(ctx context.Context, fn func(q Store) error) error {
+	return inTx(ctx, q.db, func(db DBTX) Store { return New(db) }, fn)
+}
+
+func (q *Queries) Conn(ctx context.Context) (Store, func(), error) {
+	return conn(ctx, q.db, func(db DBTX) Store { return New(db) })
+}
+
+func inTx(ctx context.Context, db DBTX, with func(db DBTX) Store, fn func(q Store) error) error {
+	pool, ok := db.(connPool)
+	if !ok {
+		return errNotPool
+	}
+
+	tx, err := pool.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("Failed to start transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	err = fn(with(tx))
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("Failed to commit transaction: %v", err)
+	}
+
+	return nil
+}
+
+func conn(ctx context.Context, db DBTX, with func(db DBTX) Store) (Store, func(), error) {
+	pool, ok := db.(connPool)
+	if !ok {
+		return nil, nil, errNotPool
+	}
+
+	c, err := pool.Conn(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Failed to get database connection: %v", err)
+	}
+
+	return with(c), func() { c.Close() }, nil
+}
+
+// Returned, wrapped, for a row that would break a unique constraint by
+// stores that have no driver error of their own to report
+var ErrUniqueViolation = errors.New("unique constraint violated")
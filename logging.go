@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"strings"
+
+	"github.com/matt-horst/blog-agg/internal/database"
+
+	"github.com/lib/pq"
+)
+
+var (
+	errUnexpectedStatus = errors.New("Unexpected response")
+	errFeedParse = errors.New("Failed to decode rss feed xml")
+)
+
+type logConfig struct {
+	level slog.Level
+	format string
+}
+
+func parseLogConfig(level string, format string) (logConfig, error) {
+	cfg := logConfig{level: slog.LevelInfo, format: "text"}
+
+	if level != "" {
+		err := cfg.level.UnmarshalText([]byte(level))
+		if err != nil {
+			return logConfig{}, fmt.Errorf("Invalid log level `%s`: %v", level, err)
+		}
+	}
+
+	if format != "" {
+		format = strings.ToLower(format)
+		if format != "text" && format != "json" {
+			return logConfig{}, fmt.Errorf("Invalid log format `%s`: must be text or json", format)
+		}
+		cfg.format = format
+	}
+
+	return cfg, nil
+}
+
+// Installs a default slog logger writing to w. Output from the standard log
+// package is routed through it as well.
+func setupLogging(w io.Writer, cfg logConfig) {
+	opts := &slog.HandlerOptions{Level: cfg.level}
+
+	var handler slog.Handler
+	if cfg.format == "json" {
+		handler = slog.NewJSONHandler(w, opts)
+	} else {
+		handler = slog.NewTextHandler(w, opts)
+	}
+
+	slog.SetDefault(slog.New(handler))
+}
+
+// Attributes for logging an error along with a coarse class that can be
+// indexed and alerted on
+func errAttrs(err error) slog.Attr {
+	return slog.Group("error",
+		slog.String("message", err.Error()),
+		slog.String("class", errorClass(err)),
+	)
+}
+
+func errorClass(err error) string {
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	var pqErr *pq.Error
+
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.As(err, &dnsErr):
+		return "dns"
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return "timeout"
+	case errors.As(err, &netErr):
+		return "network"
+	case errors.Is(err, errUnexpectedStatus):
+		return "http_status"
+	case errors.Is(err, errFeedParse):
+		return "parse"
+	case errors.Is(err, sql.ErrNoRows):
+		return "not_found"
+	case isUniqueViolation(err):
+		return "duplicate"
+	case errors.As(err, &pqErr):
+		return "database"
+	default:
+		return "other"
+	}
+}
+
+func feedLogger(feed database.Feed) *slog.Logger {
+	return slog.With(
+		slog.String("feed_id", feed.ID.String()),
+		slog.String("feed_url", feed.Url),
+	)
+}
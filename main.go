@@ -1,20 +1,36 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"flag"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 	"github.com/matt-horst/blog-agg/internal/config"
 	"github.com/matt-horst/blog-agg/internal/database"
 
 	_ "github.com/lib/pq"
+	"github.com/pressly/goose/v3"
 )
 
+// How long in-flight work may continue after SIGINT/SIGTERM before it is
+// cancelled
+const shutdownTimeout = 30 * time.Second
 
 type state struct {
 	cfg *config.Config
-	db *database.Queries
+	db database.Store
+	// The database under db, for migrations
+	sqlDB *sql.DB
+	dialect goose.Dialect
+	logCfg logConfig
+	// Alert notifications still being sent
+	notifications sync.WaitGroup
 }
 
 type command struct {
@@ -23,16 +39,16 @@ type command struct {
 }
 
 type commands struct {
-	handlers map[string]func (*state, command) error
+	handlers map[string]func (context.Context, *state, command) error
 }
 
-func (c *commands) run(s *state, cmd command) error {
+func (c *commands) run(ctx context.Context, s *state, cmd command) error {
 	handler, ok := c.handlers[cmd.name]
 	if !ok {
 		return fmt.Errorf("No such command `%s`\n", cmd.name)
 	}
 
-	err := handler(s, cmd)
+	err := handler(ctx, s, cmd)
 	if err != nil {
 		return fmt.Errorf("Failed to run handler for `%s`: %v", cmd.name, err)
 	}
@@ -40,51 +56,176 @@ func (c *commands) run(s *state, cmd command) error {
 	return nil
 }
 
-func (c *commands) register(name string, f func(*state, command) error) {
+func (c *commands) register(name string, f func(context.Context, *state, command) error) {
 	c.handlers[name] = f
 }
 
+// Returns a context that is only cancelled `grace` after ctx is, so work
+// started before a shutdown signal gets a chance to finish
+func withShutdownGrace(ctx context.Context, grace time.Duration) (context.Context, context.CancelFunc) {
+	graceCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	stop := context.AfterFunc(ctx, func() {
+		time.AfterFunc(grace, cancel)
+	})
+
+	return graceCtx, func() {
+		stop()
+		cancel()
+	}
+}
+
+func fatal(msg string, err error) {
+	slog.Error(msg, errAttrs(err))
+	os.Exit(1)
+}
+
 func main() {
-	cfg, err := config.Read()
+	logLevel := flag.String("log-level", "", "minimum log level: debug, info, warn or error (overrides log_level in config)")
+	logFormat := flag.String("log-format", "", "log output format: text or json (overrides log_format in config)")
+	profile := flag.String("profile", "", "config profile to use (overrides GATOR_PROFILE and the profile set with `profile use`)")
+	flag.Parse()
+
+	// The commands that manage the config file have to work without one, or
+	// with one that's invalid, since they are how it gets fixed
+	managesConfig := flag.Arg(0) == "config" || flag.Arg(0) == "profile"
+
+	readConfig := config.ReadProfile
+	if managesConfig {
+		readConfig = config.ReadProfileOrEmpty
+	}
+	cfg, err := readConfig(*profile)
+	if err != nil {
+		fatal("Failed to read config file", err)
+	}
+
+	if !managesConfig {
+		err = cfg.Validate()
+		if err != nil {
+			fatal(fmt.Sprintf("Invalid config for profile `%s`, fix it with `config set`", cfg.Profile()), err)
+		}
+	}
+
+	level := cfg.LogLevel
+	if *logLevel != "" {
+		level = *logLevel
+	}
+	format := cfg.LogFormat
+	if *logFormat != "" {
+		format = *logFormat
+	}
+	logCfg, err := parseLogConfig(level, format)
+	if err != nil && managesConfig {
+		// Leave the bad setting for `config validate` to report
+		logCfg, err = parseLogConfig(*logLevel, *logFormat)
+	}
 	if err != nil {
-		log.Fatalf("Failed to read config file: %v\n", err)
+		fatal("Invalid logging configuration", err)
 	}
+	setupLogging(os.Stderr, logCfg)
 
-	db, err := sql.Open("postgres", cfg.DbURL)
+	db, store, dialect, err := openDatabase(cfg.DbURL)
+	if err != nil && !managesConfig {
+		fatal("Failed to open database", err)
+	}
 	if err != nil {
-		log.Fatalf("Failed to open database: %v", err)
+		// Carry on without it, so the profile's db_url can still be fixed
+		slog.Warn("Failed to open database", "err", err)
 	}
 
 	s := &state{
 		cfg: &cfg,
-		db: database.New(db),
+		db: store,
+		sqlDB: db,
+		dialect: dialect,
+		logCfg: logCfg,
 	}
 
-	cmds := commands {handlers: make(map[string]func(*state, command) error)}
-	cmds.register("login", handlerLogin)
-	cmds.register("register", handlerRegister)
-	cmds.register("reset", handlerReset)
-	cmds.register("users", handlerUsers)
-	cmds.register("addfeed", middlewareLoggedIn(handlerAddFeed))
+	cmds := commands {handlers: make(map[string]func(context.Context, *state, command) error)}
+	cmds.register("login", middlewareAuditAnonymous(handlerLogin))
+	cmds.register("register", middlewareAuditAnonymous(handlerRegister))
+	cmds.register("logout", middlewareAuditAnonymous(handlerLogout))
+	cmds.register("passwd", middlewareLoggedIn(middlewareAudit(handlerPasswd)))
+	cmds.register("setpassword", middlewareAuditAnonymous(handlerSetPassword))
+	cmds.register("reset", middlewareLoggedIn(middlewareAudit(middlewareAdmin(handlerReset))))
+	cmds.register("users", middlewareLoggedIn(middlewareAdmin(handlerUsers)))
+	cmds.register("audit", middlewareLoggedIn(handlerAudit))
+	cmds.register("setrole", middlewareLoggedIn(middlewareAudit(middlewareAdmin(handlerSetRole))))
+	cmds.register("renameuser", middlewareLoggedIn(middlewareAudit(handlerRenameUser)))
+	cmds.register("deleteuser", middlewareLoggedIn(middlewareAudit(handlerDeleteUser)))
+	cmds.register("addfeed", middlewareLoggedIn(middlewareAudit(handlerAddFeed)))
 	cmds.register("feeds", handlerFeeds)
-	cmds.register("follow", middlewareLoggedIn(handlerFollow))
+	cmds.register("follow", middlewareLoggedIn(middlewareAudit(handlerFollow)))
 	cmds.register("following", middlewareLoggedIn(handlerFollowing))
-	cmds.register("unfollow", middlewareLoggedIn(handlerUnfollow))
+	cmds.register("unfollow", middlewareLoggedIn(middlewareAudit(handlerUnfollow)))
+	cmds.register("editfollow", middlewareLoggedIn(middlewareAudit(handlerEditFollow)))
+	cmds.register("renamefeed", middlewareLoggedIn(middlewareAudit(handlerRenameFeed)))
+	cmds.register("setfeedurl", middlewareLoggedIn(middlewareAudit(handlerSetFeedURL)))
+	cmds.register("pausefeed", middlewareLoggedIn(middlewareAudit(handlerPauseFeed)))
+	cmds.register("resumefeed", middlewareLoggedIn(middlewareAudit(handlerResumeFeed)))
+	cmds.register("deletefeed", middlewareLoggedIn(middlewareAudit(middlewareAdmin(handlerDeleteFeed))))
+	cmds.register("transferfeed", middlewareLoggedIn(middlewareAudit(handlerTransferFeed)))
 	cmds.register("agg", handlerAgg)
 	cmds.register("browse", middlewareLoggedIn(handlerBrowse))
-
-	if len(os.Args) < 2 {
-		log.Fatalf("Requires at least 2 args\n")
+	cmds.register("serve", handlerServe)
+	cmds.register("health", handlerHealth)
+	cmds.register("prune", middlewareLoggedIn(middlewareAudit(handlerPrune)))
+	cmds.register("retention", middlewareLoggedIn(middlewareAudit(handlerRetention)))
+	cmds.register("read", middlewareLoggedIn(middlewareAudit(handlerRead)))
+	cmds.register("unread", middlewareLoggedIn(middlewareAudit(handlerUnread)))
+	cmds.register("save", middlewareLoggedIn(middlewareAudit(handlerSave)))
+	cmds.register("unsave", middlewareLoggedIn(middlewareAudit(handlerUnsave)))
+	cmds.register("saved", middlewareLoggedIn(handlerSaved))
+	cmds.register("search", middlewareLoggedIn(handlerSearch))
+	cmds.register("addalert", middlewareLoggedIn(middlewareAudit(handlerAddAlert)))
+	cmds.register("alerts", middlewareLoggedIn(handlerAlerts))
+	cmds.register("removealert", middlewareLoggedIn(middlewareAudit(handlerRemoveAlert)))
+	cmds.register("alerted", middlewareLoggedIn(handlerAlerted))
+	cmds.register("addfilter", middlewareLoggedIn(middlewareAudit(handlerAddFilter)))
+	cmds.register("filters", middlewareLoggedIn(handlerFilters))
+	cmds.register("removefilter", middlewareLoggedIn(middlewareAudit(handlerRemoveFilter)))
+	cmds.register("previewfilter", middlewareLoggedIn(handlerPreviewFilter))
+	cmds.register("mkfolder", middlewareLoggedIn(middlewareAudit(handlerMkFolder)))
+	cmds.register("rmfolder", middlewareLoggedIn(middlewareAudit(handlerRmFolder)))
+	cmds.register("movefolder", middlewareLoggedIn(middlewareAudit(handlerMoveFolder)))
+	cmds.register("movefeed", middlewareLoggedIn(middlewareAudit(handlerMoveFeed)))
+	cmds.register("export", middlewareLoggedIn(handlerExport))
+	cmds.register("import", middlewareLoggedIn(middlewareAudit(handlerImport)))
+	cmds.register("profile", middlewareAuditSubcommands([]string{"use", "add", "remove"}, handlerProfile))
+	cmds.register("config", middlewareAuditSubcommands([]string{"set"}, handlerConfig))
+	cmds.register("migrate", middlewareAuditSubcommands([]string{"up", "down"}, handlerMigrate))
+
+	if flag.NArg() < 1 {
+		fmt.Fprintf(os.Stderr, "Usage: %s [flags] <command> [args...]\n", os.Args[0])
+		flag.PrintDefaults()
+		os.Exit(1)
 	}
 
-	name := os.Args[1]
-	args := os.Args[2:]
+	name := flag.Arg(0)
+	args := flag.Args()[1:]
 
 	cmd := command {name: name, args: args}
 
-	err = cmds.run(s, cmd)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		// Restore the default behavior so a second signal kills immediately
+		<-ctx.Done()
+		stop()
+	}()
+
+	if !skipSchemaCheck[cmd.name] {
+		err = checkSchemaVersion(ctx, s)
+		if err != nil {
+			stop()
+			closeDatabase(db)
+			fatal("Database schema check failed", err)
+		}
+	}
+
+	err = cmds.run(ctx, s, cmd)
+	stop()
+	closeDatabase(db)
 	if err != nil {
-		log.Fatalf("Error: %v\n", err)
+		fatal("Command failed", err)
 	}
 }
 
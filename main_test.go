@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/matt-horst/blog-agg/internal/config"
+	"github.com/matt-horst/blog-agg/internal/database"
+
+	"github.com/google/uuid"
+)
+
+func TestMain(m *testing.M) {
+	// Scrapes log every post; keep test output to failures
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	os.Exit(m.Run())
+}
+
+// A state backed by an empty in-memory store
+func newTestState(t *testing.T) *state {
+	t.Helper()
+
+	// Keep anything that does touch the config away from the real one
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	return &state{
+		cfg: &config.Config{},
+		db: database.NewMemory(),
+	}
+}
+
+func createTestUser(t *testing.T, s *state, name string) database.User {
+	t.Helper()
+
+	user, err := s.db.CreateUser(context.Background(), database.CreateUserParams{ID: uuid.New(), Name: name})
+	if err != nil {
+		t.Fatalf("Failed to create user `%s`: %v", name, err)
+	}
+
+	return user
+}
+
+// Adds a feed owned, and so followed, by user
+func createTestFeed(t *testing.T, s *state, user database.User, name, url string) database.Feed {
+	t.Helper()
+
+	_, err := runHandler(t, handlerAddFeed, s, user, name, url)
+	if err != nil {
+		t.Fatalf("Failed to add feed `%s`: %v", url, err)
+	}
+
+	feed, err := s.db.GetFeed(context.Background(), url)
+	if err != nil {
+		t.Fatalf("Failed to get feed `%s`: %v", url, err)
+	}
+
+	return feed
+}
+
+// Runs a logged-in handler as user, returning what it printed
+func runHandler(t *testing.T, handler func(context.Context, *state, command, database.User) error, s *state, user database.User, args ...string) (string, error) {
+	t.Helper()
+
+	return runNamedHandler(t, handler, s, user, "test", args...)
+}
+
+// Like runHandler, for handlers whose output or audit events name the command
+func runNamedHandler(t *testing.T, handler func(context.Context, *state, command, database.User) error, s *state, user database.User, name string, args ...string) (string, error) {
+	t.Helper()
+
+	var err error
+	out := captureStdout(t, func() {
+		err = handler(context.Background(), s, command{name: name, args: args}, user)
+	})
+
+	return out, err
+}
+
+func captureStdout(t *testing.T, f func()) string {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Failed to create pipe: %v", err)
+	}
+
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() {
+		os.Stdout = stdout
+	}()
+
+	// Read concurrently so a chatty handler can't fill the pipe and block
+	out := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		out <- string(data)
+	}()
+
+	f()
+	w.Close()
+
+	return <-out
+}
+
+type testItem struct {
+	title string
+	link string
+	description string
+	pubDate string
+	creator string
+	categories []string
+}
+
+// Renders an RSS 2.0 document, with atom:link elements for any hub and self
+// links
+func rssDocument(title, hub, self string, items ...testItem) string {
+	b := &strings.Builder{}
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	b.WriteString(`<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom" xmlns:dc="http://purl.org/dc/elements/1.1/">` + "\n")
+	fmt.Fprintf(b, "<channel>\n<title>%s</title>\n<link>https://example.com/</link>\n<description>Test feed</description>\n", title)
+	if hub != "" {
+		fmt.Fprintf(b, "<atom:link rel=\"hub\" href=\"%s\"/>\n", hub)
+	}
+	if self != "" {
+		fmt.Fprintf(b, "<atom:link rel=\"self\" href=\"%s\"/>\n", self)
+	}
+	for _, item := range items {
+		fmt.Fprintf(b, "<item>\n<title>%s</title>\n<link>%s</link>\n<description>%s</description>\n", item.title, item.link, item.description)
+		if item.pubDate != "" {
+			fmt.Fprintf(b, "<pubDate>%s</pubDate>\n", item.pubDate)
+		}
+		if item.creator != "" {
+			fmt.Fprintf(b, "<dc:creator>%s</dc:creator>\n", item.creator)
+		}
+		for _, c := range item.categories {
+			fmt.Fprintf(b, "<category>%s</category>\n", c)
+		}
+		b.WriteString("</item>\n")
+	}
+	b.WriteString("</channel>\n</rss>\n")
+
+	return b.String()
+}
+
+type testResponse struct {
+	status int
+	body string
+}
+
+// Serves canned responses by path, standing in for the feeds' real hosts
+type feedServer struct {
+	*httptest.Server
+
+	mu sync.Mutex
+	responses map[string]testResponse
+	hits map[string]int
+	userAgents []string
+}
+
+func newFeedServer(t *testing.T) *feedServer {
+	t.Helper()
+
+	fs := &feedServer{responses: map[string]testResponse{}, hits: map[string]int{}}
+	fs.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fs.mu.Lock()
+		fs.hits[r.URL.Path]++
+		fs.userAgents = append(fs.userAgents, r.UserAgent())
+		resp, ok := fs.responses[r.URL.Path]
+		fs.mu.Unlock()
+
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/rss+xml")
+		w.WriteHeader(resp.status)
+		io.WriteString(w, resp.body)
+	}))
+	t.Cleanup(fs.Close)
+
+	return fs
+}
+
+// Serves body at path and returns the URL it is served from
+func (fs *feedServer) serve(path string, status int, body string) string {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	fs.responses[path] = testResponse{status: status, body: body}
+
+	return fs.URL + path
+}
+
+func (fs *feedServer) hitsFor(path string) int {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	return fs.hits[path]
+}
+
+func rfc1123(t time.Time) string {
+	return t.UTC().Format(time.RFC1123Z)
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	metricFetches = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gator_feed_fetches_total",
+			Help: "Feed fetches by HTTP status code, or \"error\" when no response was received.",
+		},
+		[]string{"status"},
+	)
+	metricFetchDuration = promauto.NewHistogram(
+		prometheus.HistogramOpts{
+			Name: "gator_feed_fetch_duration_seconds",
+			Help: "Time taken to fetch and read a feed.",
+			Buckets: prometheus.ExponentialBuckets(0.05, 2, 10),
+		},
+	)
+	metricFetchBytes = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "gator_feed_fetch_bytes_total",
+			Help: "Bytes downloaded while fetching feeds.",
+		},
+	)
+	metricPosts = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gator_posts_total",
+			Help: "Feed items processed by outcome (inserted, updated, duplicate, failed).",
+		},
+		[]string{"result"},
+	)
+	metricParseFailures = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "gator_feed_parse_failures_total",
+			Help: "Feeds that could not be decoded.",
+		},
+	)
+	metricDateParseFailures = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "gator_date_parse_failures_total",
+			Help: "Item publication dates that matched no known format.",
+		},
+	)
+	metricLastSuccess = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "gator_last_successful_scrape_timestamp_seconds",
+			Help: "Unix time of the last scrape that completed without error.",
+		},
+	)
+)
+
+// Registers a gauge reporting how long ago the least recently fetched feed
+// was fetched (or created, if it never has been)
+func registerStalestFeedMetric(ctx context.Context, s *state) error {
+	return prometheus.Register(prometheus.NewGaugeFunc(
+		prometheus.GaugeOpts{
+			Name: "gator_stalest_feed_age_seconds",
+			Help: "Seconds since the least recently fetched feed was last fetched.",
+		},
+		func() float64 {
+			feed, err := s.db.GetNextFeedToFetch(ctx)
+			if err != nil {
+				return 0
+			}
+
+			fetchedAt := feed.CreatedAt
+			if feed.LastFetchedAt.Valid {
+				fetchedAt = feed.LastFetchedAt.Time
+			}
+
+			return time.Since(fetchedAt).Seconds()
+		},
+	))
+}
+
+func observeFetch(status int, start time.Time) {
+	label := "error"
+	if status != 0 {
+		label = strconv.Itoa(status)
+	}
+
+	metricFetches.WithLabelValues(label).Inc()
+	metricFetchDuration.Observe(time.Since(start).Seconds())
+}
+
+// Counts the bytes read through it, also adding them to the downloaded bytes
+// metric
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	metricFetchBytes.Add(float64(n))
+	return n, err
+}
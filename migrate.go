@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"embed"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"time"
+
+	"github.com/pressly/goose/v3"
+)
+
+//go:embed sql/schema/*.sql sql/sqlite/schema/*.sql
+var migrations embed.FS
+
+// Commands that work without an up-to-date schema, either because they don't
+// touch the database or because they change the schema themselves
+var skipSchemaCheck = map[string]bool{
+	"migrate": true,
+	"config": true,
+	"profile": true,
+}
+
+func newMigrator(s *state) (*goose.Provider, error) {
+	dir := "sql/schema"
+	if s.dialect == goose.DialectSQLite3 {
+		dir = "sql/sqlite/schema"
+	}
+
+	schema, err := fs.Sub(migrations, dir)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to load migrations: %v", err)
+	}
+
+	provider, err := goose.NewProvider(s.dialect, s.sqlDB, schema)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to load migrations: %v", err)
+	}
+
+	return provider, nil
+}
+
+// Fails unless the database schema is exactly the version this build was
+// written against, saying what to run to fix it
+func checkSchemaVersion(ctx context.Context, s *state) error {
+	err := s.sqlDB.PingContext(ctx)
+	if err != nil {
+		return fmt.Errorf("Failed to connect to the database: %v", err)
+	}
+
+	provider, err := newMigrator(s)
+	if err != nil {
+		return err
+	}
+
+	current, target, err := provider.GetVersions(ctx)
+	if err != nil {
+		return fmt.Errorf("Failed to check the database schema (if this is a new database, run `%s migrate up`): %v", os.Args[0], err)
+	}
+
+	if current < target {
+		return fmt.Errorf("Database schema is at version %d but this build needs %d: run `%s migrate up`", current, target, os.Args[0])
+	}
+	if current > target {
+		return fmt.Errorf("Database schema is at version %d, newer than this build's %d: upgrade gator, or run `%s migrate down --to %d` with the newer build", current, target, os.Args[0], target)
+	}
+
+	return nil
+}
+
+// Applies or rolls back the embedded schema migrations:
+//
+//	migrate up [--to version]
+//	migrate down [--to version]
+//	migrate status
+func handlerMigrate(ctx context.Context, s *state, cmd command) error {
+	if len(cmd.args) == 0 {
+		return fmt.Errorf("migrate requires a subcommand: up, down or status")
+	}
+
+	fs := flag.NewFlagSet("migrate "+cmd.args[0], flag.ContinueOnError)
+	to := fs.Int64("to", -1, "version to migrate to (up: default latest; down: default one step)")
+
+	positional, err := parseFlags(fs, cmd.args[1:])
+	if err != nil {
+		return err
+	}
+	if len(positional) != 0 {
+		return fmt.Errorf("migrate %s takes no arguments besides flags", cmd.args[0])
+	}
+
+	provider, err := newMigrator(s)
+	if err != nil {
+		return err
+	}
+
+	results := []*goose.MigrationResult{}
+	switch cmd.args[0] {
+	case "up":
+		if *to >= 0 {
+			results, err = provider.UpTo(ctx, *to)
+		} else {
+			results, err = provider.Up(ctx)
+		}
+
+	case "down":
+		if *to >= 0 {
+			results, err = provider.DownTo(ctx, *to)
+		} else {
+			var result *goose.MigrationResult
+			result, err = provider.Down(ctx)
+			if result != nil {
+				results = append(results, result)
+			}
+		}
+
+	case "status":
+		return printMigrationStatus(ctx, provider)
+
+	default:
+		return fmt.Errorf("Unknown migrate subcommand `%s`: expected up, down or status", cmd.args[0])
+	}
+
+	for _, r := range results {
+		fmt.Println(r)
+	}
+	if err != nil {
+		return fmt.Errorf("Migration failed: %v", err)
+	}
+
+	version, err := provider.GetDBVersion(ctx)
+	if err != nil {
+		return fmt.Errorf("Failed to get schema version: %v", err)
+	}
+	fmt.Printf("Database schema is at version %d\n", version)
+
+	return nil
+}
+
+func printMigrationStatus(ctx context.Context, provider *goose.Provider) error {
+	statuses, err := provider.Status(ctx)
+	if err != nil {
+		return fmt.Errorf("Failed to get migration status: %v", err)
+	}
+
+	for _, st := range statuses {
+		if st.State == goose.StateApplied {
+			fmt.Printf("* %s: applied %s\n", st.Source.Path, st.AppliedAt.Local().Format(time.DateTime))
+		} else {
+			fmt.Printf("* %s: pending\n", st.Source.Path)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/matt-horst/blog-agg/internal/database"
+
+	"github.com/google/uuid"
+)
+
+type opml struct {
+	XMLName xml.Name `xml:"opml"`
+	Version string `xml:"version,attr"`
+	Head opmlHead `xml:"head"`
+	Body opmlBody `xml:"body"`
+}
+
+type opmlHead struct {
+	Title string `xml:"title"`
+	DateCreated string `xml:"dateCreated,omitempty"`
+}
+
+type opmlBody struct {
+	Outlines []opmlOutline `xml:"outline"`
+}
+
+// An outline with an xmlUrl is a feed; any other outline is a folder
+type opmlOutline struct {
+	Text string `xml:"text,attr"`
+	Title string `xml:"title,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+	XMLURL string `xml:"xmlUrl,attr,omitempty"`
+	Description string `xml:"description,attr,omitempty"`
+	// The follow's priority, in gator's own namespace so other readers ignore
+	// it. Kept as text so a missing one can be told apart from 0.
+	Priority string `xml:"https://github.com/matt-horst/blog-agg priority,attr,omitempty"`
+	Outlines []opmlOutline `xml:"outline"`
+}
+
+func (o opmlOutline) name() string {
+	if o.Title != "" {
+		return o.Title
+	}
+	if o.Text != "" {
+		return o.Text
+	}
+
+	return o.XMLURL
+}
+
+// Writes the followed feeds as OPML, with folders as nested outlines
+func handlerExport(ctx context.Context, s *state, cmd command, user database.User) error {
+	if len(cmd.args) > 1 {
+		return fmt.Errorf("export takes at most one argument: file")
+	}
+
+	following, err := s.db.GetFeedFollowsForUser(ctx, user.Name)
+	if err != nil {
+		return fmt.Errorf("Failed to get followed feeds: %v", err)
+	}
+
+	t, err := loadFolders(ctx, s.db, user.ID)
+	if err != nil {
+		return err
+	}
+
+	byFolder := map[uuid.UUID][]database.GetFeedFollowsForUserRow{}
+	for _, f := range following {
+		byFolder[parentKey(f.FolderID)] = append(byFolder[parentKey(f.FolderID)], f)
+	}
+
+	doc := opml{
+		Version: "2.0",
+		Head: opmlHead{
+			Title: fmt.Sprintf("%s's feeds", user.Name),
+			DateCreated: time.Now().Format(time.RFC1123Z),
+		},
+		Body: opmlBody{Outlines: exportOutlines(t, byFolder, uuid.Nil)},
+	}
+
+	var w io.Writer = os.Stdout
+	if len(cmd.args) == 1 {
+		f, err := os.Create(cmd.args[0])
+		if err != nil {
+			return fmt.Errorf("Failed to create `%s`: %v", cmd.args[0], err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	_, err = io.WriteString(w, xml.Header)
+	if err != nil {
+		return fmt.Errorf("Failed to write OPML: %v", err)
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	err = enc.Encode(doc)
+	if err != nil {
+		return fmt.Errorf("Failed to write OPML: %v", err)
+	}
+	fmt.Fprintln(w)
+
+	return nil
+}
+
+func exportOutlines(t *folderTree, follows map[uuid.UUID][]database.GetFeedFollowsForUserRow, parent uuid.UUID) []opmlOutline {
+	outlines := []opmlOutline{}
+
+	for _, f := range t.children[parent] {
+		outlines = append(outlines, opmlOutline{Text: f.Name, Title: f.Name, Outlines: exportOutlines(t, follows, f.ID)})
+	}
+
+	for _, f := range follows[parent] {
+		o := opmlOutline{Text: f.FeedName, Title: f.FeedName, Type: "rss", XMLURL: f.FeedUrl, Description: f.Notes}
+		if f.Priority != 0 {
+			o.Priority = strconv.Itoa(int(f.Priority))
+		}
+		outlines = append(outlines, o)
+	}
+
+	return outlines
+}
+
+// Follows every feed in an OPML file, adding feeds that don't exist yet and
+// filing them in the file's folders. Feeds already followed are moved.
+func handlerImport(ctx context.Context, s *state, cmd command, user database.User) error {
+	if len(cmd.args) != 1 {
+		return fmt.Errorf("import requires an OPML file")
+	}
+
+	f, err := os.Open(cmd.args[0])
+	if err != nil {
+		return fmt.Errorf("Failed to open `%s`: %v", cmd.args[0], err)
+	}
+	defer f.Close()
+
+	doc := opml{}
+	err = xml.NewDecoder(f).Decode(&doc)
+	if err != nil {
+		return fmt.Errorf("Failed to parse OPML: %v", err)
+	}
+
+	imp := opmlImport{user: user}
+	err = s.db.InTx(ctx, func(q database.Store) error {
+		t, err := loadFolders(ctx, q, user.ID)
+		if err != nil {
+			return err
+		}
+
+		imp = opmlImport{q: q, user: user, folders: t}
+		return imp.outlines(ctx, doc.Body.Outlines, nil)
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Imported %d feeds (%d new)\n", imp.followed, imp.created)
+
+	return nil
+}
+
+type opmlImport struct {
+	q database.Store
+	user database.User
+	folders *folderTree
+	followed int
+	created int
+}
+
+// Imports the outlines into the folder reached by the names in path. Folder
+// names come from the file as they are, so one with a slash in it stays a
+// single folder.
+func (imp *opmlImport) outlines(ctx context.Context, outlines []opmlOutline, path []string) error {
+	folderID := uuid.NullUUID{}
+	if len(path) > 0 {
+		folder, err := ensureFolder(ctx, imp.q, imp.folders, imp.user.ID, path)
+		if err != nil {
+			return err
+		}
+		folderID = nullFolderID(folder)
+	}
+
+	for _, o := range outlines {
+		if o.XMLURL == "" {
+			// Some exporters nest the whole list in a single untitled outline
+			childPath := path
+			if name := strings.TrimSpace(o.name()); name != "" {
+				childPath = append(slices.Clip(path), name)
+			}
+
+			err := imp.outlines(ctx, o.Outlines, childPath)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		err := imp.feed(ctx, o, folderID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (imp *opmlImport) feed(ctx context.Context, o opmlOutline, folderID uuid.NullUUID) error {
+	feed, err := imp.q.GetFeed(ctx, o.XMLURL)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = validateFeedURL(o.XMLURL)
+		if err != nil {
+			return err
+		}
+
+		feed, err = imp.q.CreateFeed(
+			ctx,
+			database.CreateFeedParams{
+				ID: uuid.New(),
+				Name: o.name(),
+				Url: o.XMLURL,
+				UserID: imp.user.ID,
+			},
+		)
+		if err != nil {
+			return fmt.Errorf("Failed to create feed `%s`: %v", o.XMLURL, err)
+		}
+		imp.created++
+	}
+	if err != nil {
+		return fmt.Errorf("Unable to find feed `%s`: %v", o.XMLURL, err)
+	}
+
+	position, err := placeFeedFollow(ctx, imp.q, imp.user.ID, folderID, -1)
+	if err != nil {
+		return err
+	}
+
+	err = imp.q.UpsertFeedFollow(
+		ctx,
+		database.UpsertFeedFollowParams{
+			ID: uuid.New(),
+			UserID: imp.user.ID,
+			FeedID: feed.ID,
+			FolderID: folderID,
+			Position: position,
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("Failed to follow feed `%s`: %v", o.XMLURL, err)
+	}
+	imp.followed++
+
+	// Keep the file's naming and notes as this user's own, without renaming
+	// the feed for everyone else
+	follow, err := imp.q.GetFeedFollow(ctx, database.GetFeedFollowParams{UserID: imp.user.ID, FeedID: feed.ID})
+	if err != nil {
+		return fmt.Errorf("Failed to follow feed `%s`: %v", o.XMLURL, err)
+	}
+
+	title := follow.Title
+	if o.name() != feed.Name {
+		title = o.name()
+	}
+	notes := follow.Notes
+	if o.Description != "" {
+		notes = o.Description
+	}
+	priority := follow.Priority
+	if o.Priority != "" {
+		n, err := strconv.ParseInt(o.Priority, 10, 32)
+		if err != nil {
+			return fmt.Errorf("Invalid priority `%s` for feed `%s`", o.Priority, o.XMLURL)
+		}
+		priority = int32(n)
+	}
+
+	if title != follow.Title || notes != follow.Notes || priority != follow.Priority {
+		err = imp.q.UpdateFeedFollowDetails(
+			ctx,
+			database.UpdateFeedFollowDetailsParams{
+				UserID: imp.user.ID,
+				FeedID: feed.ID,
+				Title: title,
+				Notes: notes,
+				Priority: priority,
+			},
+		)
+		if err != nil {
+			return fmt.Errorf("Failed to update feed-follow for `%s`: %v", o.XMLURL, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func TestOPMLPriority(t *testing.T) {
+	doc := opml{
+		Version: "2.0",
+		Body: opmlBody{Outlines: []opmlOutline{
+			{Text: "Go", Type: "rss", XMLURL: "https://go.dev/blog/feed.atom", Priority: "3"},
+			{Text: "Rust", Type: "rss", XMLURL: "https://blog.rust-lang.org/feed.xml"},
+		}},
+	}
+
+	var buf bytes.Buffer
+	err := xml.NewEncoder(&buf).Encode(doc)
+	if err != nil {
+		t.Fatalf("Failed to encode: %v", err)
+	}
+	if strings.Count(buf.String(), "priority=") != 1 {
+		t.Errorf("Encoded %s, want a priority on the first outline only", buf.String())
+	}
+
+	decoded := opml{}
+	err = xml.Unmarshal(buf.Bytes(), &decoded)
+	if err != nil {
+		t.Fatalf("Failed to decode: %v", err)
+	}
+	if got := decoded.Body.Outlines; len(got) != 2 || got[0].Priority != "3" || got[1].Priority != "" {
+		t.Errorf("Decoded outlines %+v", got)
+	}
+
+	// Any prefix for the namespace will do, and the plain attribute belongs to
+	// someone else
+	other := `<opml version="2.0" xmlns:g="https://github.com/matt-horst/blog-agg"><body>
+		<outline text="Go" xmlUrl="https://go.dev/blog/feed.atom" g:priority="-1"></outline>
+		<outline text="Rust" xmlUrl="https://blog.rust-lang.org/feed.xml" priority="5"></outline>
+	</body></opml>`
+	decoded = opml{}
+	err = xml.Unmarshal([]byte(other), &decoded)
+	if err != nil {
+		t.Fatalf("Failed to decode: %v", err)
+	}
+	if got := decoded.Body.Outlines; len(got) != 2 || got[0].Priority != "-1" || got[1].Priority != "" {
+		t.Errorf("Decoded outlines %+v", got)
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/matt-horst/blog-agg/internal/config"
+)
+
+// Lists config profiles, or switches, adds or removes one:
+//
+//	profile
+//	profile use <name>
+//	profile add <name> <db_url>
+//	profile remove <name>
+func handlerProfile(ctx context.Context, s *state, cmd command) error {
+	if len(cmd.args) == 0 {
+		return printProfiles(s)
+	}
+
+	switch sub, args := cmd.args[0], cmd.args[1:]; sub {
+	case "use":
+		if len(args) != 1 {
+			return fmt.Errorf("profile use requires a profile name")
+		}
+		err := config.UseProfile(args[0])
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Now using profile `%s`\n", args[0])
+		if env := os.Getenv("GATOR_PROFILE"); env != "" && env != args[0] {
+			fmt.Printf("Note: GATOR_PROFILE=%s overrides this until it is unset\n", env)
+		}
+
+	case "add":
+		if len(args) != 2 {
+			return fmt.Errorf("profile add requires two arguments: name db_url")
+		}
+		err := config.AddProfile(args[0], args[1])
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Added profile `%s`; switch to it with `profile use %s`\n", args[0], args[0])
+
+	case "remove":
+		if len(args) != 1 {
+			return fmt.Errorf("profile remove requires a profile name")
+		}
+		err := config.RemoveProfile(args[0])
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Removed profile `%s`\n", args[0])
+
+	default:
+		return fmt.Errorf("Unknown profile subcommand `%s`: expected use, add or remove", sub)
+	}
+
+	return nil
+}
+
+func printProfiles(s *state) error {
+	names, err := config.Profiles()
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		current := ""
+		if name == s.cfg.Profile() {
+			current = " (current)"
+		}
+
+		fmt.Printf("* %s%s\n", name, current)
+	}
+
+	return nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/matt-horst/blog-agg/internal/config"
+)
+
+func TestHandlerProfile(t *testing.T) {
+	s := newTestState(t)
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("GATOR_PROFILE", "")
+
+	_, err := s.cfg.Set("db_url", "postgres://localhost/personal")
+	if err != nil {
+		t.Fatalf("Failed to create config: %v", err)
+	}
+
+	profile := func(args ...string) string {
+		t.Helper()
+
+		var err error
+		out := captureStdout(t, func() {
+			err = handlerProfile(context.Background(), s, command{name: "profile", args: args})
+		})
+		if err != nil {
+			t.Fatalf("profile %q failed: %v", args, err)
+		}
+		return out
+	}
+
+	if out := profile("add", "team", "postgres://db.example.com/team"); out != "Added profile `team`; switch to it with `profile use team`\n" {
+		t.Errorf("profile add printed %q", out)
+	}
+	if out := profile(); out != "* default (current)\n* team\n" {
+		t.Errorf("profile printed %q", out)
+	}
+
+	t.Setenv("GATOR_PROFILE", "default")
+	if out := profile("use", "team"); out != "Now using profile `team`\nNote: GATOR_PROFILE=default overrides this until it is unset\n" {
+		t.Errorf("profile use printed %q", out)
+	}
+	t.Setenv("GATOR_PROFILE", "")
+
+	cfg, err := config.Read()
+	if err != nil {
+		t.Fatalf("Failed to read config: %v", err)
+	}
+	if cfg.Profile() != "team" || cfg.DbURL != "postgres://db.example.com/team" {
+		t.Errorf("Read profile `%s` with db_url %q after switching to `team`", cfg.Profile(), cfg.DbURL)
+	}
+
+	profile("remove", "team")
+	cfg, err = config.Read()
+	if err != nil {
+		t.Fatalf("Failed to read config: %v", err)
+	}
+	if cfg.Profile() != config.DefaultProfile {
+		t.Errorf("Read profile `%s` after removing the current one", cfg.Profile())
+	}
+
+	for _, args := range [][]string{
+		{"use"},
+		{"use", "staging"},
+		{"add", "team"},
+		{"remove", "default"},
+		{"rename", "team"},
+	} {
+		err := runCommand(t, handlerProfile, s, "profile", args...)
+		if err == nil {
+			t.Errorf("profile %q succeeded", args)
+		}
+	}
+}
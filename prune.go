@@ -0,0 +1,253 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/matt-horst/blog-agg/internal/database"
+
+	"github.com/google/uuid"
+)
+
+// Posts deleted per statement, so pruning a large backlog doesn't hold locks
+// on millions of rows at once
+const defaultPruneBatchSize = 5000
+
+// The configured default retention as query parameters
+func defaultRetention(s *state) (sql.NullInt32, sql.NullInt32) {
+	keepLast := sql.NullInt32{}
+	keepDays := sql.NullInt32{}
+
+	if s.cfg.Retention != nil {
+		if s.cfg.Retention.KeepLast > 0 {
+			keepLast = sql.NullInt32{Int32: int32(s.cfg.Retention.KeepLast), Valid: true}
+		}
+		if s.cfg.Retention.KeepDays > 0 {
+			keepDays = sql.NullInt32{Int32: int32(s.cfg.Retention.KeepDays), Valid: true}
+		}
+	}
+
+	return keepLast, keepDays
+}
+
+// The posts prunePosts deleted from one feed
+type prunedFeed struct {
+	name string
+	posts int
+}
+
+// Deletes posts no retention policy keeps, in batches, returning what was
+// deleted per feed. Saved posts are never deleted.
+func prunePosts(ctx context.Context, s *state, feedID uuid.NullUUID, batchSize int) (map[uuid.UUID]prunedFeed, error) {
+	keepLast, keepDays := defaultRetention(s)
+	deleted := map[uuid.UUID]prunedFeed{}
+
+	for {
+		rows, err := s.db.PrunePosts(
+			ctx,
+			database.PrunePostsParams{
+				KeepLast: keepLast,
+				KeepDays: keepDays,
+				FeedID: feedID,
+				BatchSize: int32(batchSize),
+			},
+		)
+		if err != nil {
+			return deleted, fmt.Errorf("Failed to prune posts: %w", err)
+		}
+
+		for _, row := range rows {
+			pruned := deleted[row.FeedID]
+			pruned.name = row.FeedName
+			pruned.posts++
+			deleted[row.FeedID] = pruned
+		}
+
+		if len(rows) < batchSize {
+			return deleted, nil
+		}
+	}
+}
+
+// Run periodically by agg when started with --prune-posts
+func prunePostsForAgg(ctx context.Context, s *state) error {
+	deleted, err := prunePosts(ctx, s, uuid.NullUUID{}, defaultPruneBatchSize)
+
+	total := 0
+	for _, pruned := range deleted {
+		total += pruned.posts
+	}
+	if total > 0 {
+		slog.Info("Pruned posts", slog.Int("posts", total), slog.Int("feeds", len(deleted)))
+	}
+
+	return err
+}
+
+// Deletes posts outside the retention policies. Pruning every feed affects
+// every user, so only admins may; with --feed, whoever may manage that feed.
+func handlerPrune(ctx context.Context, s *state, cmd command, user database.User) error {
+	fs := flag.NewFlagSet("prune", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "only report what would be deleted")
+	yes := fs.Bool("yes", false, "don't ask for confirmation")
+	feedURL := fs.String("feed", "", "only prune the feed with this URL")
+	batchSize := fs.Int("batch-size", defaultPruneBatchSize, "posts to delete per statement")
+
+	positional, err := parseFlags(fs, cmd.args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 0 {
+		return fmt.Errorf("prune takes no arguments besides flags")
+	}
+	if *batchSize <= 0 {
+		return fmt.Errorf("--batch-size must be positive")
+	}
+
+	feedID := uuid.NullUUID{}
+	if *feedURL != "" {
+		feed, err := s.db.GetFeed(ctx, *feedURL)
+		if err != nil {
+			return fmt.Errorf("Unable to find feed `%s`: %v", *feedURL, err)
+		}
+		if !canManageFeed(user, feed) {
+			return fmt.Errorf("Only the user who added `%s` or an admin can prune it", feed.Name)
+		}
+		feedID = uuid.NullUUID{UUID: feed.ID, Valid: true}
+	} else if !isAdmin(user) {
+		return fmt.Errorf("`%s` can only be run by an admin without --feed", cmd.name)
+	}
+
+	if *dryRun {
+		keepLast, keepDays := defaultRetention(s)
+		counts, err := s.db.CountPrunablePosts(ctx, database.CountPrunablePostsParams{KeepLast: keepLast, KeepDays: keepDays, FeedID: feedID})
+		if err != nil {
+			return fmt.Errorf("Failed to count prunable posts: %v", err)
+		}
+
+		total := int64(0)
+		for _, c := range counts {
+			fmt.Printf("* %s: %d posts\n", c.FeedName, c.Posts)
+			total += c.Posts
+		}
+		fmt.Printf("Would delete %d posts from %d feeds\n", total, len(counts))
+
+		return nil
+	}
+
+	action := "delete every post outside the retention policies"
+	if *feedURL != "" {
+		action = fmt.Sprintf("delete the posts of `%s` outside the retention policies", *feedURL)
+	}
+	err = confirm(action, "prune", *yes)
+	if err != nil {
+		return err
+	}
+
+	deleted, err := prunePosts(ctx, s, feedID, *batchSize)
+
+	// Report what was deleted even if a later batch failed
+	feeds := slices.Collect(maps.Values(deleted))
+	slices.SortFunc(feeds, func(a, b prunedFeed) int { return strings.Compare(a.name, b.name) })
+
+	total := 0
+	for _, pruned := range feeds {
+		fmt.Printf("* %s: %d posts\n", pruned.name, pruned.posts)
+		total += pruned.posts
+	}
+	fmt.Printf("Deleted %d posts from %d feeds\n", total, len(deleted))
+
+	return err
+}
+
+// Shows the retention policies, or sets or clears the policy of one feed
+func handlerRetention(ctx context.Context, s *state, cmd command, user database.User) error {
+	fs := flag.NewFlagSet("retention", flag.ContinueOnError)
+	keepLast := fs.Int("keep-last", 0, "keep the newest N posts of the feed")
+	keepDays := fs.Int("keep-days", 0, "keep posts newer than N days")
+	clear := fs.Bool("clear", false, "remove the feed's policy so the default applies")
+
+	positional, err := parseFlags(fs, cmd.args)
+	if err != nil {
+		return err
+	}
+
+	if len(positional) == 0 {
+		return printRetentionPolicies(ctx, s)
+	}
+	if len(positional) != 1 {
+		return fmt.Errorf("retention takes at most one argument: feed url")
+	}
+
+	feed, err := s.db.GetFeed(ctx, positional[0])
+	if err != nil {
+		return fmt.Errorf("Unable to find feed `%s`: %v", positional[0], err)
+	}
+	if !canManageFeed(user, feed) {
+		return fmt.Errorf("Only the user who added `%s` or an admin can change its retention", feed.Name)
+	}
+
+	if *clear {
+		_, err = s.db.DeleteFeedRetentionPolicy(ctx, feed.ID)
+		if err != nil {
+			return fmt.Errorf("Failed to clear retention for `%s`: %v", feed.Name, err)
+		}
+		return nil
+	}
+
+	if *keepLast < 0 || *keepDays < 0 {
+		return fmt.Errorf("--keep-last and --keep-days must not be negative")
+	}
+	if *keepLast == 0 && *keepDays == 0 {
+		return fmt.Errorf("retention requires --keep-last, --keep-days or --clear")
+	}
+
+	err = s.db.SetFeedRetentionPolicy(
+		ctx,
+		database.SetFeedRetentionPolicyParams{
+			FeedID: feed.ID,
+			KeepLast: sql.NullInt32{Int32: int32(*keepLast), Valid: *keepLast > 0},
+			KeepDays: sql.NullInt32{Int32: int32(*keepDays), Valid: *keepDays > 0},
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("Failed to set retention for `%s`: %v", feed.Name, err)
+	}
+
+	return nil
+}
+
+func printRetentionPolicies(ctx context.Context, s *state) error {
+	keepLast, keepDays := defaultRetention(s)
+	fmt.Printf("Default: %s\n", describeRetention(keepLast, keepDays))
+
+	policies, err := s.db.GetFeedRetentionPolicies(ctx)
+	if err != nil {
+		return fmt.Errorf("Failed to get retention policies: %v", err)
+	}
+
+	for _, p := range policies {
+		fmt.Printf("* %s (%s): %s\n", p.FeedName, p.FeedUrl, describeRetention(p.KeepLast, p.KeepDays))
+	}
+
+	return nil
+}
+
+func describeRetention(keepLast, keepDays sql.NullInt32) string {
+	switch {
+	case keepLast.Valid && keepDays.Valid:
+		return fmt.Sprintf("keep the last %d posts and posts from the last %d days", keepLast.Int32, keepDays.Int32)
+	case keepLast.Valid:
+		return fmt.Sprintf("keep the last %d posts", keepLast.Int32)
+	case keepDays.Valid:
+		return fmt.Sprintf("keep posts from the last %d days", keepDays.Int32)
+	}
+
+	return "keep everything"
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/matt-horst/blog-agg/internal/config"
+)
+
+func TestPrune(t *testing.T) {
+	testPrune(t, newTestState(t))
+}
+
+func testPrune(t *testing.T, s *state) {
+	ctx := context.Background()
+	day := func(d int) time.Time {
+		return time.Date(2024, time.March, d, 12, 0, 0, 0, time.UTC)
+	}
+
+	s.cfg.Retention = &config.RetentionPolicy{KeepLast: 2}
+	alice := createTestUser(t, s, "alice")
+	bob := createTestUser(t, s, "bob")
+	tech := createTestFeed(t, s, alice, "Tech", "https://tech.example.com/rss")
+	garden := createTestFeed(t, s, bob, "Garden", "https://garden.example.com/rss")
+	for d := 1; d <= 5; d++ {
+		createTestPost(t, s, tech, fmt.Sprintf("Tech %d", d), day(d))
+	}
+	for d := 1; d <= 3; d++ {
+		createTestPost(t, s, garden, fmt.Sprintf("Garden %d", d), day(d))
+	}
+
+	// Saved posts are kept but still count towards keep-last
+	_, err := runHandler(t, handlerSave, s, alice, "https://tech.example.com/rss/tech-1")
+	if err != nil {
+		t.Fatalf("Failed to save post: %v", err)
+	}
+
+	_, err = runHandler(t, handlerRetention, s, bob, "--keep-last", "1", tech.Url)
+	if err == nil || !strings.Contains(err.Error(), "Only the user who added `Tech`") {
+		t.Errorf("Member set retention of someone else's feed: %v", err)
+	}
+	_, err = runHandler(t, handlerRetention, s, bob, "--keep-last", "1", garden.Url)
+	if err != nil {
+		t.Fatalf("Failed to set retention: %v", err)
+	}
+	out, err := runHandler(t, handlerRetention, s, bob)
+	if err != nil {
+		t.Fatalf("Failed to list retention: %v", err)
+	}
+	want := "Default: keep the last 2 posts\n* Garden (https://garden.example.com/rss): keep the last 1 posts\n"
+	if out != want {
+		t.Errorf("retention printed %q, want %q", out, want)
+	}
+
+	out, err = runHandler(t, handlerPrune, s, alice, "--dry-run")
+	if err != nil {
+		t.Fatalf("prune --dry-run failed: %v", err)
+	}
+	want = "* Garden: 2 posts\n* Tech: 2 posts\nWould delete 4 posts from 2 feeds\n"
+	if out != want {
+		t.Errorf("prune --dry-run printed %q, want %q", out, want)
+	}
+
+	_, err = runHandler(t, handlerPrune, s, bob, "--yes")
+	if err == nil || !strings.Contains(err.Error(), "only be run by an admin") {
+		t.Errorf("Member pruned every feed: %v", err)
+	}
+	_, err = runHandler(t, handlerPrune, s, bob, "--yes", "--feed", tech.Url)
+	if err == nil || !strings.Contains(err.Error(), "Only the user who added `Tech`") {
+		t.Errorf("Member pruned someone else's feed: %v", err)
+	}
+
+	out, err = runHandler(t, handlerPrune, s, bob, "--yes", "--feed", garden.Url)
+	if err != nil {
+		t.Fatalf("Owner failed to prune their feed: %v", err)
+	}
+	if want := "* Garden: 2 posts\nDeleted 2 posts from 1 feeds\n"; out != want {
+		t.Errorf("prune --feed printed %q, want %q", out, want)
+	}
+
+	out, err = runHandler(t, handlerPrune, s, alice, "--yes", "--batch-size", "1")
+	if err != nil {
+		t.Fatalf("prune failed: %v", err)
+	}
+	if want := "* Tech: 2 posts\nDeleted 2 posts from 1 feeds\n"; out != want {
+		t.Errorf("prune printed %q, want %q", out, want)
+	}
+
+	for url, want := range map[string]bool{
+		"https://tech.example.com/rss/tech-1": true,
+		"https://tech.example.com/rss/tech-2": false,
+		"https://tech.example.com/rss/tech-3": false,
+		"https://tech.example.com/rss/tech-5": true,
+		"https://garden.example.com/rss/garden-2": false,
+		"https://garden.example.com/rss/garden-3": true,
+	} {
+		_, err := s.db.GetPostByURL(ctx, url)
+		if got := err == nil; got != want {
+			t.Errorf("Post `%s` kept: %v, want %v", url, got, want)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/matt-horst/blog-agg/internal/database"
+
+	"github.com/google/uuid"
+)
+
+func handlerRead(ctx context.Context, s *state, cmd command, user database.User) error {
+	return setReadState(ctx, s, cmd, user, true)
+}
+
+func handlerUnread(ctx context.Context, s *state, cmd command, user database.User) error {
+	return setReadState(ctx, s, cmd, user, false)
+}
+
+// Marks a single post, every post of a feed, or every followed post published
+// before a date as read or unread
+func setReadState(ctx context.Context, s *state, cmd command, user database.User, read bool) error {
+	fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	feedURL := fs.String("feed", "", "apply to every post of the feed with this URL")
+	before := fs.String("before", "", "apply to every followed post published before this date (YYYY-MM-DD)")
+
+	positional, err := parseFlags(fs, cmd.args)
+	if err != nil {
+		return err
+	}
+
+	label := "read"
+	if !read {
+		label = "unread"
+	}
+
+	selectors := len(positional)
+	if *feedURL != "" {
+		selectors++
+	}
+	if *before != "" {
+		selectors++
+	}
+	if selectors != 1 {
+		return fmt.Errorf("%s requires exactly one of: post ID or URL, --feed url, --before date", cmd.name)
+	}
+
+	var count int64
+	switch {
+	case *feedURL != "":
+		feed, err := s.db.GetFeed(ctx, *feedURL)
+		if err != nil {
+			return fmt.Errorf("Unable to find feed `%s`: %v", *feedURL, err)
+		}
+
+		if read {
+			count, err = s.db.MarkFeedRead(ctx, database.MarkFeedReadParams{UserID: user.ID, FeedID: feed.ID})
+		} else {
+			count, err = s.db.MarkFeedUnread(ctx, database.MarkFeedUnreadParams{UserID: user.ID, FeedID: feed.ID})
+		}
+		if err != nil {
+			return fmt.Errorf("Failed to mark feed `%s` as %s: %v", feed.Name, label, err)
+		}
+	case *before != "":
+		date, err := parseDate(*before)
+		if err != nil {
+			return err
+		}
+
+		if read {
+			count, err = s.db.MarkPostsReadBefore(ctx, database.MarkPostsReadBeforeParams{UserID: user.ID, Before: date})
+		} else {
+			count, err = s.db.MarkPostsUnreadBefore(ctx, database.MarkPostsUnreadBeforeParams{UserID: user.ID, Before: date})
+		}
+		if err != nil {
+			return fmt.Errorf("Failed to mark posts before %s as %s: %v", *before, label, err)
+		}
+	default:
+		post, err := findPost(ctx, s, positional[0])
+		if err != nil {
+			return err
+		}
+
+		if read {
+			count, err = s.db.MarkPostRead(ctx, database.MarkPostReadParams{UserID: user.ID, PostID: post.ID})
+		} else {
+			count, err = s.db.MarkPostUnread(ctx, database.MarkPostUnreadParams{UserID: user.ID, PostID: post.ID})
+		}
+		if err != nil {
+			return fmt.Errorf("Failed to mark post `%s` as %s: %v", post.Url, label, err)
+		}
+	}
+
+	// Posts already in that state aren't counted
+	fmt.Printf("Marked %s as %s\n", plural(count, "post"), label)
+
+	return nil
+}
+
+// Looks up a post by its ID, falling back to its URL
+func findPost(ctx context.Context, s *state, idOrURL string) (database.Post, error) {
+	id, err := uuid.Parse(idOrURL)
+	if err == nil {
+		post, err := s.db.GetPostByID(ctx, id)
+		if err != nil {
+			return database.Post{}, fmt.Errorf("Unable to find post `%s`: %v", idOrURL, err)
+		}
+		return post, nil
+	}
+
+	post, err := s.db.GetPostByURL(ctx, idOrURL)
+	if err != nil {
+		return database.Post{}, fmt.Errorf("Unable to find post `%s`: %v", idOrURL, err)
+	}
+
+	return post, nil
+}
+
+// Parses a user-supplied date, accepting a plain YYYY-MM-DD as well as the
+// formats understood by parseTime
+func parseDate(str string) (time.Time, error) {
+	date, err := time.Parse(time.DateOnly, str)
+	if err == nil {
+		return date, nil
+	}
+
+	return parseTime(str)
+}
+
+// A count followed by the noun, in the plural unless the count is one
+func plural(n int64, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, noun)
+	}
+
+	return fmt.Sprintf("%d %ss", n, noun)
+}
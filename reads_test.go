@@ -0,0 +1,94 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadState(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2024, time.March, d, 12, 0, 0, 0, time.UTC)
+	}
+
+	s := newTestState(t)
+	alice := createTestUser(t, s, "alice")
+	tech := createTestFeed(t, s, alice, "Tech", "https://tech.example.com/rss")
+	cooking := createTestFeed(t, s, alice, "Cooking", "https://cooking.example.com/rss")
+	tech1 := createTestPost(t, s, tech, "Tech 1", day(1))
+	createTestPost(t, s, cooking, "Cooking 2", day(2))
+	createTestPost(t, s, tech, "Tech 3", day(3))
+	createTestPost(t, s, cooking, "Cooking 4", day(4))
+
+	unread := func() []string {
+		t.Helper()
+
+		out, err := runHandler(t, handlerBrowse, s, alice, "10")
+		if err != nil {
+			t.Fatalf("browse failed: %v", err)
+		}
+		return browseTitles(out)
+	}
+
+	mark := func(read bool, args ...string) string {
+		t.Helper()
+
+		handler := handlerRead
+		if !read {
+			handler = handlerUnread
+		}
+		out, err := runHandler(t, handler, s, alice, args...)
+		if err != nil {
+			t.Fatalf("Marking %v failed: %v", args, err)
+		}
+		return strings.TrimSpace(out)
+	}
+
+	if out := mark(true, tech1.ID.String()); out != "Marked 1 post as read" {
+		t.Errorf("Marking a post read printed %q", out)
+	}
+	if out := mark(true, tech1.Url); out != "Marked 0 posts as read" {
+		t.Errorf("Marking a read post read again printed %q", out)
+	}
+	if got, want := unread(), []string{"Cooking 4", "Tech 3", "Cooking 2"}; !slices.Equal(got, want) {
+		t.Errorf("Unread posts %q, want %q", got, want)
+	}
+
+	if out := mark(true, "--feed", cooking.Url); out != "Marked 2 posts as read" {
+		t.Errorf("Marking a feed read printed %q", out)
+	}
+	if got, want := unread(), []string{"Tech 3"}; !slices.Equal(got, want) {
+		t.Errorf("Unread posts %q, want %q", got, want)
+	}
+
+	if out := mark(false, "--before", "2024-03-03"); out != "Marked 2 posts as unread" {
+		t.Errorf("Marking posts before a date unread printed %q", out)
+	}
+	if got, want := unread(), []string{"Tech 3", "Cooking 2", "Tech 1"}; !slices.Equal(got, want) {
+		t.Errorf("Unread posts %q, want %q", got, want)
+	}
+
+	if out := mark(true, "--before", "2024-03-10"); out != "Marked 3 posts as read" {
+		t.Errorf("Marking posts before a date read printed %q", out)
+	}
+	if out := mark(false, tech1.ID.String()); out != "Marked 1 post as unread" {
+		t.Errorf("Marking a post unread printed %q", out)
+	}
+	if out := mark(false, tech1.ID.String()); out != "Marked 0 posts as unread" {
+		t.Errorf("Marking an unread post unread printed %q", out)
+	}
+
+	for _, args := range [][]string{
+		{},
+		{tech1.Url, "--feed", tech.Url},
+		{"--feed", "https://unknown.example.com/rss"},
+		{"https://unknown.example.com/post"},
+		{"--before", "someday"},
+	} {
+		_, err := runHandler(t, handlerRead, s, alice, args...)
+		if err == nil {
+			t.Errorf("read %v succeeded", args)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/matt-horst/blog-agg/internal/database"
+
+	"github.com/google/uuid"
+)
+
+func handlerSave(ctx context.Context, s *state, cmd command, user database.User) error {
+	fs := flag.NewFlagSet("save", flag.ContinueOnError)
+	note := fs.String("note", "", "note to keep with the saved post")
+	tags := fs.String("tags", "", "comma-separated tags")
+
+	positional, err := parseFlags(fs, cmd.args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return fmt.Errorf("save requires a post ID or URL")
+	}
+
+	post, err := findPost(ctx, s, positional[0])
+	if err != nil {
+		return err
+	}
+
+	feed, err := s.db.GetFeedByID(ctx, post.FeedID)
+	if err != nil {
+		return fmt.Errorf("Unable to find feed for post `%s`: %v", post.Url, err)
+	}
+
+	saved, err := s.db.SavePost(
+		ctx,
+		database.SavePostParams{
+			ID: uuid.New(),
+			UserID: user.ID,
+			PostID: uuid.NullUUID{UUID: post.ID, Valid: true},
+			Title: post.Title,
+			Url: post.Url,
+			Description: post.Description,
+			PublishedAt: post.PublishedAt,
+			FeedName: feed.Name,
+			Note: *note,
+			Tags: parseTags(*tags),
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("Failed to save post: %v", err)
+	}
+
+	fmt.Printf("Saved `%s` (%s)\n", saved.Title, saved.ID)
+
+	return nil
+}
+
+func handlerUnsave(ctx context.Context, s *state, cmd command, user database.User) error {
+	if len(cmd.args) != 1 {
+		return fmt.Errorf("unsave requires a saved post ID, post ID or URL")
+	}
+
+	var removed int64
+	id, err := uuid.Parse(cmd.args[0])
+	if err == nil {
+		removed, err = s.db.UnsavePostByID(ctx, database.UnsavePostByIDParams{UserID: user.ID, ID: id})
+	} else {
+		removed, err = s.db.UnsavePostByURL(ctx, database.UnsavePostByURLParams{UserID: user.ID, Url: cmd.args[0]})
+	}
+	if err != nil {
+		return fmt.Errorf("Failed to unsave `%s`: %v", cmd.args[0], err)
+	}
+	if removed == 0 {
+		return fmt.Errorf("No saved post matches `%s`", cmd.args[0])
+	}
+
+	fmt.Printf("Removed `%s` from saved posts\n", cmd.args[0])
+
+	return nil
+}
+
+func handlerSaved(ctx context.Context, s *state, cmd command, user database.User) error {
+	fs := flag.NewFlagSet("saved", flag.ContinueOnError)
+	tag := fs.String("tag", "", "only list posts with this tag")
+
+	_, err := parseFlags(fs, cmd.args)
+	if err != nil {
+		return err
+	}
+
+	var saved []database.SavedPost
+	if *tag != "" {
+		saved, err = s.db.GetSavedPostsForUserByTag(ctx, database.GetSavedPostsForUserByTagParams{UserID: user.ID, Tag: *tag})
+	} else {
+		saved, err = s.db.GetSavedPostsForUser(ctx, user.ID)
+	}
+	if err != nil {
+		return fmt.Errorf("Failed to get saved posts: %v", err)
+	}
+
+	fmt.Printf("You have %d saved posts\n", len(saved))
+	for _, p := range saved {
+		fmt.Printf("*** %s ***\n", p.Title)
+		fmt.Printf("From %s", p.FeedName)
+		if p.PublishedAt.Valid {
+			fmt.Printf(", %s", p.PublishedAt.Time.Format("Mon Jan 2, 2006"))
+		}
+		fmt.Println()
+		if p.Note != "" {
+			fmt.Printf("Note: %s\n", p.Note)
+		}
+		if len(p.Tags) > 0 {
+			fmt.Printf("Tags: %s\n", strings.Join(p.Tags, ", "))
+		}
+		fmt.Printf("Link: %s\n", p.Url)
+		fmt.Printf("ID: %s\n", p.ID)
+		fmt.Println()
+	}
+
+	return nil
+}
+
+func parseTags(str string) []string {
+	tags := []string{}
+	for _, tag := range strings.Split(str, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+
+	return tags
+}
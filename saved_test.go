@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSavedPosts(t *testing.T) {
+	ctx := context.Background()
+	day := func(d int) time.Time {
+		return time.Date(2024, time.March, d, 12, 0, 0, 0, time.UTC)
+	}
+
+	s := newTestState(t)
+	alice := createTestUser(t, s, "alice")
+	bob := createTestUser(t, s, "bob")
+	tech := createTestFeed(t, s, alice, "Tech", "https://tech.example.com/rss")
+	tech1 := createTestPost(t, s, tech, "Tech 1", day(1))
+	createTestPost(t, s, tech, "Tech 2", day(2))
+
+	saved := func(user string, args ...string) string {
+		t.Helper()
+
+		u, err := s.db.GetUser(ctx, user)
+		if err != nil {
+			t.Fatalf("Failed to get user: %v", err)
+		}
+		out, err := runHandler(t, handlerSaved, s, u, args...)
+		if err != nil {
+			t.Fatalf("saved %v failed: %v", args, err)
+		}
+		return out
+	}
+
+	out, err := runHandler(t, handlerSave, s, alice, "--note", "For the newsletter", "--tags", "go, newsletter,", tech1.ID.String())
+	if err != nil {
+		t.Fatalf("Failed to save by ID: %v", err)
+	}
+	_, savedID, _ := strings.Cut(strings.TrimSuffix(out, ")\n"), " (")
+	_, err = runHandler(t, handlerSave, s, alice, "--tags", "misc", "https://tech.example.com/rss/tech-2")
+	if err != nil {
+		t.Fatalf("Failed to save by URL: %v", err)
+	}
+
+	// Saving again updates the note and keeps the tags
+	_, err = runHandler(t, handlerSave, s, alice, "--note", "Lead story", tech1.Url)
+	if err != nil {
+		t.Fatalf("Failed to save again: %v", err)
+	}
+
+	out = saved("alice")
+	if got, want := browseTitles(out), []string{"Tech 2", "Tech 1"}; !slices.Equal(got, want) {
+		t.Errorf("Saved posts %q, want %q", got, want)
+	}
+	for _, line := range []string{"You have 2 saved posts", "From Tech, Fri Mar 1, 2024", "Note: Lead story", "Tags: go, newsletter"} {
+		if !strings.Contains(out, line+"\n") {
+			t.Errorf("saved printed %q, want a line %q", out, line)
+		}
+	}
+	if got, want := browseTitles(saved("alice", "--tag", "newsletter")), []string{"Tech 1"}; !slices.Equal(got, want) {
+		t.Errorf("Saved posts tagged newsletter %q, want %q", got, want)
+	}
+	if got := browseTitles(saved("bob")); len(got) != 0 {
+		t.Errorf("Saved posts of another user %q", got)
+	}
+
+	// Saved posts outlive the posts they were saved from
+	_, err = runHandler(t, handlerDeleteFeed, s, alice, "--yes", tech.Url)
+	if err != nil {
+		t.Fatalf("Failed to delete feed: %v", err)
+	}
+	if got, want := browseTitles(saved("alice")), []string{"Tech 2", "Tech 1"}; !slices.Equal(got, want) {
+		t.Errorf("Saved posts after deleting the feed %q, want %q", got, want)
+	}
+
+	_, err = runHandler(t, handlerUnsave, s, bob, tech1.Url)
+	if err == nil || !strings.Contains(err.Error(), "No saved post matches") {
+		t.Errorf("Unsaved another user's post: %v", err)
+	}
+	// The deleted post's ID no longer leads to the saved copy, its own ID does
+	_, err = runHandler(t, handlerUnsave, s, alice, tech1.ID.String())
+	if err == nil {
+		t.Error("Unsaved by the ID of a deleted post")
+	}
+	_, err = runHandler(t, handlerUnsave, s, alice, savedID)
+	if err != nil {
+		t.Fatalf("Failed to unsave by saved post ID: %v", err)
+	}
+	_, err = runHandler(t, handlerUnsave, s, alice, "https://tech.example.com/rss/tech-2")
+	if err != nil {
+		t.Fatalf("Failed to unsave by URL: %v", err)
+	}
+	_, err = runHandler(t, handlerUnsave, s, alice, tech1.Url)
+	if err == nil {
+		t.Error("Unsaved a post twice")
+	}
+	if out := saved("alice"); out != "You have 0 saved posts\n" {
+		t.Errorf("saved printed %q after unsaving everything", out)
+	}
+}
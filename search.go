@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/matt-horst/blog-agg/internal/database"
+)
+
+// Searches post titles, descriptions and content. Queries use web search
+// syntax: "quoted phrases", `or` between alternatives, and -word to exclude,
+// which has to come after -- or be quoted so it isn't taken for a flag.
+func handlerSearch(ctx context.Context, s *state, cmd command, user database.User) error {
+	fs := flag.NewFlagSet("search", flag.ContinueOnError)
+	limit := fs.Int("limit", 10, "maximum number of results")
+	allFeeds := fs.Bool("all-feeds", false, "search every feed, not just the ones you follow")
+	since := fs.String("since", "", "only match posts published on or after this date")
+	until := fs.String("until", "", "only match posts published before this date")
+
+	positional, err := parseFlags(fs, cmd.args)
+	if err != nil {
+		return err
+	}
+	if len(positional) == 0 {
+		return fmt.Errorf("search requires a query")
+	}
+
+	params := database.SearchPostsParams{
+		Query: strings.Join(positional, " "),
+		FollowedOnly: !*allFeeds,
+		UserID: user.ID,
+		RowLimit: int32(*limit),
+	}
+
+	if *since != "" {
+		date, err := parseDate(*since)
+		if err != nil {
+			return err
+		}
+		params.Since = sql.NullTime{Time: date, Valid: true}
+	}
+	if *until != "" {
+		date, err := parseDate(*until)
+		if err != nil {
+			return err
+		}
+		params.Until = sql.NullTime{Time: date, Valid: true}
+	}
+
+	results, err := s.db.SearchPosts(ctx, params)
+	if err != nil {
+		return fmt.Errorf("Failed to search posts: %v", err)
+	}
+
+	fmt.Printf("Found %d posts matching `%s`\n", len(results), params.Query)
+	for _, r := range results {
+		fmt.Printf("*** %s ***\n", r.Title)
+		fmt.Printf("From %s", r.FeedName)
+		if r.PublishedAt.Valid {
+			fmt.Printf(", %s", r.PublishedAt.Time.Format("Mon Jan 2, 2006"))
+		}
+		fmt.Printf(" (rank %.3f)\n", r.Rank)
+		fmt.Printf("	%s\n", strings.Join(strings.Fields(r.Snippet), " "))
+		fmt.Printf("Link: %s\n", r.Url)
+		fmt.Printf("ID: %s\n", r.ID)
+		fmt.Println()
+	}
+
+	return nil
+}
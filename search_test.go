@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/matt-horst/blog-agg/internal/database"
+
+	"github.com/google/uuid"
+)
+
+func TestSearch(t *testing.T) {
+	testSearch(t, newTestState(t))
+}
+
+func testSearch(t *testing.T, s *state) {
+	day := func(d int) time.Time {
+		return time.Date(2024, time.March, d, 12, 0, 0, 0, time.UTC)
+	}
+
+	alice := createTestUser(t, s, "alice")
+	bob := createTestUser(t, s, "bob")
+	tech := createTestFeed(t, s, alice, "Tech", "https://tech.example.com/rss")
+	cooking := createTestFeed(t, s, bob, "Cooking", "https://cooking.example.com/rss")
+
+	for _, p := range []struct {
+		feed database.Feed
+		title string
+		description string
+		day int
+	}{
+		{feed: tech, title: "Connection pooling in Go", description: "Tuning pgbouncer for a busy service", day: 3},
+		{feed: tech, title: "Goroutine leaks", description: "A leaked goroutine held a database connection open", day: 5},
+		{feed: tech, title: "Release notes", description: "Minor fixes", day: 1},
+		{feed: cooking, title: "Connection between cooks", description: "Pooling pans in a busy kitchen", day: 4},
+	} {
+		_, err := s.db.CreatePost(
+			context.Background(),
+			database.CreatePostParams{
+				ID: uuid.New(),
+				Title: p.title,
+				Url: p.feed.Url + "/" + strings.ReplaceAll(strings.ToLower(p.title), " ", "-"),
+				Description: p.description,
+				PublishedAt: sql.NullTime{Time: day(p.day), Valid: true},
+				FeedID: p.feed.ID,
+				Categories: []string{},
+			},
+		)
+		if err != nil {
+			t.Fatalf("Failed to create post `%s`: %v", p.title, err)
+		}
+	}
+
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{args: []string{"connection"}, want: []string{"Connection pooling in Go", "Goroutine leaks"}},
+		{args: []string{"--all-feeds", "connection"}, want: []string{"Connection between cooks", "Connection pooling in Go", "Goroutine leaks"}},
+		{args: []string{`"connection pooling"`}, want: []string{"Connection pooling in Go"}},
+		{args: []string{"pgbouncer", "or", "goroutine"}, want: []string{"Connection pooling in Go", "Goroutine leaks"}},
+		{args: []string{"connection -goroutine"}, want: []string{"Connection pooling in Go"}},
+		{args: []string{"--all-feeds", "--", "connection", "-goroutine"}, want: []string{"Connection between cooks", "Connection pooling in Go"}},
+		{args: []string{"--since", "2024-03-04", "connection"}, want: []string{"Goroutine leaks"}},
+		{args: []string{"--until", "2024-03-04", "connection"}, want: []string{"Connection pooling in Go"}},
+		{args: []string{"kubernetes"}, want: []string{}},
+	}
+
+	for _, tt := range tests {
+		out, err := runHandler(t, handlerSearch, s, alice, tt.args...)
+		if err != nil {
+			t.Errorf("search %q failed: %v", tt.args, err)
+			continue
+		}
+
+		// Ranking differs between stores, so only compare what matched
+		got := browseTitles(out)
+		slices.Sort(got)
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("search %q found %q, want %q", tt.args, got, tt.want)
+		}
+	}
+
+	out, err := runHandler(t, handlerSearch, s, alice, "--limit", "1", "connection")
+	if err != nil {
+		t.Fatalf("search --limit failed: %v", err)
+	}
+	if !strings.HasPrefix(out, "Found 1 posts matching `connection`\n") || len(browseTitles(out)) != 1 {
+		t.Errorf("search --limit 1 printed %q", out)
+	}
+
+	for _, args := range [][]string{
+		{},
+		{"--since", "someday", "connection"},
+	} {
+		_, err := runHandler(t, handlerSearch, s, alice, args...)
+		if err == nil {
+			t.Errorf("search %q succeeded", args)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+-- name: CreateAlertRule :one
+INSERT INTO alert_rules (id, created_at, updated_at, user_id, name, kind, pattern, notifier, target)
+VALUES ($1, NOW(), NOW(), $2, $3, $4, $5, $6, $7)
+RETURNING *;
+
+-- name: GetAlertRulesForUser :many
+SELECT * FROM alert_rules
+WHERE user_id = $1
+ORDER BY name;
+
+-- name: GetAlertRulesForFeed :many
+SELECT alert_rules.* FROM alert_rules
+INNER JOIN feed_follows ON feed_follows.user_id = alert_rules.user_id
+WHERE feed_follows.feed_id = $1;
+
+-- name: DeleteAlertRule :execrows
+DELETE FROM alert_rules
+WHERE user_id = $1 AND name = $2;
+
+-- name: CreatePostAlert :exec
+INSERT INTO post_alerts (post_id, rule_id, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT DO NOTHING;
+
+-- name: GetAlertedPostsForUser :many
+SELECT posts.*, alert_rules.name AS rule_name, post_alerts.created_at AS alerted_at
+FROM post_alerts
+INNER JOIN alert_rules ON alert_rules.id = post_alerts.rule_id
+INNER JOIN posts ON posts.id = post_alerts.post_id
+WHERE alert_rules.user_id = $1
+ORDER BY post_alerts.created_at DESC
+LIMIT $2;
@@ -0,0 +1,14 @@
+-- name: CreateAuditEvent :exec
+INSERT INTO audit_events (id, created_at, actor_id, actor_name, command, args, outcome, error)
+VALUES ($1, NOW(), $2, $3, $4, $5, $6, $7);
+
+-- name: GetAuditEvents :many
+SELECT * FROM audit_events
+WHERE (sqlc.narg(actor_id)::uuid IS NULL OR actor_id = sqlc.narg(actor_id)::uuid)
+AND (sqlc.narg(actor_name)::text IS NULL OR actor_name = sqlc.narg(actor_name)::text)
+AND (sqlc.narg(command)::text IS NULL OR command = sqlc.narg(command)::text)
+AND (sqlc.narg(since)::timestamp IS NULL OR created_at >= sqlc.narg(since)::timestamp)
+AND (sqlc.narg(until)::timestamp IS NULL OR created_at < sqlc.narg(until)::timestamp)
+AND (NOT sqlc.arg(failures_only)::boolean OR outcome = 'failure')
+ORDER BY created_at DESC
+LIMIT sqlc.arg(row_limit);
@@ -0,0 +1,21 @@
+-- name: CreateFeedFetch :exec
+INSERT INTO feed_fetches (id, feed_id, fetched_at, status_code, duration_ms, bytes, items_seen, new_posts, error)
+VALUES ($1, $2, NOW(), $3, $4, $5, $6, $7, $8);
+
+-- name: GetFeedFetchesSince :many
+SELECT * FROM feed_fetches
+WHERE fetched_at >= $1
+ORDER BY fetched_at ASC;
+
+-- name: DeleteFeedFetchesBefore :execrows
+DELETE FROM feed_fetches
+WHERE fetched_at < $1;
+
+-- name: GetLastFeedFetches :many
+SELECT DISTINCT ON (feed_id) * FROM feed_fetches
+ORDER BY feed_id, fetched_at DESC;
+
+-- name: GetLastSuccessfulFeedFetches :many
+SELECT DISTINCT ON (feed_id) * FROM feed_fetches
+WHERE error IS NULL
+ORDER BY feed_id, fetched_at DESC;
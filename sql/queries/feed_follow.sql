@@ -9,13 +9,43 @@ INNER JOIN feeds ON inserted_feed_follows.feed_id = feeds.id
 INNER JOIN users ON inserted_feed_follows.user_id = users.id;
 
 -- name: GetFeedFollowsForUser :many
-SELECT feed_follows.*, users.name AS user_name, feeds.name AS feed_name 
+SELECT feed_follows.*, users.name AS user_name, COALESCE(NULLIF(feed_follows.title, ''), feeds.name)::text AS feed_name, feeds.url AS feed_url
 FROM feed_follows
 INNER JOIN users ON feed_follows.user_id = users.id
 INNER JOIN feeds ON feed_follows.feed_id = feeds.id
-WHERE users.name = $1;
+WHERE users.name = $1
+ORDER BY feed_follows.position, feeds.name;
 
 -- name: DeleteFeedFollow :one
 DELETE FROM feed_follows
 WHERE feed_id = $1 AND user_id = $2
 RETURNING *;
+
+-- name: GetFeedFollow :one
+SELECT * FROM feed_follows
+WHERE user_id = $1 AND feed_id = $2;
+
+-- name: UpdateFeedFollowDetails :exec
+UPDATE feed_follows
+SET title = $3, notes = $4, priority = $5, updated_at = NOW()
+WHERE user_id = $1 AND feed_id = $2;
+
+-- name: UpsertFeedFollow :exec
+INSERT INTO feed_follows (id, created_at, updated_at, user_id, feed_id, folder_id, position)
+VALUES ($1, NOW(), NOW(), $2, $3, $4, $5)
+ON CONFLICT (user_id, feed_id) DO UPDATE
+SET folder_id = EXCLUDED.folder_id, position = EXCLUDED.position, updated_at = NOW();
+
+-- name: MoveFeedFollow :execrows
+UPDATE feed_follows
+SET folder_id = $3, position = $4, updated_at = NOW()
+WHERE user_id = $1 AND feed_id = $2;
+
+-- name: NextFeedFollowPosition :one
+SELECT COALESCE(MAX(position) + 1, 0)::integer AS position FROM feed_follows
+WHERE user_id = $1 AND folder_id IS NOT DISTINCT FROM $2;
+
+-- name: ShiftFeedFollowPositions :exec
+UPDATE feed_follows
+SET position = position + 1
+WHERE user_id = $1 AND folder_id IS NOT DISTINCT FROM $2 AND position >= $3;
@@ -17,6 +17,42 @@ WHERE id = $1;
 
 -- name: GetNextFeedToFetch :one
 SELECT * FROM feeds
+WHERE paused_at IS NULL
 ORDER BY last_fetched_at ASC NULLS FIRST
 LIMIT 1;
 
+-- name: CountFeedsDue :one
+SELECT COUNT(*) FROM feeds
+WHERE paused_at IS NULL
+AND (last_fetched_at IS NULL OR last_fetched_at < sqlc.arg(fetched_before));
+
+-- name: RenameFeed :exec
+UPDATE feeds
+SET name = $2, updated_at = NOW()
+WHERE id = $1;
+
+-- name: UpdateFeedURL :exec
+UPDATE feeds
+SET url = $2, hub_url = NULL, self_url = NULL, last_fetched_at = NULL, updated_at = NOW()
+WHERE id = $1;
+
+-- name: SetFeedPaused :exec
+UPDATE feeds
+SET paused_at = $2, updated_at = NOW()
+WHERE id = $1;
+
+-- name: CountFeedPosts :one
+SELECT COUNT(*) FROM posts WHERE feed_id = $1;
+
+-- name: DeleteFeed :exec
+DELETE FROM feeds WHERE id = $1;
+
+-- name: SetFeedOwner :exec
+UPDATE feeds
+SET user_id = $2, updated_at = NOW()
+WHERE id = $1;
+
+-- name: TransferFeeds :execrows
+UPDATE feeds
+SET user_id = sqlc.arg(to_user_id), updated_at = NOW()
+WHERE user_id = sqlc.arg(from_user_id);
@@ -0,0 +1,36 @@
+-- name: MarkPostRead :execrows
+INSERT INTO post_reads (user_id, post_id, read_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT DO NOTHING;
+
+-- name: MarkPostUnread :execrows
+DELETE FROM post_reads
+WHERE user_id = $1 AND post_id = $2;
+
+-- name: MarkFeedRead :execrows
+INSERT INTO post_reads (user_id, post_id, read_at)
+SELECT sqlc.arg(user_id)::uuid, posts.id, NOW() FROM posts
+WHERE posts.feed_id = sqlc.arg(feed_id)
+ON CONFLICT DO NOTHING;
+
+-- name: MarkFeedUnread :execrows
+DELETE FROM post_reads
+USING posts
+WHERE post_reads.post_id = posts.id
+AND post_reads.user_id = $1
+AND posts.feed_id = $2;
+
+-- name: MarkPostsReadBefore :execrows
+INSERT INTO post_reads (user_id, post_id, read_at)
+SELECT sqlc.arg(user_id)::uuid, posts.id, NOW() FROM posts
+INNER JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+WHERE feed_follows.user_id = sqlc.arg(user_id)::uuid
+AND COALESCE(posts.published_at, posts.created_at) < sqlc.arg(before)::timestamp
+ON CONFLICT DO NOTHING;
+
+-- name: MarkPostsUnreadBefore :execrows
+DELETE FROM post_reads
+USING posts
+WHERE post_reads.post_id = posts.id
+AND post_reads.user_id = sqlc.arg(user_id)
+AND COALESCE(posts.published_at, posts.created_at) < sqlc.arg(before)::timestamp;
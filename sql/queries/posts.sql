@@ -1,7 +1,7 @@
 -- name: CreatePost :one
-INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id)
+INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id, content, author, categories)
 VALUES (
-    $1, NOW(), NOW(), $2, $3, $4, $5, $6
+    $1, NOW(), NOW(), $2, $3, $4, $5, $6, $7, $8, $9
 )
 RETURNING *;
 
@@ -11,3 +11,88 @@ INNER JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
 WHERE feed_follows.user_id = $1
 ORDER BY published_at DESC
 LIMIT $2;
+
+-- name: GetPostByID :one
+SELECT * FROM posts WHERE id = $1;
+
+-- name: GetPostByURL :one
+SELECT * FROM posts WHERE url = $1;
+
+-- name: BrowsePostsNewestFirst :many
+SELECT posts.* FROM posts
+INNER JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+WHERE feed_follows.user_id = sqlc.arg(user_id)
+AND (sqlc.narg(feed_ids)::uuid[] IS NULL OR posts.feed_id = ANY(sqlc.narg(feed_ids)::uuid[]))
+AND (sqlc.narg(since)::timestamp IS NULL OR COALESCE(posts.published_at, posts.created_at) >= sqlc.narg(since)::timestamp)
+AND (sqlc.narg(until)::timestamp IS NULL OR COALESCE(posts.published_at, posts.created_at) < sqlc.narg(until)::timestamp)
+AND (NOT sqlc.arg(unread_only)::bool OR NOT EXISTS (
+    SELECT 1 FROM post_reads
+    WHERE post_reads.post_id = posts.id AND post_reads.user_id = sqlc.arg(user_id)
+))
+AND (NOT sqlc.arg(apply_filters)::bool OR post_visible(sqlc.arg(user_id), posts))
+AND (sqlc.narg(min_priority)::integer IS NULL OR feed_follows.priority >= sqlc.narg(min_priority)::integer)
+AND (
+    sqlc.narg(cursor_published_at)::timestamp IS NULL
+    OR (COALESCE(posts.published_at, posts.created_at), posts.id) < (sqlc.narg(cursor_published_at)::timestamp, sqlc.narg(cursor_id)::uuid)
+)
+ORDER BY COALESCE(posts.published_at, posts.created_at) DESC, posts.id DESC
+LIMIT sqlc.arg(row_limit);
+
+-- name: BrowsePostsOldestFirst :many
+SELECT posts.* FROM posts
+INNER JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+WHERE feed_follows.user_id = sqlc.arg(user_id)
+AND (sqlc.narg(feed_ids)::uuid[] IS NULL OR posts.feed_id = ANY(sqlc.narg(feed_ids)::uuid[]))
+AND (sqlc.narg(since)::timestamp IS NULL OR COALESCE(posts.published_at, posts.created_at) >= sqlc.narg(since)::timestamp)
+AND (sqlc.narg(until)::timestamp IS NULL OR COALESCE(posts.published_at, posts.created_at) < sqlc.narg(until)::timestamp)
+AND (NOT sqlc.arg(unread_only)::bool OR NOT EXISTS (
+    SELECT 1 FROM post_reads
+    WHERE post_reads.post_id = posts.id AND post_reads.user_id = sqlc.arg(user_id)
+))
+AND (NOT sqlc.arg(apply_filters)::bool OR post_visible(sqlc.arg(user_id), posts))
+AND (sqlc.narg(min_priority)::integer IS NULL OR feed_follows.priority >= sqlc.narg(min_priority)::integer)
+AND (
+    sqlc.narg(cursor_published_at)::timestamp IS NULL
+    OR (COALESCE(posts.published_at, posts.created_at), posts.id) > (sqlc.narg(cursor_published_at)::timestamp, sqlc.narg(cursor_id)::uuid)
+)
+ORDER BY COALESCE(posts.published_at, posts.created_at) ASC, posts.id ASC
+LIMIT sqlc.arg(row_limit);
+
+-- name: SearchPosts :many
+SELECT
+    posts.id,
+    posts.title,
+    posts.url,
+    posts.published_at,
+    feeds.name AS feed_name,
+    ts_rank_cd(posts.search_vector, query)::real AS rank,
+    ts_headline(
+        'english',
+        posts.title || ' ' || posts.description || ' ' || posts.content,
+        query,
+        'MaxFragments=2, MinWords=5, MaxWords=20, StartSel=**, StopSel=**'
+    )::text AS snippet
+FROM posts
+INNER JOIN feeds ON feeds.id = posts.feed_id
+CROSS JOIN websearch_to_tsquery('english', sqlc.arg(query)) AS query
+WHERE posts.search_vector @@ query
+AND (NOT sqlc.arg(followed_only)::bool OR EXISTS (
+    SELECT 1 FROM feed_follows
+    WHERE feed_follows.feed_id = posts.feed_id AND feed_follows.user_id = sqlc.arg(user_id)
+))
+AND (sqlc.narg(since)::timestamp IS NULL OR COALESCE(posts.published_at, posts.created_at) >= sqlc.narg(since)::timestamp)
+AND (sqlc.narg(until)::timestamp IS NULL OR COALESCE(posts.published_at, posts.created_at) < sqlc.narg(until)::timestamp)
+ORDER BY rank DESC, posts.published_at DESC
+LIMIT sqlc.arg(row_limit);
+
+-- name: UpdatePost :execrows
+-- Refreshes a feed's post from its item, if the item has changed since
+UPDATE posts
+SET title = $3, description = $4, published_at = $5, content = $6, author = $7, categories = $8, updated_at = NOW()
+WHERE url = $1 AND feed_id = $2
+AND (title, description, published_at, content, author, categories) IS DISTINCT FROM ($3, $4, $5, $6, $7, $8);
+
+-- name: GetLastPostTimes :many
+-- When each feed last got a new post
+SELECT DISTINCT ON (feed_id) feed_id, created_at FROM posts
+ORDER BY feed_id, created_at DESC;
@@ -0,0 +1,31 @@
+-- name: SetFeedRetentionPolicy :exec
+INSERT INTO feed_retention_policies (feed_id, created_at, updated_at, keep_last, keep_days)
+VALUES ($1, NOW(), NOW(), $2, $3)
+ON CONFLICT (feed_id) DO UPDATE
+SET keep_last = EXCLUDED.keep_last, keep_days = EXCLUDED.keep_days, updated_at = NOW();
+
+-- name: DeleteFeedRetentionPolicy :execrows
+DELETE FROM feed_retention_policies
+WHERE feed_id = $1;
+
+-- name: GetFeedRetentionPolicies :many
+SELECT feed_retention_policies.*, feeds.name AS feed_name, feeds.url AS feed_url
+FROM feed_retention_policies
+INNER JOIN feeds ON feeds.id = feed_retention_policies.feed_id
+ORDER BY feeds.name;
+
+-- name: CountPrunablePosts :many
+SELECT feeds.name AS feed_name, COUNT(*) AS posts
+FROM prunable_posts(sqlc.narg(keep_last)::integer, sqlc.narg(keep_days)::integer, sqlc.narg(feed_id)::uuid) AS prunable
+INNER JOIN feeds ON feeds.id = prunable.feed_id
+GROUP BY feeds.id, feeds.name
+ORDER BY feeds.name;
+
+-- name: PrunePosts :many
+DELETE FROM posts
+WHERE id IN (
+    SELECT prunable.post_id
+    FROM prunable_posts(sqlc.narg(keep_last)::integer, sqlc.narg(keep_days)::integer, sqlc.narg(feed_id)::uuid) AS prunable
+    LIMIT sqlc.arg(batch_size)
+)
+RETURNING feed_id, (SELECT feeds.name FROM feeds WHERE feeds.id = posts.feed_id)::text AS feed_name;
@@ -0,0 +1,26 @@
+-- name: SavePost :one
+INSERT INTO saved_posts (id, created_at, updated_at, user_id, post_id, title, url, description, published_at, feed_name, note, tags)
+VALUES ($1, NOW(), NOW(), $2, $3, $4, $5, $6, $7, $8, $9, $10)
+ON CONFLICT (user_id, url) DO UPDATE
+SET note = COALESCE(NULLIF(EXCLUDED.note, ''), saved_posts.note),
+    tags = COALESCE(NULLIF(EXCLUDED.tags, '{}'), saved_posts.tags),
+    updated_at = NOW()
+RETURNING *;
+
+-- name: UnsavePostByID :execrows
+DELETE FROM saved_posts
+WHERE user_id = sqlc.arg(user_id) AND (id = sqlc.arg(id) OR post_id = sqlc.arg(id));
+
+-- name: UnsavePostByURL :execrows
+DELETE FROM saved_posts
+WHERE user_id = $1 AND url = $2;
+
+-- name: GetSavedPostsForUser :many
+SELECT * FROM saved_posts
+WHERE user_id = $1
+ORDER BY created_at DESC;
+
+-- name: GetSavedPostsForUserByTag :many
+SELECT * FROM saved_posts
+WHERE user_id = $1 AND sqlc.arg(tag)::text = ANY(tags)
+ORDER BY created_at DESC;
@@ -0,0 +1,24 @@
+-- name: CreateSession :exec
+INSERT INTO sessions (id, created_at, last_used_at, expires_at, user_id, token_hash)
+VALUES ($1, NOW(), NOW(), $2, $3, $4);
+
+-- name: GetUserBySession :one
+UPDATE sessions
+SET last_used_at = NOW()
+FROM users
+WHERE sessions.token_hash = $1
+AND sessions.expires_at > NOW()
+AND users.id = sessions.user_id
+RETURNING users.*;
+
+-- name: DeleteSession :exec
+DELETE FROM sessions
+WHERE token_hash = $1;
+
+-- name: DeleteOtherSessions :exec
+DELETE FROM sessions
+WHERE user_id = $1 AND token_hash <> $2;
+
+-- name: DeleteExpiredSessions :execrows
+DELETE FROM sessions
+WHERE expires_at <= NOW();
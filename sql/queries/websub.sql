@@ -0,0 +1,33 @@
+-- name: GetFeedByID :one
+SELECT * FROM feeds WHERE id = $1;
+
+-- name: SetFeedWebSubLinks :exec
+UPDATE feeds
+SET hub_url = $2, self_url = $3, updated_at = NOW()
+WHERE id = $1;
+
+-- name: GetPushFeeds :many
+SELECT * FROM feeds
+WHERE hub_url IS NOT NULL AND paused_at IS NULL;
+
+-- name: UpsertWebSubSubscription :one
+INSERT INTO websub_subscriptions (id, created_at, updated_at, feed_id, hub_url, topic_url, secret)
+VALUES ($1, NOW(), NOW(), $2, $3, $4, $5)
+ON CONFLICT (feed_id) DO UPDATE
+SET hub_url = EXCLUDED.hub_url,
+    topic_url = EXCLUDED.topic_url,
+    secret = EXCLUDED.secret,
+    verified_at = NULL,
+    updated_at = NOW()
+RETURNING *;
+
+-- name: GetWebSubSubscription :one
+SELECT * FROM websub_subscriptions WHERE feed_id = $1;
+
+-- name: MarkWebSubSubscriptionVerified :exec
+UPDATE websub_subscriptions
+SET verified_at = NOW(), lease_expires_at = $2, updated_at = NOW()
+WHERE feed_id = $1;
+
+-- name: DeleteWebSubSubscription :exec
+DELETE FROM websub_subscriptions WHERE feed_id = $1;
@@ -0,0 +1,23 @@
+-- +goose Up
+ALTER TABLE feeds
+ADD COLUMN hub_url TEXT DEFAULT NULL,
+ADD COLUMN self_url TEXT DEFAULT NULL;
+
+CREATE TABLE websub_subscriptions (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    feed_id UUID UNIQUE NOT NULL REFERENCES feeds(id) ON DELETE CASCADE,
+    hub_url TEXT NOT NULL,
+    topic_url TEXT NOT NULL,
+    secret TEXT NOT NULL,
+    verified_at TIMESTAMP DEFAULT NULL,
+    lease_expires_at TIMESTAMP DEFAULT NULL
+);
+
+-- +goose Down
+DROP TABLE websub_subscriptions;
+
+ALTER TABLE feeds
+DROP COLUMN hub_url,
+DROP COLUMN self_url;
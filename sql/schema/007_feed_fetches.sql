@@ -0,0 +1,17 @@
+-- +goose Up
+CREATE TABLE feed_fetches (
+    id UUID PRIMARY KEY,
+    feed_id UUID NOT NULL REFERENCES feeds(id) ON DELETE CASCADE,
+    fetched_at TIMESTAMP NOT NULL,
+    status_code INTEGER,
+    duration_ms INTEGER NOT NULL,
+    bytes BIGINT NOT NULL,
+    items_seen INTEGER NOT NULL,
+    new_posts INTEGER NOT NULL,
+    error TEXT
+);
+
+CREATE INDEX feed_fetches_fetched_at_idx ON feed_fetches (fetched_at);
+
+-- +goose Down
+DROP TABLE feed_fetches;
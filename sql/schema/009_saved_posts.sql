@@ -0,0 +1,21 @@
+-- +goose Up
+-- Saved posts keep their own copy of the post so they outlive retention
+-- pruning of the posts table
+CREATE TABLE saved_posts (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    post_id UUID REFERENCES posts(id) ON DELETE SET NULL,
+    title TEXT NOT NULL,
+    url TEXT NOT NULL,
+    description TEXT NOT NULL,
+    published_at TIMESTAMP,
+    feed_name TEXT NOT NULL,
+    note TEXT NOT NULL DEFAULT '',
+    tags TEXT[] NOT NULL DEFAULT '{}',
+    UNIQUE(user_id, url)
+);
+
+-- +goose Down
+DROP TABLE saved_posts;
@@ -0,0 +1,24 @@
+-- +goose Up
+CREATE TABLE alert_rules (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    kind TEXT NOT NULL CHECK (kind IN ('keyword', 'regex', 'author', 'feed')),
+    pattern TEXT NOT NULL,
+    notifier TEXT NOT NULL,
+    target TEXT NOT NULL DEFAULT '',
+    UNIQUE(user_id, name)
+);
+
+CREATE TABLE post_alerts (
+    post_id UUID NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    rule_id UUID NOT NULL REFERENCES alert_rules(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (post_id, rule_id)
+);
+
+-- +goose Down
+DROP TABLE post_alerts;
+DROP TABLE alert_rules;
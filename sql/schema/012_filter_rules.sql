@@ -0,0 +1,86 @@
+-- +goose Up
+ALTER TABLE posts
+ADD COLUMN author TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE posts
+ADD COLUMN categories TEXT[] NOT NULL DEFAULT '{}';
+
+CREATE TABLE filter_rules (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    action TEXT NOT NULL CHECK (action IN ('exclude', 'include')),
+    field TEXT NOT NULL CHECK (field IN ('title', 'content', 'category', 'author', 'any')),
+    pattern TEXT NOT NULL,
+    is_regex BOOLEAN NOT NULL DEFAULT FALSE,
+    feed_id UUID REFERENCES feeds(id) ON DELETE CASCADE,
+    UNIQUE(user_id, name)
+);
+
+-- Whether a post matches a pattern on one of its fields. Plain patterns are
+-- case-insensitive substrings, except categories which must match exactly
+-- (ignoring case). Regex patterns are case-insensitive.
+-- +goose StatementBegin
+CREATE FUNCTION filter_matches(field TEXT, pattern TEXT, is_regex BOOLEAN, post posts)
+RETURNS BOOLEAN AS $$
+    SELECT EXISTS (
+        SELECT 1 FROM unnest(
+            CASE field
+                WHEN 'title' THEN ARRAY[post.title]
+                WHEN 'content' THEN ARRAY[post.description, post.content]
+                WHEN 'author' THEN ARRAY[post.author]
+                WHEN 'category' THEN post.categories
+                ELSE ARRAY[post.title, post.description, post.content, post.author] || post.categories
+            END
+        ) AS value
+        WHERE CASE
+            WHEN is_regex THEN value ~* pattern
+            WHEN field = 'category' THEN lower(value) = lower(pattern)
+            ELSE strpos(lower(value), lower(pattern)) > 0
+        END
+    );
+$$ LANGUAGE SQL STABLE;
+-- +goose StatementEnd
+
+-- Whether a post should be shown to a user: it must match none of their
+-- exclude rules and, if any include rules apply to its feed, at least one of those
+-- +goose StatementBegin
+CREATE FUNCTION post_visible(viewer_id UUID, post posts)
+RETURNS BOOLEAN AS $$
+    SELECT NOT EXISTS (
+        SELECT 1 FROM filter_rules
+        WHERE filter_rules.user_id = viewer_id
+        AND filter_rules.action = 'exclude'
+        AND (filter_rules.feed_id IS NULL OR filter_rules.feed_id = post.feed_id)
+        AND filter_matches(filter_rules.field, filter_rules.pattern, filter_rules.is_regex, post)
+    )
+    AND (
+        NOT EXISTS (
+            SELECT 1 FROM filter_rules
+            WHERE filter_rules.user_id = viewer_id
+            AND filter_rules.action = 'include'
+            AND (filter_rules.feed_id IS NULL OR filter_rules.feed_id = post.feed_id)
+        )
+        OR EXISTS (
+            SELECT 1 FROM filter_rules
+            WHERE filter_rules.user_id = viewer_id
+            AND filter_rules.action = 'include'
+            AND (filter_rules.feed_id IS NULL OR filter_rules.feed_id = post.feed_id)
+            AND filter_matches(filter_rules.field, filter_rules.pattern, filter_rules.is_regex, post)
+        )
+    );
+$$ LANGUAGE SQL STABLE;
+-- +goose StatementEnd
+
+-- +goose Down
+DROP FUNCTION post_visible(UUID, posts);
+DROP FUNCTION filter_matches(TEXT, TEXT, BOOLEAN, posts);
+DROP TABLE filter_rules;
+
+ALTER TABLE posts
+DROP COLUMN categories;
+
+ALTER TABLE posts
+DROP COLUMN author;
@@ -0,0 +1,30 @@
+-- +goose Up
+CREATE TABLE folders (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    parent_id UUID REFERENCES folders(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    position INTEGER NOT NULL DEFAULT 0
+);
+
+-- Folder names are unique among their siblings
+CREATE UNIQUE INDEX folders_top_level_name_idx ON folders (user_id, name) WHERE parent_id IS NULL;
+CREATE UNIQUE INDEX folders_nested_name_idx ON folders (parent_id, name) WHERE parent_id IS NOT NULL;
+
+-- Deleting a folder moves its feeds back to the top level
+ALTER TABLE feed_follows
+ADD COLUMN folder_id UUID REFERENCES folders(id) ON DELETE SET NULL;
+
+ALTER TABLE feed_follows
+ADD COLUMN position INTEGER NOT NULL DEFAULT 0;
+
+-- +goose Down
+ALTER TABLE feed_follows
+DROP COLUMN position;
+
+ALTER TABLE feed_follows
+DROP COLUMN folder_id;
+
+DROP TABLE folders;
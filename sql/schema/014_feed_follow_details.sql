@@ -0,0 +1,19 @@
+-- +goose Up
+ALTER TABLE feed_follows
+ADD COLUMN title TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE feed_follows
+ADD COLUMN notes TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE feed_follows
+ADD COLUMN priority INTEGER NOT NULL DEFAULT 0;
+
+-- +goose Down
+ALTER TABLE feed_follows
+DROP COLUMN priority;
+
+ALTER TABLE feed_follows
+DROP COLUMN notes;
+
+ALTER TABLE feed_follows
+DROP COLUMN title;
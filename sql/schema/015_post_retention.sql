@@ -0,0 +1,53 @@
+-- +goose Up
+CREATE TABLE feed_retention_policies (
+    feed_id UUID PRIMARY KEY REFERENCES feeds(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    keep_last INTEGER CHECK (keep_last > 0),
+    keep_days INTEGER CHECK (keep_days > 0)
+);
+
+CREATE INDEX posts_feed_posted_at_idx ON posts (feed_id, (COALESCE(published_at, created_at)) DESC, id DESC);
+
+-- Posts no retention rule keeps. Each feed uses its own policy, falling back
+-- field by field to the given defaults; a post survives if it is among the
+-- last keep_last posts of its feed or newer than keep_days. Saved posts are
+-- never prunable.
+-- +goose StatementBegin
+CREATE FUNCTION prunable_posts(default_keep_last INTEGER, default_keep_days INTEGER, only_feed UUID)
+RETURNS TABLE (post_id UUID, feed_id UUID) AS $$
+    WITH policies AS (
+        SELECT
+            feeds.id AS feed_id,
+            COALESCE(p.keep_last, default_keep_last) AS keep_last,
+            COALESCE(p.keep_days, default_keep_days) AS keep_days
+        FROM feeds
+        LEFT JOIN feed_retention_policies AS p ON p.feed_id = feeds.id
+        WHERE only_feed IS NULL OR feeds.id = only_feed
+    ), ranked AS (
+        SELECT
+            posts.id,
+            posts.feed_id,
+            COALESCE(posts.published_at, posts.created_at) AS posted_at,
+            ROW_NUMBER() OVER (
+                PARTITION BY posts.feed_id
+                ORDER BY COALESCE(posts.published_at, posts.created_at) DESC, posts.id DESC
+            ) AS rank
+        FROM posts
+        WHERE only_feed IS NULL OR posts.feed_id = only_feed
+    )
+    SELECT ranked.id, ranked.feed_id FROM ranked
+    INNER JOIN policies ON policies.feed_id = ranked.feed_id
+    WHERE (policies.keep_last IS NOT NULL OR policies.keep_days IS NOT NULL)
+    AND (policies.keep_last IS NULL OR ranked.rank > policies.keep_last)
+    AND (policies.keep_days IS NULL OR ranked.posted_at < NOW() - make_interval(days => policies.keep_days))
+    AND NOT EXISTS (
+        SELECT 1 FROM saved_posts WHERE saved_posts.post_id = ranked.id
+    );
+$$ LANGUAGE SQL STABLE;
+-- +goose StatementEnd
+
+-- +goose Down
+DROP FUNCTION prunable_posts(INTEGER, INTEGER, UUID);
+DROP INDEX posts_feed_posted_at_idx;
+DROP TABLE feed_retention_policies;
@@ -0,0 +1,21 @@
+-- +goose Up
+ALTER TABLE feeds
+ADD COLUMN paused_at TIMESTAMP;
+
+-- Deleting a feed deletes its posts. Saved posts keep their own copy of the
+-- title, link and description, so they survive.
+ALTER TABLE posts
+DROP CONSTRAINT posts_feed_id_fkey;
+
+ALTER TABLE posts
+ADD CONSTRAINT posts_feed_id_fkey FOREIGN KEY (feed_id) REFERENCES feeds(id) ON DELETE CASCADE;
+
+-- +goose Down
+ALTER TABLE posts
+DROP CONSTRAINT posts_feed_id_fkey;
+
+ALTER TABLE posts
+ADD CONSTRAINT posts_feed_id_fkey FOREIGN KEY (feed_id) REFERENCES feeds(id);
+
+ALTER TABLE feeds
+DROP COLUMN paused_at;
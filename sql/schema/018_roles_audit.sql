@@ -0,0 +1,31 @@
+-- +goose Up
+ALTER TABLE users
+ADD COLUMN role TEXT NOT NULL DEFAULT 'member' CHECK (role IN ('admin', 'member'));
+
+-- Existing installs need someone who can run admin commands; pick the user
+-- who registered first
+UPDATE users
+SET role = 'admin'
+WHERE id = (SELECT id FROM users ORDER BY created_at LIMIT 1);
+
+-- Who ran which command. Events must outlive the users who caused them
+-- (reset deletes everyone), so actor_id is deliberately not a foreign key and
+-- the actor's name is copied.
+CREATE TABLE audit_events (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    actor_id UUID,
+    actor_name TEXT NOT NULL,
+    command TEXT NOT NULL,
+    args TEXT[] NOT NULL DEFAULT '{}',
+    outcome TEXT NOT NULL CHECK (outcome IN ('success', 'failure')),
+    error TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX audit_events_created_at_idx ON audit_events(created_at);
+
+-- +goose Down
+DROP TABLE audit_events;
+
+ALTER TABLE users
+DROP COLUMN role;
@@ -0,0 +1,14 @@
+-- name: CreateAuditEvent :exec
+INSERT INTO audit_events (id, created_at, actor_id, actor_name, command, args, outcome, error)
+VALUES (?1, strftime('%Y-%m-%d %H:%M:%f', 'now'), ?2, ?3, ?4, ?5, ?6, ?7);
+
+-- name: GetAuditEvents :many
+SELECT * FROM audit_events
+WHERE (?1 IS NULL OR actor_id = ?1)
+AND (?2 IS NULL OR actor_name = ?2)
+AND (?3 IS NULL OR command = ?3)
+AND (?4 IS NULL OR created_at >= ?4)
+AND (?5 IS NULL OR created_at < ?5)
+AND (NOT ?6 OR outcome = 'failure')
+ORDER BY created_at DESC
+LIMIT ?7;
@@ -0,0 +1,22 @@
+-- name: CreateFeedFetch :exec
+INSERT INTO feed_fetches (id, feed_id, fetched_at, status_code, duration_ms, bytes, items_seen, new_posts, error)
+VALUES (?1, ?2, strftime('%Y-%m-%d %H:%M:%f', 'now'), ?3, ?4, ?5, ?6, ?7, ?8);
+
+-- name: GetFeedFetchesSince :many
+SELECT * FROM feed_fetches
+WHERE fetched_at >= ?1
+ORDER BY fetched_at ASC;
+
+-- name: DeleteFeedFetchesBefore :execrows
+DELETE FROM feed_fetches
+WHERE fetched_at < ?1;
+
+-- name: GetLastFeedFetches :many
+-- SQLite takes the bare columns from the row holding the MAX
+SELECT id, feed_id, MAX(fetched_at) AS fetched_at, status_code, duration_ms, bytes, items_seen, new_posts, error FROM feed_fetches
+GROUP BY feed_id;
+
+-- name: GetLastSuccessfulFeedFetches :many
+SELECT id, feed_id, MAX(fetched_at) AS fetched_at, status_code, duration_ms, bytes, items_seen, new_posts, error FROM feed_fetches
+WHERE error IS NULL
+GROUP BY feed_id;
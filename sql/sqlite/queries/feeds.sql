@@ -0,0 +1,58 @@
+-- name: CreateFeed :one
+INSERT INTO feeds (id, created_at, updated_at, name, url, user_id)
+VALUES (?1, strftime('%Y-%m-%d %H:%M:%f', 'now'), strftime('%Y-%m-%d %H:%M:%f', 'now'), ?2, ?3, ?4)
+RETURNING *;
+
+-- name: GetFeeds :many
+SELECT feeds.*, users.name AS user_name FROM feeds
+INNER JOIN users ON feeds.user_id = users.id;
+
+-- name: GetFeed :one
+SELECT * FROM feeds WHERE url = ?1;
+
+-- name: MarkFeedFetched :exec
+UPDATE feeds
+SET last_fetched_at = strftime('%Y-%m-%d %H:%M:%f', 'now'), updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now')
+WHERE id = ?1;
+
+-- name: GetNextFeedToFetch :one
+SELECT * FROM feeds
+WHERE paused_at IS NULL
+ORDER BY last_fetched_at ASC NULLS FIRST
+LIMIT 1;
+
+-- name: CountFeedsDue :one
+SELECT COUNT(*) FROM feeds
+WHERE paused_at IS NULL
+AND (last_fetched_at IS NULL OR last_fetched_at < ?1);
+
+-- name: RenameFeed :exec
+UPDATE feeds
+SET name = ?2, updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now')
+WHERE id = ?1;
+
+-- name: UpdateFeedURL :exec
+UPDATE feeds
+SET url = ?2, hub_url = NULL, self_url = NULL, last_fetched_at = NULL, updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now')
+WHERE id = ?1;
+
+-- name: SetFeedPaused :exec
+UPDATE feeds
+SET paused_at = ?2, updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now')
+WHERE id = ?1;
+
+-- name: CountFeedPosts :one
+SELECT COUNT(*) FROM posts WHERE feed_id = ?1;
+
+-- name: DeleteFeed :exec
+DELETE FROM feeds WHERE id = ?1;
+
+-- name: SetFeedOwner :exec
+UPDATE feeds
+SET user_id = ?2, updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now')
+WHERE id = ?1;
+
+-- name: TransferFeeds :execrows
+UPDATE feeds
+SET user_id = ?1, updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now')
+WHERE user_id = ?2;
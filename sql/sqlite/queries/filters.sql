@@ -0,0 +1,29 @@
+-- name: CreateFilterRule :one
+INSERT INTO filter_rules (id, created_at, updated_at, user_id, name, action, field, pattern, is_regex, feed_id)
+VALUES (?1, strftime('%Y-%m-%d %H:%M:%f', 'now'), strftime('%Y-%m-%d %H:%M:%f', 'now'), ?2, ?3, ?4, ?5, ?6, ?7, ?8)
+RETURNING *;
+
+-- name: GetFilterRulesForUser :many
+SELECT filter_rules.*, feeds.name AS feed_name FROM filter_rules
+LEFT JOIN feeds ON feeds.id = filter_rules.feed_id
+WHERE filter_rules.user_id = ?1
+ORDER BY filter_rules.name;
+
+-- name: GetFilterRuleByName :one
+SELECT * FROM filter_rules
+WHERE user_id = ?1 AND name = ?2;
+
+-- name: DeleteFilterRule :execrows
+DELETE FROM filter_rules
+WHERE user_id = ?1 AND name = ?2;
+
+-- name: PreviewFilterRule :many
+SELECT posts.id, posts.title, posts.url, posts.published_at, feeds.name AS feed_name, COUNT(*) OVER () AS total
+FROM posts
+INNER JOIN feeds ON feeds.id = posts.feed_id
+INNER JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+WHERE feed_follows.user_id = ?1
+AND (?2 IS NULL OR posts.feed_id = ?2)
+AND filter_matches(?3, ?4, ?5, posts.title, posts.description, posts.content, posts.author, posts.categories) = ?6
+ORDER BY COALESCE(posts.published_at, posts.created_at) DESC
+LIMIT ?7;
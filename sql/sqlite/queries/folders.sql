@@ -0,0 +1,27 @@
+-- name: CreateFolder :one
+INSERT INTO folders (id, created_at, updated_at, user_id, parent_id, name, position)
+VALUES (?1, strftime('%Y-%m-%d %H:%M:%f', 'now'), strftime('%Y-%m-%d %H:%M:%f', 'now'), ?2, ?3, ?4, ?5)
+RETURNING *;
+
+-- name: GetFoldersForUser :many
+SELECT * FROM folders
+WHERE user_id = ?1
+ORDER BY position, name;
+
+-- name: DeleteFolder :execrows
+DELETE FROM folders
+WHERE id = ?1 AND user_id = ?2;
+
+-- name: MoveFolder :exec
+UPDATE folders
+SET parent_id = ?3, position = ?4, updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now')
+WHERE id = ?1 AND user_id = ?2;
+
+-- name: NextFolderPosition :one
+SELECT CAST(COALESCE(MAX(position) + 1, 0) AS INTEGER) AS position FROM folders
+WHERE user_id = ?1 AND parent_id IS ?2;
+
+-- name: ShiftFolderPositions :exec
+UPDATE folders
+SET position = position + 1
+WHERE user_id = ?1 AND parent_id IS ?2 AND position >= ?3;
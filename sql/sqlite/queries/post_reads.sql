@@ -0,0 +1,35 @@
+-- name: MarkPostRead :execrows
+INSERT INTO post_reads (user_id, post_id, read_at)
+VALUES (?1, ?2, strftime('%Y-%m-%d %H:%M:%f', 'now'))
+ON CONFLICT DO NOTHING;
+
+-- name: MarkPostUnread :execrows
+DELETE FROM post_reads
+WHERE user_id = ?1 AND post_id = ?2;
+
+-- name: MarkFeedRead :execrows
+INSERT INTO post_reads (user_id, post_id, read_at)
+SELECT ?1, posts.id, strftime('%Y-%m-%d %H:%M:%f', 'now') FROM posts
+WHERE posts.feed_id = ?2
+ON CONFLICT DO NOTHING;
+
+-- name: MarkFeedUnread :execrows
+DELETE FROM post_reads
+WHERE user_id = ?1
+AND post_id IN (SELECT id FROM posts WHERE feed_id = ?2);
+
+-- name: MarkPostsReadBefore :execrows
+INSERT INTO post_reads (user_id, post_id, read_at)
+SELECT ?1, posts.id, strftime('%Y-%m-%d %H:%M:%f', 'now') FROM posts
+INNER JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+WHERE feed_follows.user_id = ?1
+AND COALESCE(posts.published_at, posts.created_at) < ?2
+ON CONFLICT DO NOTHING;
+
+-- name: MarkPostsUnreadBefore :execrows
+DELETE FROM post_reads
+WHERE user_id = ?1
+AND post_id IN (
+    SELECT id FROM posts
+    WHERE COALESCE(published_at, created_at) < ?2
+);
@@ -0,0 +1,139 @@
+-- name: CreatePost :one
+INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id, content, author, categories)
+VALUES (
+    ?1, strftime('%Y-%m-%d %H:%M:%f', 'now'), strftime('%Y-%m-%d %H:%M:%f', 'now'), ?2, ?3, ?4, ?5, ?6, ?7, ?8, ?9
+)
+RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id, content, NULL AS search_vector, author, categories;
+
+-- name: GetPostsForUser :many
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.content, NULL AS search_vector, posts.author, posts.categories FROM posts
+INNER JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+WHERE feed_follows.user_id = ?1
+ORDER BY published_at DESC NULLS FIRST
+LIMIT ?2;
+
+-- name: GetPostByID :one
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, content, NULL AS search_vector, author, categories FROM posts WHERE id = ?1;
+
+-- name: GetPostByURL :one
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, content, NULL AS search_vector, author, categories FROM posts WHERE url = ?1;
+
+-- name: BrowsePostsNewestFirst :many
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.content, NULL AS search_vector, posts.author, posts.categories FROM posts
+INNER JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+WHERE feed_follows.user_id = ?1
+AND (?2 IS NULL OR posts.feed_id IN (SELECT value FROM json_each(?2)))
+AND (?3 IS NULL OR COALESCE(posts.published_at, posts.created_at) >= ?3)
+AND (?4 IS NULL OR COALESCE(posts.published_at, posts.created_at) < ?4)
+AND (NOT ?5 OR NOT EXISTS (
+    SELECT 1 FROM post_reads
+    WHERE post_reads.post_id = posts.id AND post_reads.user_id = ?1
+))
+AND (NOT ?6 OR (
+    NOT EXISTS (
+        SELECT 1 FROM filter_rules
+        WHERE filter_rules.user_id = ?1
+        AND filter_rules.action = 'exclude'
+        AND (filter_rules.feed_id IS NULL OR filter_rules.feed_id = posts.feed_id)
+        AND filter_matches(filter_rules.field, filter_rules.pattern, filter_rules.is_regex, posts.title, posts.description, posts.content, posts.author, posts.categories)
+    )
+    AND (
+        NOT EXISTS (
+            SELECT 1 FROM filter_rules
+            WHERE filter_rules.user_id = ?1
+            AND filter_rules.action = 'include'
+            AND (filter_rules.feed_id IS NULL OR filter_rules.feed_id = posts.feed_id)
+        )
+        OR EXISTS (
+            SELECT 1 FROM filter_rules
+            WHERE filter_rules.user_id = ?1
+            AND filter_rules.action = 'include'
+            AND (filter_rules.feed_id IS NULL OR filter_rules.feed_id = posts.feed_id)
+            AND filter_matches(filter_rules.field, filter_rules.pattern, filter_rules.is_regex, posts.title, posts.description, posts.content, posts.author, posts.categories)
+        )
+    )
+))
+AND (?7 IS NULL OR feed_follows.priority >= ?7)
+AND (
+    ?8 IS NULL
+    OR (COALESCE(posts.published_at, posts.created_at), posts.id) < (?8, ?9)
+)
+ORDER BY COALESCE(posts.published_at, posts.created_at) DESC, posts.id DESC
+LIMIT ?10;
+
+-- name: BrowsePostsOldestFirst :many
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.content, NULL AS search_vector, posts.author, posts.categories FROM posts
+INNER JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+WHERE feed_follows.user_id = ?1
+AND (?2 IS NULL OR posts.feed_id IN (SELECT value FROM json_each(?2)))
+AND (?3 IS NULL OR COALESCE(posts.published_at, posts.created_at) >= ?3)
+AND (?4 IS NULL OR COALESCE(posts.published_at, posts.created_at) < ?4)
+AND (NOT ?5 OR NOT EXISTS (
+    SELECT 1 FROM post_reads
+    WHERE post_reads.post_id = posts.id AND post_reads.user_id = ?1
+))
+AND (NOT ?6 OR (
+    NOT EXISTS (
+        SELECT 1 FROM filter_rules
+        WHERE filter_rules.user_id = ?1
+        AND filter_rules.action = 'exclude'
+        AND (filter_rules.feed_id IS NULL OR filter_rules.feed_id = posts.feed_id)
+        AND filter_matches(filter_rules.field, filter_rules.pattern, filter_rules.is_regex, posts.title, posts.description, posts.content, posts.author, posts.categories)
+    )
+    AND (
+        NOT EXISTS (
+            SELECT 1 FROM filter_rules
+            WHERE filter_rules.user_id = ?1
+            AND filter_rules.action = 'include'
+            AND (filter_rules.feed_id IS NULL OR filter_rules.feed_id = posts.feed_id)
+        )
+        OR EXISTS (
+            SELECT 1 FROM filter_rules
+            WHERE filter_rules.user_id = ?1
+            AND filter_rules.action = 'include'
+            AND (filter_rules.feed_id IS NULL OR filter_rules.feed_id = posts.feed_id)
+            AND filter_matches(filter_rules.field, filter_rules.pattern, filter_rules.is_regex, posts.title, posts.description, posts.content, posts.author, posts.categories)
+        )
+    )
+))
+AND (?7 IS NULL OR feed_follows.priority >= ?7)
+AND (
+    ?8 IS NULL
+    OR (COALESCE(posts.published_at, posts.created_at), posts.id) > (?8, ?9)
+)
+ORDER BY COALESCE(posts.published_at, posts.created_at) ASC, posts.id ASC
+LIMIT ?10;
+
+-- name: SearchPosts :many
+SELECT
+    posts.id,
+    posts.title,
+    posts.url,
+    posts.published_at,
+    feeds.name AS feed_name,
+    -bm25(posts_search, 0, 4.0, 2.0, 1.0) AS rank,
+    snippet(posts_search, -1, '**', '**', '...', 20) AS snippet
+FROM posts_search
+INNER JOIN posts ON posts.id = posts_search.post_id
+INNER JOIN feeds ON feeds.id = posts.feed_id
+WHERE posts_search MATCH ?1
+AND (NOT ?2 OR EXISTS (
+    SELECT 1 FROM feed_follows
+    WHERE feed_follows.feed_id = posts.feed_id AND feed_follows.user_id = ?3
+))
+AND (?4 IS NULL OR COALESCE(posts.published_at, posts.created_at) >= ?4)
+AND (?5 IS NULL OR COALESCE(posts.published_at, posts.created_at) < ?5)
+ORDER BY rank DESC, posts.published_at DESC
+LIMIT ?6;
+
+-- name: UpdatePost :execrows
+-- Refreshes a feed's post from its item, if the item has changed since
+UPDATE posts
+SET title = ?3, description = ?4, published_at = ?5, content = ?6, author = ?7, categories = ?8, updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now')
+WHERE url = ?1 AND feed_id = ?2
+AND (title IS NOT ?3 OR description IS NOT ?4 OR published_at IS NOT ?5 OR content IS NOT ?6 OR author IS NOT ?7 OR categories IS NOT ?8);
+
+-- name: GetLastPostTimes :many
+-- When each feed last got a new post
+SELECT feed_id, MAX(created_at) AS created_at FROM posts
+GROUP BY feed_id;
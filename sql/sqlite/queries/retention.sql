@@ -0,0 +1,88 @@
+-- name: SetFeedRetentionPolicy :exec
+INSERT INTO feed_retention_policies (feed_id, created_at, updated_at, keep_last, keep_days)
+VALUES (?1, strftime('%Y-%m-%d %H:%M:%f', 'now'), strftime('%Y-%m-%d %H:%M:%f', 'now'), ?2, ?3)
+ON CONFLICT (feed_id) DO UPDATE
+SET keep_last = excluded.keep_last, keep_days = excluded.keep_days, updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now');
+
+-- name: DeleteFeedRetentionPolicy :execrows
+DELETE FROM feed_retention_policies
+WHERE feed_id = ?1;
+
+-- name: GetFeedRetentionPolicies :many
+SELECT feed_retention_policies.*, feeds.name AS feed_name, feeds.url AS feed_url
+FROM feed_retention_policies
+INNER JOIN feeds ON feeds.id = feed_retention_policies.feed_id
+ORDER BY feeds.name;
+
+-- name: CountPrunablePosts :many
+WITH policies AS (
+    SELECT
+        feeds.id AS feed_id,
+        COALESCE(p.keep_last, ?1) AS keep_last,
+        COALESCE(p.keep_days, ?2) AS keep_days
+    FROM feeds
+    LEFT JOIN feed_retention_policies AS p ON p.feed_id = feeds.id
+    WHERE ?3 IS NULL OR feeds.id = ?3
+), ranked AS (
+    SELECT
+        posts.id,
+        posts.feed_id,
+        COALESCE(posts.published_at, posts.created_at) AS posted_at,
+        ROW_NUMBER() OVER (
+            PARTITION BY posts.feed_id
+            ORDER BY COALESCE(posts.published_at, posts.created_at) DESC, posts.id DESC
+        ) AS rank
+    FROM posts
+    WHERE ?3 IS NULL OR posts.feed_id = ?3
+), prunable AS (
+    SELECT ranked.id AS post_id, ranked.feed_id FROM ranked
+    INNER JOIN policies ON policies.feed_id = ranked.feed_id
+    WHERE (policies.keep_last IS NOT NULL OR policies.keep_days IS NOT NULL)
+    AND (policies.keep_last IS NULL OR ranked.rank > policies.keep_last)
+    AND (policies.keep_days IS NULL OR ranked.posted_at < strftime('%Y-%m-%d %H:%M:%f', 'now', '-' || policies.keep_days || ' days'))
+    AND NOT EXISTS (
+        SELECT 1 FROM saved_posts WHERE saved_posts.post_id = ranked.id
+    )
+)
+SELECT feeds.name AS feed_name, COUNT(*) AS posts
+FROM prunable
+INNER JOIN feeds ON feeds.id = prunable.feed_id
+GROUP BY feeds.id, feeds.name
+ORDER BY feeds.name;
+
+-- name: PrunePosts :many
+WITH policies AS (
+    SELECT
+        feeds.id AS feed_id,
+        COALESCE(p.keep_last, ?1) AS keep_last,
+        COALESCE(p.keep_days, ?2) AS keep_days
+    FROM feeds
+    LEFT JOIN feed_retention_policies AS p ON p.feed_id = feeds.id
+    WHERE ?3 IS NULL OR feeds.id = ?3
+), ranked AS (
+    SELECT
+        posts.id,
+        posts.feed_id,
+        COALESCE(posts.published_at, posts.created_at) AS posted_at,
+        ROW_NUMBER() OVER (
+            PARTITION BY posts.feed_id
+            ORDER BY COALESCE(posts.published_at, posts.created_at) DESC, posts.id DESC
+        ) AS rank
+    FROM posts
+    WHERE ?3 IS NULL OR posts.feed_id = ?3
+), prunable AS (
+    SELECT ranked.id AS post_id, ranked.feed_id FROM ranked
+    INNER JOIN policies ON policies.feed_id = ranked.feed_id
+    WHERE (policies.keep_last IS NOT NULL OR policies.keep_days IS NOT NULL)
+    AND (policies.keep_last IS NULL OR ranked.rank > policies.keep_last)
+    AND (policies.keep_days IS NULL OR ranked.posted_at < strftime('%Y-%m-%d %H:%M:%f', 'now', '-' || policies.keep_days || ' days'))
+    AND NOT EXISTS (
+        SELECT 1 FROM saved_posts WHERE saved_posts.post_id = ranked.id
+    )
+)
+DELETE FROM posts
+WHERE id IN (
+    SELECT post_id FROM prunable
+    LIMIT ?4
+)
+RETURNING feed_id, (SELECT name FROM feeds WHERE feeds.id = posts.feed_id) AS feed_name;
@@ -0,0 +1,26 @@
+-- name: SavePost :one
+INSERT INTO saved_posts (id, created_at, updated_at, user_id, post_id, title, url, description, published_at, feed_name, note, tags)
+VALUES (?1, strftime('%Y-%m-%d %H:%M:%f', 'now'), strftime('%Y-%m-%d %H:%M:%f', 'now'), ?2, ?3, ?4, ?5, ?6, ?7, ?8, ?9, ?10)
+ON CONFLICT (user_id, url) DO UPDATE
+SET note = COALESCE(NULLIF(excluded.note, ''), saved_posts.note),
+    tags = COALESCE(NULLIF(excluded.tags, '[]'), saved_posts.tags),
+    updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now')
+RETURNING *;
+
+-- name: UnsavePostByID :execrows
+DELETE FROM saved_posts
+WHERE user_id = ?1 AND (id = ?2 OR post_id = ?2);
+
+-- name: UnsavePostByURL :execrows
+DELETE FROM saved_posts
+WHERE user_id = ?1 AND url = ?2;
+
+-- name: GetSavedPostsForUser :many
+SELECT * FROM saved_posts
+WHERE user_id = ?1
+ORDER BY created_at DESC;
+
+-- name: GetSavedPostsForUserByTag :many
+SELECT * FROM saved_posts
+WHERE user_id = ?1 AND ?2 IN (SELECT value FROM json_each(tags))
+ORDER BY created_at DESC;
@@ -0,0 +1,26 @@
+-- name: CreateSession :exec
+INSERT INTO sessions (id, created_at, last_used_at, expires_at, user_id, token_hash)
+VALUES (?1, strftime('%Y-%m-%d %H:%M:%f', 'now'), strftime('%Y-%m-%d %H:%M:%f', 'now'), ?2, ?3, ?4);
+
+-- name: TouchSession :one
+UPDATE sessions
+SET last_used_at = strftime('%Y-%m-%d %H:%M:%f', 'now')
+WHERE token_hash = ?1
+AND expires_at > strftime('%Y-%m-%d %H:%M:%f', 'now')
+RETURNING user_id;
+
+-- name: GetUserByID :one
+SELECT * FROM users
+WHERE id = ?1;
+
+-- name: DeleteSession :exec
+DELETE FROM sessions
+WHERE token_hash = ?1;
+
+-- name: DeleteOtherSessions :exec
+DELETE FROM sessions
+WHERE user_id = ?1 AND token_hash <> ?2;
+
+-- name: DeleteExpiredSessions :execrows
+DELETE FROM sessions
+WHERE expires_at <= strftime('%Y-%m-%d %H:%M:%f', 'now');
@@ -0,0 +1,41 @@
+-- name: CreateUser :one
+INSERT INTO users (id, created_at, updated_at, name, password_hash, role)
+VALUES (
+    ?1, strftime('%Y-%m-%d %H:%M:%f', 'now'), strftime('%Y-%m-%d %H:%M:%f', 'now'), ?2, ?3,
+    CASE WHEN EXISTS (SELECT 1 FROM users) THEN 'member' ELSE 'admin' END
+)
+RETURNING *;
+
+-- name: GetUser :one
+SELECT * FROM users
+WHERE name = ?1;
+
+-- name: ResetUsers :exec
+DELETE FROM users;
+
+-- name: GetUsers :many
+SELECT * FROM users;
+
+-- name: SetUserPassword :exec
+UPDATE users
+SET password_hash = ?2, updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now')
+WHERE id = ?1;
+
+-- name: SetUserRole :exec
+UPDATE users
+SET role = ?2, updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now')
+WHERE id = ?1;
+
+-- name: CountAdmins :one
+SELECT COUNT(*) FROM users
+WHERE role = 'admin';
+
+-- name: RenameUser :one
+UPDATE users
+SET name = ?2, updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now')
+WHERE id = ?1
+RETURNING *;
+
+-- name: DeleteUser :exec
+DELETE FROM users
+WHERE id = ?1;
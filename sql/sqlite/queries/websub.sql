@@ -0,0 +1,33 @@
+-- name: GetFeedByID :one
+SELECT * FROM feeds WHERE id = ?1;
+
+-- name: SetFeedWebSubLinks :exec
+UPDATE feeds
+SET hub_url = ?2, self_url = ?3, updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now')
+WHERE id = ?1;
+
+-- name: GetPushFeeds :many
+SELECT * FROM feeds
+WHERE hub_url IS NOT NULL AND paused_at IS NULL;
+
+-- name: UpsertWebSubSubscription :one
+INSERT INTO websub_subscriptions (id, created_at, updated_at, feed_id, hub_url, topic_url, secret)
+VALUES (?1, strftime('%Y-%m-%d %H:%M:%f', 'now'), strftime('%Y-%m-%d %H:%M:%f', 'now'), ?2, ?3, ?4, ?5)
+ON CONFLICT (feed_id) DO UPDATE
+SET hub_url = excluded.hub_url,
+    topic_url = excluded.topic_url,
+    secret = excluded.secret,
+    verified_at = NULL,
+    updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now')
+RETURNING *;
+
+-- name: GetWebSubSubscription :one
+SELECT * FROM websub_subscriptions WHERE feed_id = ?1;
+
+-- name: MarkWebSubSubscriptionVerified :exec
+UPDATE websub_subscriptions
+SET verified_at = strftime('%Y-%m-%d %H:%M:%f', 'now'), lease_expires_at = ?2, updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now')
+WHERE feed_id = ?1;
+
+-- name: DeleteWebSubSubscription :exec
+DELETE FROM websub_subscriptions WHERE feed_id = ?1;
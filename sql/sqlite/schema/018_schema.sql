@@ -0,0 +1,244 @@
+-- +goose Up
+-- The whole schema as of Postgres migration 018, so both backends report the
+-- same schema version. Later changes need a migration here as well as in
+-- sql/schema.
+--
+-- SQLite has no UUID, array or tsvector types: UUIDs are stored as text,
+-- arrays as JSON arrays, and search uses the posts_search FTS5 table. Times
+-- are UTC text in the format strftime('%Y-%m-%d %H:%M:%f') produces, so they
+-- compare correctly as strings.
+CREATE TABLE users (
+    id TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    name TEXT UNIQUE NOT NULL,
+    password_hash TEXT,
+    role TEXT NOT NULL DEFAULT 'member' CHECK (role IN ('admin', 'member'))
+);
+
+CREATE TABLE feeds (
+    id TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    name TEXT NOT NULL,
+    url TEXT UNIQUE NOT NULL,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    last_fetched_at TIMESTAMP DEFAULT NULL,
+    hub_url TEXT DEFAULT NULL,
+    self_url TEXT DEFAULT NULL,
+    paused_at TIMESTAMP
+);
+
+CREATE TABLE folders (
+    id TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    parent_id TEXT REFERENCES folders(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    position INTEGER NOT NULL DEFAULT 0
+);
+
+-- Folder names are unique among their siblings
+CREATE UNIQUE INDEX folders_top_level_name_idx ON folders (user_id, name) WHERE parent_id IS NULL;
+CREATE UNIQUE INDEX folders_nested_name_idx ON folders (parent_id, name) WHERE parent_id IS NOT NULL;
+
+CREATE TABLE feed_follows (
+    id TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    feed_id TEXT NOT NULL REFERENCES feeds(id) ON DELETE CASCADE,
+    -- Deleting a folder moves its feeds back to the top level
+    folder_id TEXT REFERENCES folders(id) ON DELETE SET NULL,
+    position INTEGER NOT NULL DEFAULT 0,
+    title TEXT NOT NULL DEFAULT '',
+    notes TEXT NOT NULL DEFAULT '',
+    priority INTEGER NOT NULL DEFAULT 0,
+    UNIQUE(user_id, feed_id)
+);
+
+CREATE TABLE posts (
+    id TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    title TEXT NOT NULL,
+    url TEXT UNIQUE NOT NULL,
+    description TEXT NOT NULL,
+    published_at TIMESTAMP,
+    -- Deleting a feed deletes its posts; saved posts keep their own copy
+    feed_id TEXT NOT NULL REFERENCES feeds(id) ON DELETE CASCADE,
+    content TEXT NOT NULL DEFAULT '',
+    author TEXT NOT NULL DEFAULT '',
+    categories TEXT NOT NULL DEFAULT '[]'
+);
+
+CREATE INDEX posts_feed_posted_at_idx ON posts (feed_id, COALESCE(published_at, created_at) DESC, id DESC);
+
+-- Full-text index over posts, kept in step by the triggers below
+CREATE VIRTUAL TABLE posts_search USING fts5 (
+    post_id UNINDEXED,
+    title,
+    description,
+    content,
+    tokenize = 'porter unicode61'
+);
+
+-- +goose StatementBegin
+CREATE TRIGGER posts_search_insert AFTER INSERT ON posts BEGIN
+    INSERT INTO posts_search (post_id, title, description, content)
+    VALUES (new.id, new.title, new.description, new.content);
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER posts_search_update AFTER UPDATE OF title, description, content ON posts BEGIN
+    UPDATE posts_search
+    SET title = new.title, description = new.description, content = new.content
+    WHERE post_id = new.id;
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER posts_search_delete AFTER DELETE ON posts BEGIN
+    DELETE FROM posts_search WHERE post_id = old.id;
+END;
+-- +goose StatementEnd
+
+CREATE TABLE websub_subscriptions (
+    id TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    feed_id TEXT UNIQUE NOT NULL REFERENCES feeds(id) ON DELETE CASCADE,
+    hub_url TEXT NOT NULL,
+    topic_url TEXT NOT NULL,
+    secret TEXT NOT NULL,
+    verified_at TIMESTAMP DEFAULT NULL,
+    lease_expires_at TIMESTAMP DEFAULT NULL
+);
+
+CREATE TABLE feed_fetches (
+    id TEXT PRIMARY KEY,
+    feed_id TEXT NOT NULL REFERENCES feeds(id) ON DELETE CASCADE,
+    fetched_at TIMESTAMP NOT NULL,
+    status_code INTEGER,
+    duration_ms INTEGER NOT NULL,
+    bytes INTEGER NOT NULL,
+    items_seen INTEGER NOT NULL,
+    new_posts INTEGER NOT NULL,
+    error TEXT
+);
+
+CREATE INDEX feed_fetches_fetched_at_idx ON feed_fetches (fetched_at);
+
+CREATE TABLE post_reads (
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    post_id TEXT NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    read_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (user_id, post_id)
+);
+
+-- Saved posts keep their own copy of the post so they outlive retention
+-- pruning of the posts table
+CREATE TABLE saved_posts (
+    id TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    post_id TEXT REFERENCES posts(id) ON DELETE SET NULL,
+    title TEXT NOT NULL,
+    url TEXT NOT NULL,
+    description TEXT NOT NULL,
+    published_at TIMESTAMP,
+    feed_name TEXT NOT NULL,
+    note TEXT NOT NULL DEFAULT '',
+    tags TEXT NOT NULL DEFAULT '[]',
+    UNIQUE(user_id, url)
+);
+
+CREATE TABLE alert_rules (
+    id TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    kind TEXT NOT NULL CHECK (kind IN ('keyword', 'regex', 'author', 'feed')),
+    pattern TEXT NOT NULL,
+    notifier TEXT NOT NULL,
+    target TEXT NOT NULL DEFAULT '',
+    UNIQUE(user_id, name)
+);
+
+CREATE TABLE post_alerts (
+    post_id TEXT NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    rule_id TEXT NOT NULL REFERENCES alert_rules(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (post_id, rule_id)
+);
+
+-- Matching uses the filter_matches function the driver registers, since
+-- SQLite has no SQL-defined functions
+CREATE TABLE filter_rules (
+    id TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    action TEXT NOT NULL CHECK (action IN ('exclude', 'include')),
+    field TEXT NOT NULL CHECK (field IN ('title', 'content', 'category', 'author', 'any')),
+    pattern TEXT NOT NULL,
+    is_regex BOOLEAN NOT NULL DEFAULT FALSE,
+    feed_id TEXT REFERENCES feeds(id) ON DELETE CASCADE,
+    UNIQUE(user_id, name)
+);
+
+CREATE TABLE feed_retention_policies (
+    feed_id TEXT PRIMARY KEY REFERENCES feeds(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    keep_last INTEGER CHECK (keep_last > 0),
+    keep_days INTEGER CHECK (keep_days > 0)
+);
+
+CREATE TABLE sessions (
+    id TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    last_used_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    -- SHA-256 of the token; the token itself only lives in the user's config
+    token_hash TEXT UNIQUE NOT NULL
+);
+
+-- Who ran which command. actor_id is deliberately not a foreign key so events
+-- outlive the users who caused them.
+CREATE TABLE audit_events (
+    id TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    actor_id TEXT,
+    actor_name TEXT NOT NULL,
+    command TEXT NOT NULL,
+    args TEXT NOT NULL DEFAULT '[]',
+    outcome TEXT NOT NULL CHECK (outcome IN ('success', 'failure')),
+    error TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX audit_events_created_at_idx ON audit_events(created_at);
+
+-- +goose Down
+DROP TABLE audit_events;
+DROP TABLE sessions;
+DROP TABLE feed_retention_policies;
+DROP TABLE filter_rules;
+DROP TABLE post_alerts;
+DROP TABLE alert_rules;
+DROP TABLE saved_posts;
+DROP TABLE post_reads;
+DROP TABLE feed_fetches;
+DROP TABLE websub_subscriptions;
+DROP TABLE posts_search;
+DROP TABLE posts;
+DROP TABLE feed_follows;
+DROP TABLE folders;
+DROP TABLE feeds;
+DROP TABLE users;
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"database/sql"
+	"embed"
+	"errors"
+	"io/fs"
+	"strings"
+
+	"github.com/matt-horst/blog-agg/internal/database"
+
+	"github.com/lib/pq"
+	"github.com/pressly/goose/v3"
+)
+
+//go:embed sql/sqlite/queries/*.sql
+var sqliteQueryFiles embed.FS
+
+// Opens the database db_url points at: a SQLite file for sqlite: URLs,
+// otherwise a Postgres server
+func openDatabase(dbURL string) (*sql.DB, database.Store, goose.Dialect, error) {
+	path, ok := sqlitePath(dbURL)
+	if !ok {
+		db, err := sql.Open("postgres", dbURL)
+		if err != nil {
+			return nil, nil, "", err
+		}
+		return db, database.New(db), goose.DialectPostgres, nil
+	}
+
+	db, err := database.OpenSQLite(path)
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	queries, err := fs.Sub(sqliteQueryFiles, "sql/sqlite/queries")
+	if err != nil {
+		db.Close()
+		return nil, nil, "", err
+	}
+	store, err := database.NewSQLite(db, path, queries)
+	if err != nil {
+		db.Close()
+		return nil, nil, "", err
+	}
+
+	return db, store, goose.DialectSQLite3, nil
+}
+
+func closeDatabase(db *sql.DB) {
+	if db != nil {
+		db.Close()
+	}
+}
+
+// The database file of a sqlite: URL. sqlite:///abs/path.db and
+// sqlite:relative/path.db are both accepted.
+func sqlitePath(dbURL string) (string, bool) {
+	path, ok := strings.CutPrefix(dbURL, "sqlite:")
+	if !ok {
+		return "", false
+	}
+
+	return strings.TrimPrefix(path, "//"), true
+}
+
+// Whether err comes from inserting a row that would break a unique constraint,
+// on any backend
+func isUniqueViolation(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if errors.Is(err, database.ErrUniqueViolation) {
+		return true
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code.Name() == "unique_violation"
+	}
+
+	return database.IsSQLiteUniqueViolation(err)
+}
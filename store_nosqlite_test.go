@@ -0,0 +1,16 @@
+//go:build !sqlite
+
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOpenDatabaseWithoutSQLite(t *testing.T) {
+	_, _, _, err := openDatabase("sqlite:" + filepath.Join(t.TempDir(), "gator.db"))
+	if err == nil || !strings.Contains(err.Error(), "without SQLite support") {
+		t.Errorf("Opening a SQLite database returned %v, want an error saying it isn't supported", err)
+	}
+}
@@ -0,0 +1,311 @@
+//go:build sqlite
+
+package main
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/matt-horst/blog-agg/internal/config"
+	"github.com/matt-horst/blog-agg/internal/database"
+
+	"github.com/google/uuid"
+)
+
+// A state backed by a migrated SQLite database in a temporary directory
+func newSQLiteTestState(t *testing.T) *state {
+	t.Helper()
+
+	db, store, dialect, err := openDatabase("sqlite:" + filepath.Join(t.TempDir(), "gator.db"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+	})
+
+	s := &state{cfg: &config.Config{}, db: store, sqlDB: db, dialect: dialect}
+
+	provider, err := newMigrator(s)
+	if err != nil {
+		t.Fatalf("Failed to load migrations: %v", err)
+	}
+	_, err = provider.Up(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+
+	return s
+}
+
+// The SQLite driver's constraint errors are told apart by their extended
+// result codes, so a foreign key violation isn't taken for a duplicate
+func TestSQLiteUniqueViolation(t *testing.T) {
+	ctx := context.Background()
+	s := newSQLiteTestState(t)
+	alice := createTestUser(t, s, "alice")
+
+	_, err := s.db.CreateUser(ctx, database.CreateUserParams{ID: uuid.New(), Name: "alice"})
+	if !isUniqueViolation(err) {
+		t.Errorf("Creating a user with a taken name gave %v, want a unique violation", err)
+	}
+
+	_, err = s.db.CreateUser(ctx, database.CreateUserParams{ID: alice.ID, Name: "bob"})
+	if !isUniqueViolation(err) {
+		t.Errorf("Creating a user with a taken ID gave %v, want a unique violation", err)
+	}
+
+	_, err = s.db.CreateFeedFollow(ctx, database.CreateFeedFollowParams{ID: uuid.New(), UserID: alice.ID, FeedID: uuid.New()})
+	if err == nil || isUniqueViolation(err) {
+		t.Errorf("Following a missing feed gave %v, want a foreign key violation", err)
+	}
+}
+
+// Unchanged items must compare equal to the rows saved from them
+func TestSQLiteDuplicatePosts(t *testing.T) {
+	ctx := context.Background()
+	s := newSQLiteTestState(t)
+	alice := createTestUser(t, s, "alice")
+	feed := createTestFeed(t, s, alice, "Test", "https://example.com/rss")
+
+	fetched := &RSSFeed{}
+	fetched.Channel.Item = []RSSItem{
+		{Title: "One", Link: "https://example.com/1", PubDate: "Mon, 02 Jan 2006 15:04:05 -0700"},
+		{Title: "Two", Link: "https://example.com/2"},
+	}
+
+	result := savePosts(ctx, s, feed, fetched)
+	if result != (scrapeResult{newPosts: 2}) {
+		t.Errorf("First save returned %+v, want 2 new posts", result)
+	}
+
+	result = savePosts(ctx, s, feed, fetched)
+	if result != (scrapeResult{duplicates: 2}) {
+		t.Errorf("Second save returned %+v, want 2 duplicates", result)
+	}
+
+	fetched.Channel.Item[0].Title = "One, revised"
+	result = savePosts(ctx, s, feed, fetched)
+	if result != (scrapeResult{updated: 1, duplicates: 1}) {
+		t.Errorf("Save of a changed item returned %+v, want 1 updated and 1 duplicate", result)
+	}
+
+	post, err := s.db.GetPostByURL(ctx, "https://example.com/1")
+	if err != nil || post.Title != "One, revised" {
+		t.Errorf("Changed post has title %q (%v)", post.Title, err)
+	}
+}
+
+// Saving a post again only replaces the note and tags it's given
+func TestSQLiteResave(t *testing.T) {
+	ctx := context.Background()
+	s := newSQLiteTestState(t)
+	alice := createTestUser(t, s, "alice")
+	feed := createTestFeed(t, s, alice, "Test", "https://example.com/rss")
+
+	fetched := &RSSFeed{}
+	fetched.Channel.Item = []RSSItem{{Title: "One", Link: "https://example.com/1"}}
+	savePosts(ctx, s, feed, fetched)
+
+	saves := []struct {
+		args []string
+		note string
+		tags []string
+	}{
+		{args: []string{"--note", "read later", "--tags", "go,web", "https://example.com/1"}, note: "read later", tags: []string{"go", "web"}},
+		{args: []string{"https://example.com/1"}, note: "read later", tags: []string{"go", "web"}},
+		{args: []string{"--note", "done", "https://example.com/1"}, note: "done", tags: []string{"go", "web"}},
+		{args: []string{"--tags", "archive", "https://example.com/1"}, note: "done", tags: []string{"archive"}},
+	}
+	for _, save := range saves {
+		err := handlerSave(ctx, s, command{name: "save", args: save.args}, alice)
+		if err != nil {
+			t.Fatalf("Failed to save with %q: %v", save.args, err)
+		}
+
+		saved, err := s.db.GetSavedPostsForUser(ctx, alice.ID)
+		if err != nil || len(saved) != 1 {
+			t.Fatalf("Got %d saved posts, %v; want 1", len(saved), err)
+		}
+		if saved[0].Note != save.note || !slices.Equal(saved[0].Tags, save.tags) {
+			t.Errorf("After saving with %q: note %q, tags %q; want %q, %q", save.args, saved[0].Note, saved[0].Tags, save.note, save.tags)
+		}
+	}
+}
+
+// Folder names from the file are kept whole, even with a slash in them
+func TestSQLiteImportFolders(t *testing.T) {
+	ctx := context.Background()
+	s := newSQLiteTestState(t)
+	alice := createTestUser(t, s, "alice")
+
+	path := filepath.Join(t.TempDir(), "feeds.opml")
+	err := os.WriteFile(path, []byte(`<opml version="2.0"><body>
+		<outline text="News/Politics">
+			<outline text="Daily" xmlUrl="https://news.example.com/rss"></outline>
+		</outline>
+		<outline text="Tech">
+			<outline text="Go">
+				<outline text="Go Blog" xmlUrl="https://go.dev/blog/feed.atom"></outline>
+			</outline>
+		</outline>
+	</body></opml>`), 0644)
+	if err != nil {
+		t.Fatalf("Failed to write OPML: %v", err)
+	}
+
+	err = handlerImport(ctx, s, command{name: "import", args: []string{path}}, alice)
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+
+	tree, err := loadFolders(ctx, s.db, alice.ID)
+	if err != nil {
+		t.Fatalf("Failed to load folders: %v", err)
+	}
+	news, ok := tree.child(uuid.NullUUID{}, "News/Politics")
+	if !ok {
+		t.Fatalf("No top-level `News/Politics` folder in %+v", tree.folders)
+	}
+	if _, ok := tree.child(uuid.NullUUID{}, "News"); ok {
+		t.Error("`News/Politics` was split into nested folders")
+	}
+	tech, _ := tree.child(uuid.NullUUID{}, "Tech")
+	golang, ok := tree.child(nullFolderID(tech), "Go")
+	if !ok {
+		t.Fatalf("No `Tech` > `Go` folder in %+v", tree.folders)
+	}
+
+	following, err := s.db.GetFeedFollowsForUser(ctx, alice.Name)
+	if err != nil {
+		t.Fatalf("Failed to get follows: %v", err)
+	}
+	folders := map[string]uuid.NullUUID{}
+	for _, f := range following {
+		folders[f.FeedUrl] = f.FolderID
+	}
+	if folders["https://news.example.com/rss"] != nullFolderID(news) || folders["https://go.dev/blog/feed.atom"] != nullFolderID(golang) {
+		t.Errorf("Feeds filed in %v", folders)
+	}
+}
+
+// The status of a feed goes by its whole history, even with a window too short
+// to include its last success or post
+func TestSQLiteHealthWindow(t *testing.T) {
+	ctx := context.Background()
+	s := newSQLiteTestState(t)
+	alice := createTestUser(t, s, "alice")
+	quiet := createTestFeed(t, s, alice, "Quiet", "https://quiet.example.com/rss")
+	dead := createTestFeed(t, s, alice, "Dead", "https://dead.example.com/rss")
+
+	fetch := func(feed database.Feed, errText string, age string) {
+		t.Helper()
+
+		id := uuid.New()
+		err := s.db.CreateFeedFetch(ctx, database.CreateFeedFetchParams{ID: id, FeedID: feed.ID, Error: sql.NullString{String: errText, Valid: errText != ""}})
+		if err != nil {
+			t.Fatalf("Failed to record fetch: %v", err)
+		}
+		_, err = s.sqlDB.ExecContext(ctx, "UPDATE feed_fetches SET fetched_at = strftime('%Y-%m-%d %H:%M:%f', 'now', ?1) WHERE id = ?2", age, id.String())
+		if err != nil {
+			t.Fatalf("Failed to backdate fetch: %v", err)
+		}
+		err = s.db.MarkFeedFetched(ctx, feed.ID)
+		if err != nil {
+			t.Fatalf("Failed to mark feed fetched: %v", err)
+		}
+	}
+
+	_, err := s.sqlDB.ExecContext(ctx, "UPDATE feeds SET created_at = strftime('%Y-%m-%d %H:%M:%f', 'now', '-30 days')")
+	if err != nil {
+		t.Fatalf("Failed to backdate feeds: %v", err)
+	}
+
+	fetch(quiet, "", "-2 days")
+	post, err := s.db.CreatePost(ctx, database.CreatePostParams{ID: uuid.New(), Title: "Last", Url: "https://quiet.example.com/last", FeedID: quiet.ID, Categories: []string{}})
+	if err != nil {
+		t.Fatalf("Failed to create post: %v", err)
+	}
+	_, err = s.sqlDB.ExecContext(ctx, "UPDATE posts SET created_at = strftime('%Y-%m-%d %H:%M:%f', 'now', '-2 days') WHERE id = ?1", post.ID.String())
+	if err != nil {
+		t.Fatalf("Failed to backdate post: %v", err)
+	}
+	fetch(quiet, "timeout", "-1 hours")
+
+	fetch(dead, "", "-10 days")
+	fetch(dead, "404 Not Found", "-1 hours")
+
+	out := captureStdout(t, func() {
+		err = handlerHealth(ctx, s, command{name: "health", args: []string{"--days", "3", "--window", "1"}})
+	})
+	if err != nil {
+		t.Fatalf("health failed: %v", err)
+	}
+
+	if !strings.Contains(out, "Failing for 3+ days: 1\n* Dead (https://dead.example.com/rss): 404 Not Found\n") {
+		t.Errorf("health printed:\n%s\nwant only Dead failing", out)
+	}
+	if !strings.Contains(out, "No new posts for 3+ days: 0\n") {
+		t.Errorf("health printed:\n%s\nwant Quiet not silent", out)
+	}
+}
+
+// health reads tables that migrations create, so it needs the schema check
+// to point at `migrate up` instead of failing on a missing table
+func TestSQLiteSchemaCheck(t *testing.T) {
+	ctx := context.Background()
+
+	db, store, dialect, err := openDatabase("sqlite:" + filepath.Join(t.TempDir(), "gator.db"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+	})
+	s := &state{cfg: &config.Config{}, db: store, sqlDB: db, dialect: dialect}
+
+	if skipSchemaCheck["health"] {
+		t.Fatal("health skips the schema check")
+	}
+	err = checkSchemaVersion(ctx, s)
+	if err == nil || !strings.Contains(err.Error(), "migrate up") {
+		t.Errorf("Schema check of an empty database returned %v, want a hint to migrate", err)
+	}
+
+	provider, err := newMigrator(s)
+	if err != nil {
+		t.Fatalf("Failed to load migrations: %v", err)
+	}
+	_, err = provider.Up(ctx)
+	if err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+
+	err = checkSchemaVersion(ctx, s)
+	if err != nil {
+		t.Errorf("Schema check after migrating failed: %v", err)
+	}
+	captureStdout(t, func() {
+		err = handlerHealth(ctx, s, command{name: "health"})
+	})
+	if err != nil {
+		t.Errorf("health failed on a migrated database: %v", err)
+	}
+}
+
+func TestSQLitePrune(t *testing.T) {
+	testPrune(t, newSQLiteTestState(t))
+}
+
+func TestSQLiteSearch(t *testing.T) {
+	testSearch(t, newSQLiteTestState(t))
+}
+
+func TestSQLiteFilters(t *testing.T) {
+	testFilters(t, newSQLiteTestState(t))
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/matt-horst/blog-agg/internal/database"
+
+	"github.com/lib/pq"
+)
+
+func TestIsUniqueViolation(t *testing.T) {
+	tests := []struct {
+		name string
+		err error
+		want bool
+	}{
+		{name: "none", err: nil, want: false},
+		{name: "postgres", err: fmt.Errorf("Failed: %w", &pq.Error{Code: "23505"}), want: true},
+		{name: "postgres foreign key", err: &pq.Error{Code: "23503"}, want: false},
+		{name: "message only", err: errors.New("constraint failed: UNIQUE constraint failed: posts.url (2067)"), want: false},
+		{name: "memory", err: fmt.Errorf("%w: posts.url", database.ErrUniqueViolation), want: true},
+		{name: "other", err: errors.New("connection refused"), want: false},
+	}
+	for _, tt := range tests {
+		if got := isUniqueViolation(tt.err); got != tt.want {
+			t.Errorf("isUniqueViolation(%s) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSQLitePath(t *testing.T) {
+	tests := []struct {
+		url string
+		want string
+		ok bool
+	}{
+		{url: "sqlite:///var/lib/gator.db", want: "/var/lib/gator.db", ok: true},
+		{url: "sqlite:gator.db", want: "gator.db", ok: true},
+		{url: "postgres://localhost/gator", ok: false},
+	}
+	for _, tt := range tests {
+		got, ok := sqlitePath(tt.url)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("sqlitePath(%q) = %q, %v; want %q, %v", tt.url, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/matt-horst/blog-agg/internal/database"
+)
+
+// Admins may manage anyone; everyone else only themselves
+func canManageUser(user, target database.User) bool {
+	return user.ID == target.ID || isAdmin(user)
+}
+
+func handlerRenameUser(ctx context.Context, s *state, cmd command, user database.User) error {
+	if len(cmd.args) != 2 {
+		return fmt.Errorf("renameuser requires two arguments: name new-name")
+	}
+
+	target, err := s.db.GetUser(ctx, cmd.args[0])
+	if err != nil {
+		return fmt.Errorf("Unable to find user `%s`: %v", cmd.args[0], err)
+	}
+	if !canManageUser(user, target) {
+		return fmt.Errorf("Only `%s` or an admin can rename `%s`", target.Name, target.Name)
+	}
+
+	renamed, err := s.db.RenameUser(ctx, database.RenameUserParams{ID: target.ID, Name: cmd.args[1]})
+	if err != nil {
+		return fmt.Errorf("Failed to rename `%s` to `%s`: %v", target.Name, cmd.args[1], err)
+	}
+
+	// Sessions follow the user's ID, so only the name in the config changes
+	if s.cfg.CurrentUserName == target.Name {
+		err = s.cfg.SetSession(renamed.Name, s.cfg.SessionToken)
+		if err != nil {
+			return fmt.Errorf("Renamed `%s`, but failed to update the config: %v", target.Name, err)
+		}
+		s.cfg.CurrentUserName = renamed.Name
+	}
+
+	fmt.Printf("Renamed `%s` to `%s`\n", target.Name, renamed.Name)
+
+	return nil
+}
+
+// Deletes a user along with their follows, folders and other data. The feeds
+// they added are shared, so they're given to another user rather than
+// deleted.
+func handlerDeleteUser(ctx context.Context, s *state, cmd command, user database.User) error {
+	fs := flag.NewFlagSet("deleteuser", flag.ContinueOnError)
+	transferTo := fs.String("transfer-to", "", "user to give the deleted user's feeds to (default: you, or an admin when deleting yourself)")
+	yes := fs.Bool("yes", false, "don't ask for confirmation")
+
+	positional, err := parseFlags(fs, cmd.args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return fmt.Errorf("deleteuser requires a user name")
+	}
+
+	target, err := s.db.GetUser(ctx, positional[0])
+	if err != nil {
+		return fmt.Errorf("Unable to find user `%s`: %v", positional[0], err)
+	}
+	if !canManageUser(user, target) {
+		return fmt.Errorf("Only `%s` or an admin can delete `%s`", target.Name, target.Name)
+	}
+
+	if isAdmin(target) {
+		admins, err := s.db.CountAdmins(ctx)
+		if err != nil {
+			return fmt.Errorf("Failed to count admins: %v", err)
+		}
+		if admins <= 1 {
+			return fmt.Errorf("`%s` is the last admin; make someone else an admin first", target.Name)
+		}
+	}
+
+	heir, err := feedHeir(ctx, s, user, target, *transferTo)
+	if err != nil {
+		return err
+	}
+
+	err = confirm(fmt.Sprintf("delete `%s` and give their feeds to `%s`", target.Name, heir.Name), target.Name, *yes)
+	if err != nil {
+		return err
+	}
+
+	var transferred int64
+	err = s.db.InTx(ctx, func(q database.Store) error {
+		transferred, err = q.TransferFeeds(ctx, database.TransferFeedsParams{ToUserID: heir.ID, FromUserID: target.ID})
+		if err != nil {
+			return fmt.Errorf("Failed to transfer feeds of `%s`: %v", target.Name, err)
+		}
+
+		err = q.DeleteUser(ctx, target.ID)
+		if err != nil {
+			return fmt.Errorf("Failed to delete `%s`: %v", target.Name, err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if s.cfg.CurrentUserName == target.Name {
+		err = s.cfg.SetSession("", "")
+		if err != nil {
+			return fmt.Errorf("Deleted `%s`, but failed to clear the session: %v", target.Name, err)
+		}
+		s.cfg.CurrentUserName = ""
+		s.cfg.SessionToken = ""
+	}
+
+	fmt.Printf("Deleted `%s`; %d feeds now belong to `%s`\n", target.Name, transferred, heir.Name)
+
+	return nil
+}
+
+// Who gets the feeds of a deleted user: the named user if given, otherwise
+// the admin deleting them, otherwise the longest-standing other admin
+func feedHeir(ctx context.Context, s *state, user, target database.User, name string) (database.User, error) {
+	if name != "" {
+		heir, err := s.db.GetUser(ctx, name)
+		if err != nil {
+			return database.User{}, fmt.Errorf("Unable to find user `%s`: %v", name, err)
+		}
+		if heir.ID == target.ID {
+			return database.User{}, fmt.Errorf("Cannot transfer feeds of `%s` to themselves", target.Name)
+		}
+		return heir, nil
+	}
+
+	if user.ID != target.ID {
+		return user, nil
+	}
+
+	users, err := s.db.GetUsers(ctx)
+	if err != nil {
+		return database.User{}, fmt.Errorf("Failed to get users from database: %v", err)
+	}
+
+	heir := database.User{}
+	for _, u := range users {
+		if u.ID == target.ID || !isAdmin(u) {
+			continue
+		}
+		if heir.Name == "" || u.CreatedAt.Before(heir.CreatedAt) {
+			heir = u
+		}
+	}
+	if heir.Name == "" {
+		return database.User{}, fmt.Errorf("No admin to give the feeds of `%s` to; use --transfer-to", target.Name)
+	}
+
+	return heir, nil
+}
+
+func handlerTransferFeed(ctx context.Context, s *state, cmd command, user database.User) error {
+	if len(cmd.args) != 2 {
+		return fmt.Errorf("transferfeed requires two arguments: url user")
+	}
+
+	feed, err := findManagedFeed(ctx, s, user, cmd.args[0])
+	if err != nil {
+		return err
+	}
+
+	owner, err := s.db.GetUser(ctx, cmd.args[1])
+	if err != nil {
+		return fmt.Errorf("Unable to find user `%s`: %v", cmd.args[1], err)
+	}
+
+	err = s.db.SetFeedOwner(ctx, database.SetFeedOwnerParams{ID: feed.ID, UserID: owner.ID})
+	if err != nil {
+		return fmt.Errorf("Failed to transfer `%s`: %v", feed.Name, err)
+	}
+
+	fmt.Printf("`%s` now belongs to `%s`\n", feed.Name, owner.Name)
+
+	return nil
+}
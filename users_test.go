@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/matt-horst/blog-agg/internal/config"
+	"github.com/matt-horst/blog-agg/internal/database"
+)
+
+func TestRenameUser(t *testing.T) {
+	ctx := context.Background()
+	s := newTestState(t)
+	alice := createTestUser(t, s, "alice")
+	bob := createTestUser(t, s, "bob")
+	createTestUser(t, s, "carol")
+
+	err := s.cfg.SetSession("bob", "token")
+	if err != nil {
+		t.Fatalf("Failed to set session: %v", err)
+	}
+	s.cfg.CurrentUserName, s.cfg.SessionToken = "bob", "token"
+
+	out, err := runHandler(t, handlerRenameUser, s, bob, "bob", "robert")
+	if err != nil {
+		t.Fatalf("Failed to rename self: %v", err)
+	}
+	if out != "Renamed `bob` to `robert`\n" {
+		t.Errorf("renameuser printed %q", out)
+	}
+	saved, err := config.Read()
+	if err != nil {
+		t.Fatalf("Failed to read config: %v", err)
+	}
+	for _, cfg := range []config.Config{*s.cfg, saved} {
+		if cfg.CurrentUserName != "robert" || cfg.SessionToken != "token" {
+			t.Errorf("Config has user `%s` and token %q after renaming, want `robert` and the same session", cfg.CurrentUserName, cfg.SessionToken)
+		}
+	}
+
+	_, err = runHandler(t, handlerRenameUser, s, bob, "carol", "mallory")
+	if err == nil || !strings.Contains(err.Error(), "Only `carol` or an admin") {
+		t.Errorf("Member renamed someone else: %v", err)
+	}
+	_, err = runHandler(t, handlerRenameUser, s, alice, "carol", "alice")
+	if err == nil {
+		t.Error("Renamed a user to a taken name")
+	}
+
+	_, err = runHandler(t, handlerRenameUser, s, alice, "carol", "caroline")
+	if err != nil {
+		t.Fatalf("Admin failed to rename: %v", err)
+	}
+	if s.cfg.CurrentUserName != "robert" {
+		t.Errorf("Renaming someone else changed the config to `%s`", s.cfg.CurrentUserName)
+	}
+	_, err = s.db.GetUser(ctx, "caroline")
+	if err != nil {
+		t.Errorf("Renamed user not found: %v", err)
+	}
+}
+
+func TestDeleteUser(t *testing.T) {
+	ctx := context.Background()
+	s := newTestState(t)
+	alice := createTestUser(t, s, "alice")
+	bob := createTestUser(t, s, "bob")
+	carol := createTestUser(t, s, "carol")
+	garden := createTestFeed(t, s, bob, "Garden", "https://garden.example.com/rss")
+	cooking := createTestFeed(t, s, carol, "Cooking", "https://cooking.example.com/rss")
+
+	owner := func(url string) string {
+		t.Helper()
+
+		feed, err := s.db.GetFeed(ctx, url)
+		if err != nil {
+			t.Fatalf("Feed `%s` was deleted with its owner: %v", url, err)
+		}
+		for _, u := range []database.User{alice, bob, carol} {
+			if feed.UserID == u.ID {
+				return u.Name
+			}
+		}
+		return feed.UserID.String()
+	}
+
+	_, err := runHandler(t, handlerDeleteUser, s, bob, "--yes", "carol")
+	if err == nil || !strings.Contains(err.Error(), "Only `carol` or an admin") {
+		t.Errorf("Member deleted someone else: %v", err)
+	}
+	_, err = runHandler(t, handlerDeleteUser, s, alice, "--yes", "alice")
+	if err == nil || !strings.Contains(err.Error(), "last admin") {
+		t.Errorf("Deleted the last admin: %v", err)
+	}
+	_, err = runHandler(t, handlerDeleteUser, s, alice, "bob")
+	if err == nil || !strings.Contains(err.Error(), "pass --yes") {
+		t.Errorf("Deleted a user without confirmation: %v", err)
+	}
+	_, err = runHandler(t, handlerDeleteUser, s, alice, "--yes", "--transfer-to", "bob", "bob")
+	if err == nil || !strings.Contains(err.Error(), "to themselves") {
+		t.Errorf("Transferred feeds to the deleted user: %v", err)
+	}
+
+	// An admin deleting someone else gets their feeds by default
+	out, err := runHandler(t, handlerDeleteUser, s, alice, "--yes", "bob")
+	if err != nil {
+		t.Fatalf("Admin failed to delete: %v", err)
+	}
+	if out != "Deleted `bob`; 1 feeds now belong to `alice`\n" {
+		t.Errorf("deleteuser printed %q", out)
+	}
+	if got := owner(garden.Url); got != "alice" {
+		t.Errorf("`Garden` belongs to %s, want alice", got)
+	}
+	_, err = s.db.GetUser(ctx, "bob")
+	if err == nil {
+		t.Error("Deleted user still exists")
+	}
+
+	// Members deleting themselves hand their feeds to an admin and sign out
+	err = s.cfg.SetSession("carol", "token")
+	if err != nil {
+		t.Fatalf("Failed to set session: %v", err)
+	}
+	s.cfg.CurrentUserName, s.cfg.SessionToken = "carol", "token"
+	_, err = runHandler(t, handlerDeleteUser, s, carol, "--yes", "carol")
+	if err != nil {
+		t.Fatalf("Failed to delete self: %v", err)
+	}
+	if got := owner(cooking.Url); got != "alice" {
+		t.Errorf("`Cooking` belongs to %s, want alice", got)
+	}
+	saved, err := config.Read()
+	if err != nil {
+		t.Fatalf("Failed to read config: %v", err)
+	}
+	for _, cfg := range []config.Config{*s.cfg, saved} {
+		if cfg.CurrentUserName != "" || cfg.SessionToken != "" {
+			t.Errorf("Session of `%s` kept after deleting them", cfg.CurrentUserName)
+		}
+	}
+}
@@ -0,0 +1,409 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/matt-horst/blog-agg/internal/database"
+
+	"github.com/google/uuid"
+)
+
+const (
+	websubLeaseSeconds = 10 * 24 * 60 * 60
+	websubRenewBefore = 24 * time.Hour
+	websubRetryAfter = time.Hour
+	websubCheckInterval = 15 * time.Minute
+	websubMaxBodySize = 10 << 20
+)
+
+// Receives WebSub (PubSubHubbub) callbacks for feeds that advertise a hub
+type websubServer struct {
+	s *state
+	callbackBase string
+	client *http.Client
+}
+
+func handlerServe(ctx context.Context, s *state, cmd command) error {
+	if len(cmd.args) != 2 {
+		return fmt.Errorf("serve requires two arguments: listen-addr callback-base-url")
+	}
+
+	addr := cmd.args[0]
+	callbackBase := strings.TrimRight(cmd.args[1], "/")
+
+	_, err := url.ParseRequestURI(callbackBase)
+	if err != nil {
+		return fmt.Errorf("Invalid callback base URL `%s`: %v", callbackBase, err)
+	}
+
+	ws := &websubServer{
+		s: s,
+		callbackBase: callbackBase,
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+
+	server := &http.Server{Addr: addr, Handler: ws.routes()}
+
+	defer s.notifications.Wait()
+
+	go ws.subscribeLoop(ctx)
+	go func() {
+		<-ctx.Done()
+
+		// Let deliveries that are already being ingested finish
+		shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), shutdownTimeout)
+		defer cancel()
+
+		err := server.Shutdown(shutdownCtx)
+		if err != nil {
+			slog.Error("Failed to shut down WebSub server cleanly", errAttrs(err))
+		}
+	}()
+
+	slog.Info("Listening for WebSub callbacks", slog.String("addr", addr), slog.String("callback_base", callbackBase))
+
+	err = server.ListenAndServe()
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("WebSub server failed: %v", err)
+	}
+
+	slog.Info("WebSub server stopped")
+
+	return nil
+}
+
+func (ws *websubServer) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /websub/{feedID}", ws.handleVerify)
+	mux.HandleFunc("POST /websub/{feedID}", ws.handlePush)
+
+	return mux
+}
+
+func (ws *websubServer) callbackURL(feed database.Feed) string {
+	return fmt.Sprintf("%s/websub/%s", ws.callbackBase, feed.ID)
+}
+
+// Periodically (re)subscribes to the hubs of every push-capable feed
+func (ws *websubServer) subscribeLoop(ctx context.Context) {
+	ticker := time.NewTicker(websubCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		err := ws.renewSubscriptions(ctx)
+		if err != nil {
+			slog.Error("Failed to renew WebSub subscriptions", errAttrs(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (ws *websubServer) renewSubscriptions(ctx context.Context) error {
+	feeds, err := ws.s.db.GetPushFeeds(ctx)
+	if err != nil {
+		return fmt.Errorf("Failed to get push feeds: %v", err)
+	}
+
+	for _, feed := range feeds {
+		if !ws.needsSubscribe(ctx, feed) {
+			continue
+		}
+
+		err = ws.subscribe(ctx, feed)
+		if err != nil {
+			feedLogger(feed).Warn("Failed to subscribe to hub", slog.String("hub_url", feed.HubUrl.String), errAttrs(err))
+		}
+	}
+
+	return nil
+}
+
+func (ws *websubServer) needsSubscribe(ctx context.Context, feed database.Feed) bool {
+	sub, err := ws.s.db.GetWebSubSubscription(ctx, feed.ID)
+	if err != nil {
+		return true
+	}
+
+	if sub.HubUrl != feed.HubUrl.String || sub.TopicUrl != websubTopic(feed) {
+		return true
+	}
+
+	// Still waiting on the hub to verify our last request
+	if !sub.VerifiedAt.Valid {
+		return time.Since(sub.UpdatedAt) > websubRetryAfter
+	}
+
+	return !sub.LeaseExpiresAt.Valid || time.Until(sub.LeaseExpiresAt.Time) < websubRenewBefore
+}
+
+// The topic is the feed's self link when present, since that is the URL the
+// hub knows it by
+func websubTopic(feed database.Feed) string {
+	if feed.SelfUrl.Valid {
+		return feed.SelfUrl.String
+	}
+
+	return feed.Url
+}
+
+func (ws *websubServer) subscribe(ctx context.Context, feed database.Feed) error {
+	// Keep the existing secret when renewing with the same hub so deliveries
+	// signed before the renewal is verified still validate
+	existing, err := ws.s.db.GetWebSubSubscription(ctx, feed.ID)
+	secret := existing.Secret
+	if err != nil || existing.HubUrl != feed.HubUrl.String {
+		secret, err = newWebSubSecret()
+		if err != nil {
+			return err
+		}
+	}
+
+	sub, err := ws.s.db.UpsertWebSubSubscription(
+		ctx,
+		database.UpsertWebSubSubscriptionParams{
+			ID: uuid.New(),
+			FeedID: feed.ID,
+			HubUrl: feed.HubUrl.String,
+			TopicUrl: websubTopic(feed),
+			Secret: secret,
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("Failed to save subscription: %v", err)
+	}
+
+	form := url.Values{}
+	form.Set("hub.mode", "subscribe")
+	form.Set("hub.topic", sub.TopicUrl)
+	form.Set("hub.callback", ws.callbackURL(feed))
+	form.Set("hub.secret", sub.Secret)
+	form.Set("hub.lease_seconds", strconv.Itoa(websubLeaseSeconds))
+
+	req, err := http.NewRequestWithContext(ctx, "POST", sub.HubUrl, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("Failed to create subscription request for `%s`: %v", sub.HubUrl, err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := ws.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("Failed to send subscription request to `%s`: %v", sub.HubUrl, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("Hub `%s` rejected subscription with %s: %s", sub.HubUrl, resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	feedLogger(feed).Info("Requested WebSub subscription", slog.String("topic", sub.TopicUrl), slog.String("hub_url", sub.HubUrl))
+
+	return nil
+}
+
+func newWebSubSecret() (string, error) {
+	buf := make([]byte, 32)
+	_, err := rand.Read(buf)
+	if err != nil {
+		return "", fmt.Errorf("Failed to generate secret: %v", err)
+	}
+
+	return hex.EncodeToString(buf), nil
+}
+
+// Looks up the subscription named by the callback path
+func (ws *websubServer) lookup(r *http.Request) (database.WebsubSubscription, error) {
+	feedID, err := uuid.Parse(r.PathValue("feedID"))
+	if err != nil {
+		return database.WebsubSubscription{}, fmt.Errorf("Invalid feed ID `%s`: %v", r.PathValue("feedID"), err)
+	}
+
+	return ws.s.db.GetWebSubSubscription(r.Context(), feedID)
+}
+
+// Answers the hub's intent verification (and denial) requests
+func (ws *websubServer) handleVerify(w http.ResponseWriter, r *http.Request) {
+	sub, err := ws.lookup(r)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	query := r.URL.Query()
+	mode := query.Get("hub.mode")
+	topic := query.Get("hub.topic")
+
+	if topic != sub.TopicUrl {
+		slog.Warn("Rejected WebSub verification for unexpected topic", slog.String("mode", mode), slog.String("topic", topic), slog.String("expected_topic", sub.TopicUrl))
+		http.NotFound(w, r)
+		return
+	}
+
+	// gator only ever asks to subscribe, so that is the only intent to
+	// confirm or have denied, and only while the request is waiting on the
+	// hub. Anything else could be a third party trying to change the
+	// subscription.
+	switch mode {
+	case "subscribe":
+		if sub.VerifiedAt.Valid {
+			slog.Warn("Rejected WebSub verification with no pending request", slog.String("mode", mode), slog.String("topic", topic))
+			http.NotFound(w, r)
+			return
+		}
+
+		leaseExpiresAt := sql.NullTime{}
+		leaseSeconds, err := strconv.Atoi(query.Get("hub.lease_seconds"))
+		if err == nil {
+			leaseExpiresAt.Time = time.Now().Add(time.Duration(leaseSeconds) * time.Second)
+			leaseExpiresAt.Valid = true
+		}
+
+		err = ws.s.db.MarkWebSubSubscriptionVerified(
+			r.Context(),
+			database.MarkWebSubSubscriptionVerifiedParams{
+				FeedID: sub.FeedID,
+				LeaseExpiresAt: leaseExpiresAt,
+			},
+		)
+		if err != nil {
+			slog.Error("Failed to mark WebSub subscription verified", slog.String("topic", topic), errAttrs(err))
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		slog.Info("WebSub subscription verified", slog.String("topic", topic), slog.Int("lease_seconds", leaseSeconds))
+	case "unsubscribe":
+		slog.Warn("Rejected WebSub verification with no pending request", slog.String("mode", mode), slog.String("topic", topic))
+		http.NotFound(w, r)
+		return
+	case "denied":
+		if sub.VerifiedAt.Valid {
+			slog.Warn("Rejected WebSub denial with no pending request", slog.String("topic", topic), slog.String("reason", query.Get("hub.reason")))
+			http.NotFound(w, r)
+			return
+		}
+
+		slog.Warn("Hub denied WebSub subscription", slog.String("topic", topic), slog.String("reason", query.Get("hub.reason")))
+		err = ws.s.db.DeleteWebSubSubscription(r.Context(), sub.FeedID)
+		if err != nil {
+			slog.Error("Failed to delete WebSub subscription", slog.String("topic", topic), errAttrs(err))
+		}
+		w.WriteHeader(http.StatusOK)
+		return
+	default:
+		http.Error(w, "unknown hub.mode", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain")
+	w.WriteHeader(http.StatusOK)
+	io.WriteString(w, query.Get("hub.challenge"))
+}
+
+// Ingests content distributed by the hub
+func (ws *websubServer) handlePush(w http.ResponseWriter, r *http.Request) {
+	sub, err := ws.lookup(r)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, websubMaxBodySize))
+	if err != nil {
+		http.Error(w, "failed to read body", http.StatusBadRequest)
+		return
+	}
+
+	// The spec requires a 2xx even for bad signatures so the hub can't be used
+	// to probe the secret; the content is just dropped
+	w.WriteHeader(http.StatusAccepted)
+
+	err = verifyWebSubSignature(sub.Secret, r.Header.Get("X-Hub-Signature"), body)
+	if err != nil {
+		slog.Warn("Ignoring WebSub delivery", slog.String("topic", sub.TopicUrl), errAttrs(err))
+		return
+	}
+
+	feed, err := ws.s.db.GetFeedByID(r.Context(), sub.FeedID)
+	if err != nil {
+		slog.Error("Failed to find feed for WebSub delivery", slog.String("feed_id", sub.FeedID.String()), errAttrs(err))
+		return
+	}
+
+	if feed.PausedAt.Valid {
+		feedLogger(feed).Debug("Ignoring WebSub delivery for paused feed")
+		return
+	}
+
+	pushedFeed, err := parseFeed(bytes.NewReader(body))
+	if err != nil {
+		feedLogger(feed).Warn("Failed to parse WebSub delivery", errAttrs(err))
+		return
+	}
+
+	feedLogger(feed).Info("Received WebSub delivery", slog.Int("items", len(pushedFeed.Channel.Item)))
+
+	savePosts(r.Context(), ws.s, feed, pushedFeed)
+}
+
+// Checks an `X-Hub-Signature: method=signature` header against the body
+func verifyWebSubSignature(secret string, header string, body []byte) error {
+	if header == "" {
+		return errors.New("missing signature")
+	}
+
+	method, signature, ok := strings.Cut(header, "=")
+	if !ok {
+		return fmt.Errorf("malformed signature `%s`", header)
+	}
+
+	var newHash func() hash.Hash
+	switch method {
+	case "sha1":
+		newHash = sha1.New
+	case "sha256":
+		newHash = sha256.New
+	case "sha384":
+		newHash = sha512.New384
+	case "sha512":
+		newHash = sha512.New
+	default:
+		return fmt.Errorf("unsupported signature method `%s`", method)
+	}
+
+	expected, err := hex.DecodeString(signature)
+	if err != nil {
+		return fmt.Errorf("malformed signature `%s`", header)
+	}
+
+	mac := hmac.New(newHash, []byte(secret))
+	mac.Write(body)
+	if !hmac.Equal(mac.Sum(nil), expected) {
+		return errors.New("signature mismatch")
+	}
+
+	return nil
+}
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/matt-horst/blog-agg/internal/database"
+)
+
+// Records the subscription requests a hub receives
+type testHub struct {
+	*httptest.Server
+
+	mu sync.Mutex
+	requests []url.Values
+}
+
+func newTestHub(t *testing.T) *testHub {
+	t.Helper()
+
+	hub := &testHub{}
+	hub.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err := r.ParseForm()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		hub.mu.Lock()
+		hub.requests = append(hub.requests, r.PostForm)
+		hub.mu.Unlock()
+
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	t.Cleanup(hub.Close)
+
+	return hub
+}
+
+func (hub *testHub) received() []url.Values {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	return append([]url.Values{}, hub.requests...)
+}
+
+// Sends the hub's intent verification for a subscription, returning the
+// status and body of the response
+func verifyIntent(t *testing.T, callback, mode, topic, challenge string, leaseSeconds int) (int, string) {
+	t.Helper()
+
+	query := url.Values{}
+	query.Set("hub.mode", mode)
+	query.Set("hub.topic", topic)
+	query.Set("hub.challenge", challenge)
+	query.Set("hub.lease_seconds", strconv.Itoa(leaseSeconds))
+
+	resp, err := http.Get(callback + "?" + query.Encode())
+	if err != nil {
+		t.Fatalf("Verification request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+
+	return resp.StatusCode, string(body)
+}
+
+// Delivers content to the callback as the hub would, with an optional
+// X-Hub-Signature
+func pushContent(t *testing.T, callback, signature, body string) {
+	t.Helper()
+
+	req, err := http.NewRequest("POST", callback, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("Failed to create delivery: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/rss+xml")
+	if signature != "" {
+		req.Header.Set("X-Hub-Signature", signature)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Delivery failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusAccepted {
+		t.Errorf("Delivery answered with %s, want 202 whatever the signature", resp.Status)
+	}
+}
+
+func sign(secret, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestWebSub(t *testing.T) {
+	ctx := context.Background()
+	s := newTestState(t)
+	hub := newTestHub(t)
+	alice := createTestUser(t, s, "alice")
+	feed := createTestFeed(t, s, alice, "Pushed", "https://pushed.example.com/rss")
+
+	err := s.db.SetFeedWebSubLinks(
+		ctx,
+		database.SetFeedWebSubLinksParams{
+			ID: feed.ID,
+			HubUrl: sql.NullString{String: hub.URL, Valid: true},
+			SelfUrl: sql.NullString{String: "https://pushed.example.com/self", Valid: true},
+		},
+	)
+	if err != nil {
+		t.Fatalf("Failed to set hub: %v", err)
+	}
+
+	ws := &websubServer{s: s, client: http.DefaultClient}
+	callbackServer := httptest.NewServer(ws.routes())
+	t.Cleanup(callbackServer.Close)
+	ws.callbackBase = callbackServer.URL
+
+	feed, err = s.db.GetFeedByID(ctx, feed.ID)
+	if err != nil {
+		t.Fatalf("Failed to get feed: %v", err)
+	}
+	callback := ws.callbackURL(feed)
+	const topic = "https://pushed.example.com/self"
+
+	err = ws.renewSubscriptions(ctx)
+	if err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+
+	requests := hub.received()
+	if len(requests) != 1 {
+		t.Fatalf("Hub received %d requests, want 1", len(requests))
+	}
+	request := requests[0]
+	if request.Get("hub.mode") != "subscribe" || request.Get("hub.topic") != topic || request.Get("hub.callback") != callback {
+		t.Errorf("Hub received %v", request)
+	}
+	secret := request.Get("hub.secret")
+	if secret == "" {
+		t.Fatal("Subscription request had no secret")
+	}
+
+	t.Run("verification", func(t *testing.T) {
+		status, _ := verifyIntent(t, callback, "subscribe", "https://elsewhere.example.com/", "wrong-topic", 3600)
+		if status != http.StatusNotFound {
+			t.Errorf("Verification for another topic answered %d, want 404", status)
+		}
+
+		status, _ = verifyIntent(t, callback, "unsubscribe", topic, "never-asked", 0)
+		if status != http.StatusNotFound {
+			t.Errorf("Unrequested unsubscribe verification answered %d, want 404", status)
+		}
+
+		status, body := verifyIntent(t, callback, "subscribe", topic, "challenge-1", websubLeaseSeconds)
+		if status != http.StatusOK || body != "challenge-1" {
+			t.Fatalf("Verification answered %d %q, want the challenge echoed", status, body)
+		}
+
+		sub, err := s.db.GetWebSubSubscription(ctx, feed.ID)
+		if err != nil {
+			t.Fatalf("Subscription is gone: %v", err)
+		}
+		if !sub.VerifiedAt.Valid || time.Until(sub.LeaseExpiresAt.Time) < 9*24*time.Hour {
+			t.Errorf("Subscription verified at %v with lease until %v", sub.VerifiedAt, sub.LeaseExpiresAt)
+		}
+
+		// The request has been confirmed, so nothing is pending any more
+		status, _ = verifyIntent(t, callback, "subscribe", topic, "challenge-2", 60)
+		if status != http.StatusNotFound {
+			t.Errorf("Repeated verification answered %d, want 404", status)
+		}
+		status, _ = verifyIntent(t, callback, "unsubscribe", topic, "challenge-3", 0)
+		if status != http.StatusNotFound {
+			t.Errorf("Unrequested unsubscribe verification answered %d, want 404", status)
+		}
+
+		_, err = s.db.GetWebSubSubscription(ctx, feed.ID)
+		if err != nil {
+			t.Errorf("Subscription was removed by an unrequested verification: %v", err)
+		}
+	})
+
+	t.Run("signed deliveries", func(t *testing.T) {
+		signed := rssDocument("Pushed", "", "", testItem{title: "Signed", link: "https://pushed.example.com/signed"})
+		forged := rssDocument("Pushed", "", "", testItem{title: "Forged", link: "https://pushed.example.com/forged"})
+		unsigned := rssDocument("Pushed", "", "", testItem{title: "Unsigned", link: "https://pushed.example.com/unsigned"})
+
+		pushContent(t, callback, sign(secret, signed), signed)
+		pushContent(t, callback, sign("not the secret", forged), forged)
+		pushContent(t, callback, sign(secret, signed), forged)
+		pushContent(t, callback, "", unsigned)
+
+		_, err := s.db.GetPostByURL(ctx, "https://pushed.example.com/signed")
+		if err != nil {
+			t.Errorf("Correctly signed delivery wasn't saved: %v", err)
+		}
+		for _, url := range []string{"https://pushed.example.com/forged", "https://pushed.example.com/unsigned"} {
+			_, err = s.db.GetPostByURL(ctx, url)
+			if err == nil {
+				t.Errorf("Delivery of `%s` was saved without a valid signature", url)
+			}
+		}
+	})
+
+	t.Run("lease renewal", func(t *testing.T) {
+		err := ws.renewSubscriptions(ctx)
+		if err != nil {
+			t.Fatalf("Failed to renew: %v", err)
+		}
+		if got := len(hub.received()); got != 1 {
+			t.Fatalf("Hub received %d requests with most of the lease left, want 1", got)
+		}
+
+		// Bring the lease within the renewal window
+		err = s.db.MarkWebSubSubscriptionVerified(
+			ctx,
+			database.MarkWebSubSubscriptionVerifiedParams{
+				FeedID: feed.ID,
+				LeaseExpiresAt: sql.NullTime{Time: time.Now().Add(time.Hour), Valid: true},
+			},
+		)
+		if err != nil {
+			t.Fatalf("Failed to shorten lease: %v", err)
+		}
+
+		err = ws.renewSubscriptions(ctx)
+		if err != nil {
+			t.Fatalf("Failed to renew: %v", err)
+		}
+		requests := hub.received()
+		if len(requests) != 2 {
+			t.Fatalf("Hub received %d requests, want a renewal", len(requests))
+		}
+		renewal := requests[1]
+		if renewal.Get("hub.mode") != "subscribe" || renewal.Get("hub.topic") != topic || renewal.Get("hub.secret") != secret {
+			t.Errorf("Renewal sent %v, want a subscribe with the same secret", renewal)
+		}
+
+		status, body := verifyIntent(t, callback, "subscribe", topic, "renewed", websubLeaseSeconds)
+		if status != http.StatusOK || body != "renewed" {
+			t.Errorf("Verification of the renewal answered %d %q", status, body)
+		}
+
+		sub, err := s.db.GetWebSubSubscription(ctx, feed.ID)
+		if err != nil || time.Until(sub.LeaseExpiresAt.Time) < 9*24*time.Hour {
+			t.Errorf("Renewed lease runs until %v (%v)", sub.LeaseExpiresAt, err)
+		}
+	})
+
+	t.Run("denial", func(t *testing.T) {
+		status, _ := verifyIntent(t, callback, "denied", topic, "", 0)
+		if status != http.StatusNotFound {
+			t.Errorf("Denial of a verified subscription answered %d, want 404", status)
+		}
+		_, err := s.db.GetWebSubSubscription(ctx, feed.ID)
+		if err != nil {
+			t.Fatalf("Verified subscription was removed by a denial: %v", err)
+		}
+
+		// Renewing puts the subscription back to waiting on the hub, which
+		// may then deny it
+		err = s.db.MarkWebSubSubscriptionVerified(
+			ctx,
+			database.MarkWebSubSubscriptionVerifiedParams{
+				FeedID: feed.ID,
+				LeaseExpiresAt: sql.NullTime{Time: time.Now().Add(time.Hour), Valid: true},
+			},
+		)
+		if err != nil {
+			t.Fatalf("Failed to shorten lease: %v", err)
+		}
+		err = ws.renewSubscriptions(ctx)
+		if err != nil {
+			t.Fatalf("Failed to renew: %v", err)
+		}
+
+		status, _ = verifyIntent(t, callback, "denied", topic, "", 0)
+		if status != http.StatusOK {
+			t.Errorf("Denial of a pending subscription answered %d, want 200", status)
+		}
+		_, err = s.db.GetWebSubSubscription(ctx, feed.ID)
+		if err == nil {
+			t.Error("Denied subscription wasn't removed")
+		}
+	})
+}